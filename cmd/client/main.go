@@ -18,14 +18,14 @@ func main() {
 	client := amberpb.NewAmberServiceClient(conn)
 
 	// Begin transaction
-	txn, err := client.BeginTransaction(context.Background(), &amberpb.Empty{})
+	txn, err := client.BeginTransaction(context.Background(), &amberpb.BeginTransactionRequest{})
 	if err != nil {
 		log.Fatalf("BeginTransaction error: %v", err)
 	}
 	fmt.Printf("Started Txn: %s\n", txn.Id)
 
 	// Write key1
-	status, err := client.Write(context.Background(), &amberpb.WriteRequest{Key: "key1", Value: "value1", TxId: txn.Id})
+	status, err := client.Write(context.Background(), &amberpb.WriteRequest{Key: []byte("key1"), Value: []byte("value1"), TxId: txn.Id})
 	if err != nil || !status.Success {
 		log.Fatalf("Write error: %v %s", err, status.Message)
 	}
@@ -40,7 +40,7 @@ func main() {
 
 	// Read back
 	time.Sleep(1 * time.Second)
-	readResp, err := client.Read(context.Background(), &amberpb.ReadRequest{Key: "key1"})
+	readResp, err := client.Read(context.Background(), &amberpb.ReadRequest{Key: []byte("key1")})
 	if err != nil {
 		log.Fatalf("Read error: %v", err)
 	}
@@ -16,8 +16,10 @@ import (
 )
 
 type PeerConfig struct {
-	ID      string `json:"id"`
-	Address string `json:"address"`
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	GRPCAddress string `json:"grpc_address,omitempty"`
+	Suffrage    string `json:"suffrage,omitempty"`
 }
 
 // RouteResponse gives shard and node addresses for a key
@@ -218,7 +220,7 @@ func main() {
 			dialConns[addr] = conn
 			client := amberpb.NewAmberServiceClient(conn)
 			// Prepare phase: begin tx and writes
-			resp, err := client.BeginTransaction(context.Background(), &amberpb.Empty{})
+			resp, err := client.BeginTransaction(context.Background(), &amberpb.BeginTransactionRequest{})
 			if err != nil {
 				http.Error(w, fmt.Sprintf("begin tx failed %s: %v", addr, err), http.StatusInternalServerError)
 				return
@@ -229,7 +231,7 @@ func main() {
 		for addr, writes := range writesByNode {
 			client := amberpb.NewAmberServiceClient(dialConns[addr])
 			for _, wr := range writes {
-				st, err := client.Write(context.Background(), &amberpb.WriteRequest{Key: wr.Key, Value: wr.Value, TxId: txnIDs[addr]})
+				st, err := client.Write(context.Background(), &amberpb.WriteRequest{Key: []byte(wr.Key), Value: []byte(wr.Value), TxId: txnIDs[addr]})
 				if err != nil || !st.Success {
 					// Abort on all nodes
 					for a, tx := range txnIDs {
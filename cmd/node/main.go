@@ -4,14 +4,18 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/dishankoza/amberdb/internal/config"
 	"github.com/dishankoza/amberdb/internal/kvstore"
 	"github.com/dishankoza/amberdb/internal/raftstore"
 	"github.com/dishankoza/amberdb/internal/rpc"
@@ -19,90 +23,126 @@ import (
 )
 
 type PeerConfig struct {
-	ID      string `json:"id"`
-	Address string `json:"address"`
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	GRPCAddress string `json:"grpc_address,omitempty"`
+	// Suffrage is "voter" (default) or "nonvoter". Non-voters replicate the
+	// log and serve stale reads but never vote or count toward quorum.
+	Suffrage string `json:"suffrage,omitempty"`
 }
 
 func main() {
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "data.db"
-	}
-
-	nodeID := os.Getenv("NODE_ID")
-	if nodeID == "" {
-		log.Fatal("NODE_ID env variable is required")
-	}
-
-	// Raft advertise address for other nodes to connect
-	raftAdvertise := os.Getenv("RAFT_ADDR")
-	if raftAdvertise == "" {
-		log.Fatal("RAFT_ADDR env variable is required, e.g. node1:9001")
-	}
-	// Raft bind address for listening; default to 0.0.0.0:<port>
-	raftBind := os.Getenv("RAFT_BIND_ADDR")
-	log.Printf("RAFT_BIND_ADDR: %s", raftBind)
-	if raftBind == "" {
-		// extract port from advertise address
-		_, port, err := net.SplitHostPort(raftAdvertise)
-		if err != nil {
-			log.Fatalf("invalid RAFT_ADDR: %v", err)
-		}
-		raftBind = "0.0.0.0:" + port
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	store, err := kvstore.NewStore(dbPath)
+	store, err := kvstore.Open(cfg.StorageEngine, cfg.DBPath)
 	if err != nil {
 		log.Fatalf("failed to create store: %v", err)
 	}
-	defer store.Close()
 
 	fsm := raftstore.NewFSM(store)
 
 	// Load Raft peers configuration
-	configPath := os.Getenv("RAFT_CONFIG_PATH")
-	if configPath == "" {
-		configPath = "./internal/raftstore/raft_config.json"
-	}
-	peers := loadPeers(configPath)
+	peers := loadPeers(cfg.PeersPath)
 	for _, p := range peers {
 		log.Printf("Loaded peer: %s at %s", p.ID, p.Address)
 	}
-	raftServers := make([]raft.Server, 0, len(peers))
-	for _, p := range peers {
-		log.Printf("Configuring peer %s at %s", p.ID, p.Address)
-		raftServers = append(raftServers, raft.Server{
-			ID:       raft.ServerID(p.ID),
-			Address:  raft.ServerAddress(p.Address),
-			Suffrage: raft.Voter, // <-- ensure all peers are voters
-		})
+	raftServers, err := raftPeers(peers)
+	if err != nil {
+		log.Fatalf("invalid raft config: %v", err)
+	}
+	for _, srv := range raftServers {
+		log.Printf("Configuring peer %s at %s as %s", srv.ID, srv.Address, srv.Suffrage)
 	}
 
-	raftDataDir := filepath.Join("./raft-data", nodeID)
-	raftNode, err := raftstore.NewRaftNode(raftDataDir, nodeID, raftAdvertise, raftBind, raftServers, fsm)
+	port := cfg.GRPCPort
+	raftOpts := cfg.RaftOptions()
+	raftOpts.GRPCAddress = ownGRPCAddress(peers, cfg.NodeID, cfg.Raft.AdvertiseAddr, port)
+	raftNode, err := raftstore.NewRaftNode(cfg.Raft.DataDir, cfg.NodeID, cfg.Raft.AdvertiseAddr, cfg.Raft.BindAddr, raftServers, fsm, raftOpts)
 	if err != nil {
 		log.Fatalf("failed to start raft node: %v", err)
 	}
-	_ = raftNode // currently unused, but will gate write logic later
 
 	grpcServer := grpc.NewServer()
-	rpc.RegisterAmberService(grpcServer, store, raftNode)
+	forwarder := rpc.RegisterAmberService(grpcServer, store, raftNode, fsm.Clock(), grpcAddresses(peers, port))
+	rpc.RegisterAdminService(grpcServer, raftNode)
 	reflection.Register(grpcServer)
 
-	// configure gRPC port from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50051"
-	}
-
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	fmt.Printf("AmberDB Node %s running on port %s\n", nodeID, port)
-	if err := grpcServer.Serve(lis); err != nil {
+	fmt.Printf("AmberDB Node %s running on port %s\n", cfg.NodeID, port)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(lis)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-signals:
+		log.Printf("received %s, shutting down", sig)
+	case err := <-serveErr:
 		log.Fatalf("failed to serve: %v", err)
 	}
+	shutdown(grpcServer, forwarder, raftNode, store, cfg.ShutdownTimeout)
+}
+
+// raftNode is the part of *raftstore.Store that shutdown stops
+type raftNode interface {
+	Shutdown() error
+}
+
+// localStore is the part of *kvstore.Store that shutdown closes
+type localStore interface {
+	CloseWatchers(err error)
+	Close() error
+}
+
+// shutdown drains in-flight RPCs, closes the connections to the leader, steps
+// down and stops Raft, then closes the store. Raft stays up until gRPC has
+// drained so in-flight writes can commit, and the store stays open until Raft
+// stopped applying entries and its GC and expiry loops have returned.
+func shutdown(grpcServer *grpc.Server, forwarder io.Closer, raftNode raftNode, store localStore, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		// Watch streams never end on their own
+		store.CloseWatchers(kvstore.ErrWatchClosed)
+		select {
+		case <-stopped:
+		case <-time.After(timeout / 2):
+			log.Printf("gRPC graceful stop timed out, closing open connections")
+			grpcServer.Stop()
+		}
+		if err := forwarder.Close(); err != nil {
+			log.Printf("forwarder close error: %v", err)
+		}
+
+		if err := raftNode.Shutdown(); err != nil {
+			log.Printf("raft shutdown error: %v", err)
+		}
+		if err := store.Close(); err != nil {
+			log.Printf("store close error: %v", err)
+		}
+	}()
+
+	select {
+	case <-done:
+		log.Printf("shutdown complete")
+	case <-time.After(timeout):
+		log.Fatalf("shutdown did not complete within %s", timeout)
+	}
 }
 
 func loadPeers(filename string) []PeerConfig {
@@ -116,3 +156,82 @@ func loadPeers(filename string) []PeerConfig {
 	}
 	return peers
 }
+
+// raftPeers converts the peers file to the servers Raft bootstraps with. At
+// least one of them must be a voter.
+func raftPeers(peers []PeerConfig) ([]raft.Server, error) {
+	servers := make([]raft.Server, 0, len(peers))
+	for _, p := range peers {
+		suffrage, err := parseSuffrage(p.Suffrage)
+		if err != nil {
+			return nil, fmt.Errorf("peer %s: %w", p.ID, err)
+		}
+		servers = append(servers, raft.Server{
+			ID:       raft.ServerID(p.ID),
+			Address:  raft.ServerAddress(p.Address),
+			Suffrage: suffrage,
+		})
+	}
+	if !hasVoter(servers) {
+		return nil, fmt.Errorf("at least one peer must be a voter")
+	}
+	return servers, nil
+}
+
+// parseSuffrage converts a peer's configured suffrage to its raft equivalent
+func parseSuffrage(s string) (raft.ServerSuffrage, error) {
+	switch s {
+	case "", "voter":
+		return raft.Voter, nil
+	case "nonvoter":
+		return raft.Nonvoter, nil
+	default:
+		return 0, fmt.Errorf("unknown suffrage %q, expected voter or nonvoter", s)
+	}
+}
+
+func hasVoter(servers []raft.Server) bool {
+	for _, s := range servers {
+		if s.Suffrage == raft.Voter {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcAddresses maps each peer's raft address to its gRPC address. Peers without
+// an explicit grpc_address are assumed to serve gRPC on the raft host at the
+// same port as this node, which is how docker-compose lays out the cluster.
+func grpcAddresses(peers []PeerConfig, defaultPort string) map[string]string {
+	addrs := make(map[string]string, len(peers))
+	for _, p := range peers {
+		addr := p.GRPCAddress
+		if addr == "" {
+			host, _, err := net.SplitHostPort(p.Address)
+			if err != nil {
+				log.Printf("cannot derive gRPC address for peer %s: %v", p.ID, err)
+				continue
+			}
+			addr = net.JoinHostPort(host, defaultPort)
+		}
+		addrs[p.Address] = addr
+	}
+	return addrs
+}
+
+// ownGRPCAddress returns the gRPC address this node registers when elected:
+// its grpc_address in the peers file, or else the advertised raft host at
+// the gRPC port
+func ownGRPCAddress(peers []PeerConfig, nodeID, advertiseAddr, port string) string {
+	for _, p := range peers {
+		if p.ID == nodeID && p.GRPCAddress != "" {
+			return p.GRPCAddress
+		}
+	}
+	host, _, err := net.SplitHostPort(advertiseAddr)
+	if err != nil {
+		log.Printf("cannot derive own gRPC address: %v", err)
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+)
+
+func TestParseSuffrage(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		want    raft.ServerSuffrage
+		wantErr bool
+	}{
+		{"", raft.Voter, false},
+		{"voter", raft.Voter, false},
+		{"nonvoter", raft.Nonvoter, false},
+		{"Voter", 0, true},
+		{"staging", 0, true},
+	} {
+		got, err := parseSuffrage(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSuffrage(%q): got %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHasVoter(t *testing.T) {
+	voter := raft.Server{ID: "n1", Suffrage: raft.Voter}
+	nonvoter := raft.Server{ID: "n2", Suffrage: raft.Nonvoter}
+	for _, tt := range []struct {
+		name    string
+		servers []raft.Server
+		want    bool
+	}{
+		{"none", nil, false},
+		{"only nonvoters", []raft.Server{nonvoter}, false},
+		{"voter", []raft.Server{voter}, true},
+		{"mixed", []raft.Server{nonvoter, voter}, true},
+	} {
+		if got := hasVoter(tt.servers); got != tt.want {
+			t.Errorf("hasVoter(%s): got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRaftPeers(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		peers   []PeerConfig
+		want    []raft.ServerSuffrage
+		wantErr string
+	}{
+		{
+			name:  "default voters",
+			peers: []PeerConfig{{ID: "n1", Address: "node1:9001"}, {ID: "n2", Address: "node2:9001", Suffrage: "nonvoter"}},
+			want:  []raft.ServerSuffrage{raft.Voter, raft.Nonvoter},
+		},
+		{
+			name:    "no voters",
+			peers:   []PeerConfig{{ID: "n1", Address: "node1:9001", Suffrage: "nonvoter"}},
+			wantErr: "at least one peer must be a voter",
+		},
+		{
+			name:    "no peers",
+			wantErr: "at least one peer must be a voter",
+		},
+		{
+			name:    "unknown suffrage",
+			peers:   []PeerConfig{{ID: "n1", Address: "node1:9001", Suffrage: "observer"}},
+			wantErr: "peer n1: unknown suffrage",
+		},
+	} {
+		servers, err := raftPeers(tt.peers)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: raftPeers error: %v", tt.name, err)
+		}
+		if len(servers) != len(tt.want) {
+			t.Fatalf("%s: got %d servers, want %d", tt.name, len(servers), len(tt.want))
+		}
+		for i, srv := range servers {
+			p := tt.peers[i]
+			if string(srv.ID) != p.ID || string(srv.Address) != p.Address || srv.Suffrage != tt.want[i] {
+				t.Errorf("%s: got server %+v for peer %+v", tt.name, srv, p)
+			}
+		}
+	}
+}
+
+// recorder stands in for each part of a node that shutdown stops, and
+// records the order it stops them in
+type recorder struct {
+	events *[]string
+	name   string
+}
+
+func (r recorder) record(event string) {
+	*r.events = append(*r.events, r.name+" "+event)
+}
+
+func (r recorder) Close() error          { r.record("closed"); return nil }
+func (r recorder) Shutdown() error       { r.record("shut down"); return nil }
+func (r recorder) CloseWatchers(_ error) { r.record("watchers closed") }
+
+func TestShutdownOrder(t *testing.T) {
+	var events []string
+	shutdown(grpc.NewServer(), recorder{&events, "forwarder"}, recorder{&events, "raft"}, recorder{&events, "store"}, time.Second)
+	want := []string{"store watchers closed", "forwarder closed", "raft shut down", "store closed"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got %v, want %v", events, want)
+	}
+}
+
+// loopCheckingStore fails the test if it is closed while a background loop
+// of the Raft node may still use it
+type loopCheckingStore struct {
+	*kvstore.Store
+	t *testing.T
+}
+
+func (s loopCheckingStore) Close() error {
+	buf := make([]byte, 1<<20)
+	stacks := string(buf[:runtime.Stack(buf, true)])
+	for _, loop := range []string{"runGC", "runExpiry"} {
+		if strings.Contains(stacks, "raftstore.(*Store)."+loop) {
+			s.t.Errorf("store closed while %s is running", loop)
+		}
+	}
+	return s.Store.Close()
+}
+
+func TestShutdownStopsLoopsBeforeClosingStore(t *testing.T) {
+	store, err := kvstore.Open(kvstore.EngineBolt, filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	peers := []raft.Server{{ID: "n1", Address: raft.ServerAddress(addr), Suffrage: raft.Voter}}
+	opts := raftstore.Options{GCRetention: time.Hour, GCInterval: time.Millisecond, ExpiryInterval: time.Millisecond}
+	node, err := raftstore.NewRaftNode(t.TempDir(), "n1", addr, addr, peers, raftstore.NewFSM(store), opts)
+	if err != nil {
+		t.Fatalf("NewRaftNode error: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("node did not become leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	shutdown(grpc.NewServer(), io.NopCloser(nil), node, loopCheckingStore{store, t}, 10*time.Second)
+	if _, err := store.Read("k", "999"); err == nil {
+		t.Error("expected shutdown to close the store")
+	}
+}
@@ -0,0 +1,278 @@
+// internal/config/config.go
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	"gopkg.in/yaml.v3"
+)
+
+// NodeConfig is the configuration of an amberdb-node. Values are layered:
+// defaults, then the YAML config file, then environment variables, then
+// command-line flags.
+type NodeConfig struct {
+	NodeID          string        `yaml:"node_id"`
+	StorageEngine   string        `yaml:"storage_engine"` // sqlite or bolt
+	DBPath          string        `yaml:"db_path"`
+	GRPCPort        string        `yaml:"grpc_port"`
+	PeersPath       string        `yaml:"peers_path"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Raft            RaftConfig    `yaml:"raft"`
+	GC              GCConfig      `yaml:"gc"`
+}
+
+// GCConfig controls MVCC garbage collection and TTL expiration
+type GCConfig struct {
+	Retention      time.Duration `yaml:"retention"` // 0 disables GC
+	Interval       time.Duration `yaml:"interval"`
+	ExpiryInterval time.Duration `yaml:"expiry_interval"` // how often the leader expires keys past their TTL
+}
+
+// RaftConfig holds the Raft addresses, storage location and tuning knobs
+type RaftConfig struct {
+	AdvertiseAddr      string        `yaml:"advertise_addr"`
+	BindAddr           string        `yaml:"bind_addr"` // defaults to 0.0.0.0:<advertise port>
+	DataDir            string        `yaml:"data_dir"`  // defaults to ./raft-data/<node_id>
+	ApplyTimeout       time.Duration `yaml:"apply_timeout"`
+	MembershipTimeout  time.Duration `yaml:"membership_timeout"`
+	TransportTimeout   time.Duration `yaml:"transport_timeout"`
+	TransportMaxPool   int           `yaml:"transport_max_pool"`
+	SnapshotRetain     int           `yaml:"snapshot_retain"`
+	HeartbeatTimeout   time.Duration `yaml:"heartbeat_timeout"`
+	ElectionTimeout    time.Duration `yaml:"election_timeout"`
+	LeaderLeaseTimeout time.Duration `yaml:"leader_lease_timeout"`
+	CommitTimeout      time.Duration `yaml:"commit_timeout"`
+	SnapshotThreshold  uint64        `yaml:"snapshot_threshold"`
+	SnapshotInterval   time.Duration `yaml:"snapshot_interval"`
+	TrailingLogs       uint64        `yaml:"trailing_logs"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() NodeConfig {
+	opts := raftstore.DefaultOptions()
+	return NodeConfig{
+		StorageEngine:   kvstore.EngineSQLite,
+		DBPath:          "data.db",
+		GRPCPort:        "50051",
+		PeersPath:       "./internal/raftstore/raft_config.json",
+		ShutdownTimeout: 15 * time.Second,
+		Raft: RaftConfig{
+			ApplyTimeout:       opts.ApplyTimeout,
+			MembershipTimeout:  opts.MembershipTimeout,
+			TransportTimeout:   opts.TransportTimeout,
+			TransportMaxPool:   opts.TransportMaxPool,
+			SnapshotRetain:     opts.SnapshotRetain,
+			HeartbeatTimeout:   opts.HeartbeatTimeout,
+			ElectionTimeout:    opts.ElectionTimeout,
+			LeaderLeaseTimeout: opts.LeaderLeaseTimeout,
+			CommitTimeout:      opts.CommitTimeout,
+			SnapshotThreshold:  opts.SnapshotThreshold,
+			SnapshotInterval:   opts.SnapshotInterval,
+			TrailingLogs:       opts.TrailingLogs,
+		},
+		GC: GCConfig{
+			Retention:      opts.GCRetention,
+			Interval:       opts.GCInterval,
+			ExpiryInterval: opts.ExpiryInterval,
+		},
+	}
+}
+
+// override is a setting that an environment variable and a flag can set
+type override struct {
+	env   string
+	flag  string
+	usage string
+	field func(*NodeConfig) interface{} // *string, *int, *uint64 or *time.Duration
+}
+
+// overrides lists every setting that can be changed without a config file
+var overrides = []override{
+	{"NODE_ID", "node-id", "node ID", func(c *NodeConfig) interface{} { return &c.NodeID }},
+	{"STORAGE_ENGINE", "engine", "storage engine, sqlite or bolt", func(c *NodeConfig) interface{} { return &c.StorageEngine }},
+	{"DB_PATH", "db-path", "database file path", func(c *NodeConfig) interface{} { return &c.DBPath }},
+	{"PORT", "port", "gRPC port", func(c *NodeConfig) interface{} { return &c.GRPCPort }},
+	{"RAFT_CONFIG_PATH", "raft-config", "raft peers file", func(c *NodeConfig) interface{} { return &c.PeersPath }},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "graceful shutdown timeout", func(c *NodeConfig) interface{} { return &c.ShutdownTimeout }},
+	{"RAFT_ADDR", "raft-addr", "raft advertise address", func(c *NodeConfig) interface{} { return &c.Raft.AdvertiseAddr }},
+	{"RAFT_BIND_ADDR", "raft-bind-addr", "raft bind address", func(c *NodeConfig) interface{} { return &c.Raft.BindAddr }},
+	{"RAFT_DATA_DIR", "raft-data-dir", "raft data directory", func(c *NodeConfig) interface{} { return &c.Raft.DataDir }},
+	{"RAFT_APPLY_TIMEOUT", "raft-apply-timeout", "how long a write waits to be committed", func(c *NodeConfig) interface{} { return &c.Raft.ApplyTimeout }},
+	{"RAFT_MEMBERSHIP_TIMEOUT", "raft-membership-timeout", "how long a membership change waits to be committed", func(c *NodeConfig) interface{} { return &c.Raft.MembershipTimeout }},
+	{"RAFT_TRANSPORT_TIMEOUT", "raft-transport-timeout", "raft transport I/O timeout", func(c *NodeConfig) interface{} { return &c.Raft.TransportTimeout }},
+	{"RAFT_TRANSPORT_MAX_POOL", "raft-transport-max-pool", "raft connections pooled per peer", func(c *NodeConfig) interface{} { return &c.Raft.TransportMaxPool }},
+	{"RAFT_SNAPSHOT_RETAIN", "raft-snapshot-retain", "raft snapshots kept on disk", func(c *NodeConfig) interface{} { return &c.Raft.SnapshotRetain }},
+	{"RAFT_HEARTBEAT_TIMEOUT", "raft-heartbeat-timeout", "raft heartbeat timeout", func(c *NodeConfig) interface{} { return &c.Raft.HeartbeatTimeout }},
+	{"RAFT_ELECTION_TIMEOUT", "raft-election-timeout", "raft election timeout", func(c *NodeConfig) interface{} { return &c.Raft.ElectionTimeout }},
+	{"RAFT_LEADER_LEASE_TIMEOUT", "raft-leader-lease-timeout", "raft leader lease timeout", func(c *NodeConfig) interface{} { return &c.Raft.LeaderLeaseTimeout }},
+	{"RAFT_COMMIT_TIMEOUT", "raft-commit-timeout", "raft commit timeout", func(c *NodeConfig) interface{} { return &c.Raft.CommitTimeout }},
+	{"RAFT_SNAPSHOT_THRESHOLD", "raft-snapshot-threshold", "log entries between raft snapshots", func(c *NodeConfig) interface{} { return &c.Raft.SnapshotThreshold }},
+	{"RAFT_SNAPSHOT_INTERVAL", "raft-snapshot-interval", "how often raft checks whether to snapshot", func(c *NodeConfig) interface{} { return &c.Raft.SnapshotInterval }},
+	{"RAFT_TRAILING_LOGS", "raft-trailing-logs", "log entries kept after a raft snapshot", func(c *NodeConfig) interface{} { return &c.Raft.TrailingLogs }},
+	{"GC_RETENTION", "gc-retention", "how long superseded versions stay readable, 0 disables GC", func(c *NodeConfig) interface{} { return &c.GC.Retention }},
+	{"GC_INTERVAL", "gc-interval", "how often the leader proposes a GC", func(c *NodeConfig) interface{} { return &c.GC.Interval }},
+	{"GC_EXPIRY_INTERVAL", "gc-expiry-interval", "how often the leader expires keys past their TTL", func(c *NodeConfig) interface{} { return &c.GC.ExpiryInterval }},
+}
+
+// set parses value into the field of cfg that o overrides
+func (o override) set(cfg *NodeConfig, value string) error {
+	var err error
+	switch field := o.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *int:
+		*field, err = strconv.Atoi(value)
+	case *uint64:
+		*field, err = strconv.ParseUint(value, 10, 64)
+	case *time.Duration:
+		*field, err = time.ParseDuration(value)
+	default:
+		err = fmt.Errorf("unsupported field type %T", field)
+	}
+	return err
+}
+
+// Load builds the node configuration from args (usually os.Args[1:]). The
+// config file is named by the -config flag or the CONFIG_PATH env variable
+// and is optional.
+func Load(args []string) (NodeConfig, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("amberdb-node", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	flagValues := make([]*string, len(overrides))
+	for i, o := range overrides {
+		flagValues[i] = fs.String(o.flag, "", fmt.Sprintf("%s (%s)", o.usage, o.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		data, err := os.ReadFile(*path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read config file: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("invalid config file %s: %w", *path, err)
+		}
+	}
+
+	var errs []error
+	for _, o := range overrides {
+		if v := os.Getenv(o.env); v != "" {
+			if err := o.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", o.env, v, err))
+			}
+		}
+	}
+	for i, o := range overrides {
+		if v := *flagValues[i]; v != "" {
+			if err := o.set(&cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid -%s %q: %w", o.flag, v, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	cfg.fillDerived()
+	return cfg, cfg.Validate()
+}
+
+// fillDerived sets defaults that depend on other fields
+func (c *NodeConfig) fillDerived() {
+	if c.Raft.BindAddr == "" {
+		if _, port, err := net.SplitHostPort(c.Raft.AdvertiseAddr); err == nil {
+			c.Raft.BindAddr = "0.0.0.0:" + port
+		}
+	}
+	if c.Raft.DataDir == "" && c.NodeID != "" {
+		c.Raft.DataDir = filepath.Join("./raft-data", c.NodeID)
+	}
+}
+
+// Validate reports every invalid setting at once
+func (c NodeConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.NodeID != "", "node_id is required (NODE_ID or -node-id)")
+	check(c.StorageEngine == kvstore.EngineSQLite || c.StorageEngine == kvstore.EngineBolt,
+		"storage_engine %q must be %s or %s", c.StorageEngine, kvstore.EngineSQLite, kvstore.EngineBolt)
+	check(c.DBPath != "", "db_path must not be empty")
+	check(c.PeersPath != "", "peers_path must not be empty")
+	if p, err := strconv.Atoi(c.GRPCPort); err != nil || p <= 0 || p > 65535 {
+		errs = append(errs, fmt.Errorf("grpc_port %q is not a valid port", c.GRPCPort))
+	}
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+
+	r := c.Raft
+	if _, _, err := net.SplitHostPort(r.AdvertiseAddr); err != nil {
+		errs = append(errs, fmt.Errorf("raft.advertise_addr %q must be host:port, e.g. node1:9001 (RAFT_ADDR)", r.AdvertiseAddr))
+	}
+	if _, _, err := net.SplitHostPort(r.BindAddr); err != nil {
+		errs = append(errs, fmt.Errorf("raft.bind_addr %q must be host:port", r.BindAddr))
+	}
+	check(r.DataDir != "", "raft.data_dir must not be empty")
+	check(r.ApplyTimeout > 0, "raft.apply_timeout must be positive")
+	check(r.MembershipTimeout > 0, "raft.membership_timeout must be positive")
+	check(r.TransportTimeout > 0, "raft.transport_timeout must be positive")
+	check(r.TransportMaxPool > 0, "raft.transport_max_pool must be positive")
+	check(r.SnapshotRetain > 0, "raft.snapshot_retain must be at least 1")
+	check(r.HeartbeatTimeout >= 5*time.Millisecond, "raft.heartbeat_timeout must be at least 5ms")
+	check(r.ElectionTimeout >= r.HeartbeatTimeout, "raft.election_timeout (%s) must be at least raft.heartbeat_timeout (%s)", r.ElectionTimeout, r.HeartbeatTimeout)
+	check(r.LeaderLeaseTimeout >= 5*time.Millisecond, "raft.leader_lease_timeout must be at least 5ms")
+	check(r.LeaderLeaseTimeout <= r.HeartbeatTimeout, "raft.leader_lease_timeout (%s) must not exceed raft.heartbeat_timeout (%s)", r.LeaderLeaseTimeout, r.HeartbeatTimeout)
+	check(r.CommitTimeout >= time.Millisecond, "raft.commit_timeout must be at least 1ms")
+	check(r.SnapshotThreshold > 0, "raft.snapshot_threshold must be positive")
+	check(r.SnapshotInterval >= 5*time.Millisecond, "raft.snapshot_interval must be at least 5ms")
+	check(c.GC.Retention >= 0, "gc.retention must not be negative")
+	check(c.GC.Interval > 0, "gc.interval must be positive")
+	check(c.GC.ExpiryInterval > 0, "gc.expiry_interval must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// RaftOptions converts the raft section for raftstore.NewRaftNode
+func (c NodeConfig) RaftOptions() raftstore.Options {
+	r := c.Raft
+	return raftstore.Options{
+		ApplyTimeout:       r.ApplyTimeout,
+		MembershipTimeout:  r.MembershipTimeout,
+		TransportTimeout:   r.TransportTimeout,
+		TransportMaxPool:   r.TransportMaxPool,
+		SnapshotRetain:     r.SnapshotRetain,
+		HeartbeatTimeout:   r.HeartbeatTimeout,
+		ElectionTimeout:    r.ElectionTimeout,
+		LeaderLeaseTimeout: r.LeaderLeaseTimeout,
+		CommitTimeout:      r.CommitTimeout,
+		SnapshotThreshold:  r.SnapshotThreshold,
+		SnapshotInterval:   r.SnapshotInterval,
+		TrailingLogs:       r.TrailingLogs,
+		GCRetention:        c.GC.Retention,
+		GCInterval:         c.GC.Interval,
+		ExpiryInterval:     c.GC.ExpiryInterval,
+	}
+}
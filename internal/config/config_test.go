@@ -0,0 +1,169 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/config"
+)
+
+// writeConfig writes a YAML config to a temp file and returns its path
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "node.yaml")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileWithOverrides(t *testing.T) {
+	path := writeConfig(t, `
+node_id: node1
+grpc_port: "50051"
+raft:
+  advertise_addr: localhost:9001
+  heartbeat_timeout: 2s
+  election_timeout: 3s
+  snapshot_threshold: 100
+  trailing_logs: 50
+gc:
+  retention: 1h
+`)
+	t.Setenv("PORT", "50052")
+	cfg, err := config.Load([]string{"-config", path, "-node-id", "node9"})
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.NodeID != "node9" {
+		t.Errorf("expected flag to override file, got node_id %q", cfg.NodeID)
+	}
+	if cfg.GRPCPort != "50052" {
+		t.Errorf("expected env to override file, got grpc_port %q", cfg.GRPCPort)
+	}
+	if cfg.Raft.HeartbeatTimeout != 2*time.Second || cfg.Raft.ElectionTimeout != 3*time.Second {
+		t.Errorf("unexpected timeouts: %s %s", cfg.Raft.HeartbeatTimeout, cfg.Raft.ElectionTimeout)
+	}
+	if cfg.Raft.SnapshotThreshold != 100 || cfg.Raft.TrailingLogs != 50 {
+		t.Errorf("unexpected snapshot settings: %d %d", cfg.Raft.SnapshotThreshold, cfg.Raft.TrailingLogs)
+	}
+	// Derived defaults
+	if cfg.Raft.BindAddr != "0.0.0.0:9001" {
+		t.Errorf("expected derived bind addr, got %q", cfg.Raft.BindAddr)
+	}
+	if cfg.Raft.DataDir != filepath.Join("raft-data", "node9") {
+		t.Errorf("expected derived data dir, got %q", cfg.Raft.DataDir)
+	}
+	if cfg.Raft.ApplyTimeout != 5*time.Second {
+		t.Errorf("expected default apply timeout, got %s", cfg.Raft.ApplyTimeout)
+	}
+	if opts := cfg.RaftOptions(); opts.GCRetention != time.Hour || opts.GCInterval != time.Minute {
+		t.Errorf("unexpected GC settings: %s %s", opts.GCRetention, opts.GCInterval)
+	}
+	if opts := cfg.RaftOptions(); opts.ExpiryInterval != 5*time.Second {
+		t.Errorf("expected default expiry interval, got %s", opts.ExpiryInterval)
+	}
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	t.Setenv("NODE_ID", "node2")
+	t.Setenv("RAFT_ADDR", "node2:9001")
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.NodeID != "node2" || cfg.Raft.AdvertiseAddr != "node2:9001" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadRaftTuningOverrides(t *testing.T) {
+	path := writeConfig(t, `
+node_id: node1
+raft:
+  advertise_addr: localhost:9001
+  heartbeat_timeout: 1s
+  election_timeout: 1s
+`)
+	t.Setenv("RAFT_HEARTBEAT_TIMEOUT", "2s")
+	t.Setenv("RAFT_ELECTION_TIMEOUT", "2s")
+	t.Setenv("RAFT_TRANSPORT_MAX_POOL", "5")
+	cfg, err := config.Load([]string{"-config", path, "-raft-election-timeout", "4s", "-raft-trailing-logs", "7", "-raft-membership-timeout", "30s"})
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.Raft.HeartbeatTimeout != 2*time.Second {
+		t.Errorf("expected env to override heartbeat timeout, got %s", cfg.Raft.HeartbeatTimeout)
+	}
+	if cfg.Raft.ElectionTimeout != 4*time.Second {
+		t.Errorf("expected flag to override env election timeout, got %s", cfg.Raft.ElectionTimeout)
+	}
+	if cfg.Raft.TransportMaxPool != 5 || cfg.Raft.TrailingLogs != 7 {
+		t.Errorf("unexpected overrides: pool %d trailing logs %d", cfg.Raft.TransportMaxPool, cfg.Raft.TrailingLogs)
+	}
+	if opts := cfg.RaftOptions(); opts.MembershipTimeout != 30*time.Second {
+		t.Errorf("expected the membership timeout to reach the raft options, got %s", opts.MembershipTimeout)
+	}
+}
+
+func TestLoadGCOverrides(t *testing.T) {
+	t.Setenv("NODE_ID", "node1")
+	t.Setenv("RAFT_ADDR", "localhost:9001")
+	t.Setenv("GC_RETENTION", "0")
+	cfg, err := config.Load([]string{"-gc-expiry-interval", "30s"})
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.GC.Retention != 0 {
+		t.Errorf("expected GC_RETENTION=0 to disable GC, got %s", cfg.GC.Retention)
+	}
+	if cfg.GC.ExpiryInterval != 30*time.Second {
+		t.Errorf("expected flag to set expiry interval, got %s", cfg.GC.ExpiryInterval)
+	}
+}
+
+func TestLoadInvalidOverride(t *testing.T) {
+	t.Setenv("NODE_ID", "node1")
+	t.Setenv("RAFT_ADDR", "localhost:9001")
+	t.Setenv("RAFT_COMMIT_TIMEOUT", "fast")
+	_, err := config.Load([]string{"-raft-snapshot-threshold", "-1"})
+	if err == nil {
+		t.Fatal("expected error for invalid overrides")
+	}
+	for _, want := range []string{"RAFT_COMMIT_TIMEOUT", "-raft-snapshot-threshold"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	path := writeConfig(t, "node_id: n1\nraft:\n  heartbeat: 1s\n")
+	if _, err := config.Load([]string{"-config", path}); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	path := writeConfig(t, `
+storage_engine: rocks
+grpc_port: "abc"
+raft:
+  advertise_addr: nohost
+  heartbeat_timeout: 1s
+  election_timeout: 500ms
+  membership_timeout: 0s
+`)
+	_, err := config.Load([]string{"-config", path})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"node_id is required", "storage_engine", "grpc_port", "raft.advertise_addr", "raft.election_timeout", "raft.membership_timeout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
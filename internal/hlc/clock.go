@@ -2,6 +2,7 @@ package hlc
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -37,5 +38,54 @@ func (c *Clock) Now() string {
 		c.logical++
 	}
 	// fixed width: 19 digits physical + 5 digits logical
-	return fmt.Sprintf("%019d%05d", c.lastPhysical, c.logical)
+	return format(c.lastPhysical, c.logical)
+}
+
+// FromTime returns the smallest HLC timestamp at physical time t, which sorts
+// before every timestamp issued at or after t.
+func FromTime(t time.Time) string {
+	return format(t.UnixNano(), 0)
+}
+
+// Update moves the clock forward to ts, if it is behind, so that every later
+// Now sorts after ts.
+func (c *Clock) Update(ts string) error {
+	physical, logical, err := parse(ts)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if physical > c.lastPhysical || (physical == c.lastPhysical && logical > c.logical) {
+		c.lastPhysical, c.logical = physical, logical
+	}
+	return nil
+}
+
+// Add returns ts moved d forward in physical time, keeping its logical counter.
+func Add(ts string, d time.Duration) (string, error) {
+	physical, logical, err := parse(ts)
+	if err != nil {
+		return "", err
+	}
+	return format(physical+int64(d), logical), nil
+}
+
+func parse(ts string) (physical int64, logical uint32, err error) {
+	if len(ts) != 24 {
+		return 0, 0, fmt.Errorf("malformed HLC timestamp %q", ts)
+	}
+	physical, err = strconv.ParseInt(ts[:19], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed HLC timestamp %q: %w", ts, err)
+	}
+	l, err := strconv.ParseUint(ts[19:], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed HLC timestamp %q: %w", ts, err)
+	}
+	return physical, uint32(l), nil
+}
+
+func format(physical int64, logical uint32) string {
+	return fmt.Sprintf("%019d%05d", physical, logical)
 }
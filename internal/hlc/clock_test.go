@@ -53,3 +53,50 @@ func TestNowAdvancesPhysical(t *testing.T) {
 		t.Errorf("expected physical advance, but physical parts equal: %s", first[:19])
 	}
 }
+
+func TestFromTime(t *testing.T) {
+	clk := hlc.NewClock()
+	before := hlc.FromTime(time.Now())
+	ts := clk.Now()
+	after := hlc.FromTime(time.Now().Add(time.Second))
+	if len(before) != len(ts) {
+		t.Fatalf("expected fixed width %d, got %q", len(ts), before)
+	}
+	if !(before <= ts && ts < after) {
+		t.Errorf("expected %s <= %s < %s", before, ts, after)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	got, err := hlc.Add("000000000000000001000003", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if want := "000000000200000001000003"; got != want {
+		t.Errorf("Add: got %s, want %s", got, want)
+	}
+	if _, err := hlc.Add("010", time.Second); err == nil {
+		t.Error("expected error for malformed timestamp")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clk := hlc.NewClock()
+	ahead := hlc.FromTime(time.Now().Add(time.Hour))
+	if err := clk.Update(ahead); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if ts := clk.Now(); ts <= ahead {
+		t.Errorf("expected %s after %s", ts, ahead)
+	}
+	// An older timestamp does not move the clock back
+	if err := clk.Update(hlc.FromTime(time.Now())); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if ts := clk.Now(); ts <= ahead {
+		t.Errorf("expected %s after %s", ts, ahead)
+	}
+	if err := clk.Update("010"); err == nil {
+		t.Error("expected error for malformed timestamp")
+	}
+}
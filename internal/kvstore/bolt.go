@@ -0,0 +1,1099 @@
+// internal/kvstore/bolt.go
+package kvstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// versionsBucket maps encodeVersionKey(key, timestamp, txID) to an encoded record
+	versionsBucket = []byte("versions")
+	// pendingBucket holds one sub-bucket per open transaction listing the
+	// version keys it wrote, so Commit and Abort need not scan all versions
+	pendingBucket = []byte("pending")
+	// metaBucket holds engine metadata such as the GC threshold
+	metaBucket = []byte("meta")
+	// expiringBucket indexes versions with an expiry by expiryIndexKey
+	expiringBucket = []byte("expiring")
+	// changesBucket indexes committed versions by changeIndexKey
+	changesBucket = []byte("changes")
+	// txnsBucket maps a transaction ID to its encodeTxnRecord record
+	txnsBucket = []byte("txns")
+)
+
+// boltSchemaKey names the layout version of the buckets in metaBucket.
+// Version 0 keyed versions by key and timestamp only, so two transactions
+// writing a key at the same timestamp shared one slot; version 1 appends the
+// transaction ID.
+const (
+	boltSchemaKey     = "schema_version"
+	boltSchemaVersion = "1"
+)
+
+// boltEngine is a pure-Go engine on top of an ordered BoltDB file. Versions
+// of a key sort by timestamp and then transaction ID, so reads are a seek
+// plus a short walk.
+type boltEngine struct {
+	db *bolt.DB
+}
+
+// NewBoltEngine opens (or creates) a BoltDB file at path.
+func NewBoltEngine(path string) (Engine, error) {
+	// A read transaction blocks remapping the file, so snapshots, which are
+	// read while the FSM keeps writing, would stall writes that grow the
+	// file past the mapping. Map generously up front instead; it is only
+	// address space.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, InitialMmapSize: 1 << 30})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{versionsBucket, pendingBucket, metaBucket, expiringBucket, changesBucket, txnsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return migrateBolt(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init buckets: %w", err)
+	}
+	return &boltEngine{db: db}, nil
+}
+
+// migrateBolt rewrites the buckets of an older layout as boltSchemaVersion
+func migrateBolt(tx *bolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+	if string(meta.Get([]byte(boltSchemaKey))) == boltSchemaVersion {
+		return nil
+	}
+	// decodeVersionKey also reads version 0 keys, which end with the timestamp
+	var versions []Version
+	err := tx.Bucket(versionsBucket).ForEach(func(k, record []byte) error {
+		v, err := decodeVersion(k, record)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := resetBuckets(tx); err != nil {
+		return err
+	}
+	if err := loadVersions(tx, versions); err != nil {
+		return err
+	}
+	return meta.Put([]byte(boltSchemaKey), []byte(boltSchemaVersion))
+}
+
+func (e *boltEngine) Close() error {
+	return e.db.Close()
+}
+
+func (e *boltEngine) Put(pairs []KeyValue, txID, timestamp string) error {
+	return e.put(pairs, txID, timestamp, false)
+}
+
+func (e *boltEngine) Delete(keys []string, txID, timestamp string) error {
+	pairs := make([]KeyValue, len(keys))
+	for i, key := range keys {
+		pairs[i] = KeyValue{Key: key}
+	}
+	return e.put(pairs, txID, timestamp, true)
+}
+
+func (e *boltEngine) put(pairs []KeyValue, txID, timestamp string, tombstone bool) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		pending, err := tx.Bucket(pendingBucket).CreateBucketIfNotExists([]byte(txID))
+		if err != nil {
+			return err
+		}
+		for _, kv := range pairs {
+			vk := encodeVersionKey(kv.Key, timestamp, txID)
+			v := Version{Value: kv.Value, TxID: txID, Tombstone: tombstone, ExpiresAt: kv.ExpiresAt}
+			if err := putVersion(tx, vk, v); err != nil {
+				return fmt.Errorf("failed to write %q: %w", kv.Key, err)
+			}
+			if err := pending.Put(vk, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (e *boltEngine) PutCommitted(versions []Version, seq uint64) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		for _, v := range versions {
+			v.Committed, v.CommitSeq = true, seq
+			if err := putVersion(tx, encodeVersionKey(v.Key, v.Timestamp, v.TxID), v); err != nil {
+				return fmt.Errorf("failed to write %q: %w", v.Key, err)
+			}
+		}
+		return tx.Bucket(metaBucket).Put([]byte(CommitSeqMeta), []byte(formatSeq(seq)))
+	})
+}
+
+func (e *boltEngine) Get(key, readTimestamp string) (Version, bool, error) {
+	var (
+		found Version
+		ok    bool
+	)
+	err := e.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKeyPrefix(key)
+		c := tx.Bucket(versionsBucket).Cursor()
+		// Position just past the newest version at or before readTimestamp,
+		// then walk back to the first committed one
+		k, record := c.Seek(seekPast(key, readTimestamp))
+		if k == nil {
+			k, record = c.Last()
+		} else {
+			k, record = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, record = c.Prev() {
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			if v.Committed {
+				found, ok = v, true
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, ok, err
+}
+
+func (e *boltEngine) Uncommitted(txID string) ([]Version, error) {
+	var found []Version
+	err := e.db.View(func(tx *bolt.Tx) error {
+		writes := tx.Bucket(pendingBucket).Bucket([]byte(txID))
+		if writes == nil {
+			return nil
+		}
+		versions := tx.Bucket(versionsBucket)
+		return writes.ForEach(func(vk, _ []byte) error {
+			record := versions.Get(vk)
+			if record == nil {
+				return nil
+			}
+			v, err := decodeVersion(vk, record)
+			if err != nil {
+				return err
+			}
+			if !v.Committed && v.TxID == txID {
+				found = append(found, v)
+			}
+			return nil
+		})
+	})
+	return found, err
+}
+
+func (e *boltEngine) Commit(rec TxnRecord, seq uint64) ([]Version, error) {
+	var committed []Version
+	err := e.db.Update(func(tx *bolt.Tx) error {
+		err := e.resolve(tx, rec.TxID, func(vk []byte, v Version) error {
+			v.Committed, v.CommitSeq = true, seq
+			committed = append(committed, v)
+			return putVersion(tx, vk, v)
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(txnsBucket).Put([]byte(rec.TxID), encodeTxnRecord(rec)); err != nil {
+			return err
+		}
+		if len(committed) == 0 {
+			return nil
+		}
+		return tx.Bucket(metaBucket).Put([]byte(CommitSeqMeta), []byte(formatSeq(seq)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return committed, nil
+}
+
+func (e *boltEngine) Abort(rec TxnRecord) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		err := e.resolve(tx, rec.TxID, func(vk []byte, v Version) error {
+			return deleteVersion(tx, vk, v)
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(txnsBucket).Put([]byte(rec.TxID), encodeTxnRecord(rec))
+	})
+}
+
+// resolve calls fn, in key order, for every uncommitted version written by
+// txID and then forgets the transaction
+func (e *boltEngine) resolve(tx *bolt.Tx, txID string, fn func(vk []byte, v Version) error) error {
+	pending := tx.Bucket(pendingBucket)
+	writes := pending.Bucket([]byte(txID))
+	if writes == nil {
+		return nil
+	}
+	versions := tx.Bucket(versionsBucket)
+	err := writes.ForEach(func(vk, _ []byte) error {
+		record := versions.Get(vk)
+		if record == nil {
+			return nil
+		}
+		v, err := decodeVersion(vk, record)
+		if err != nil {
+			return err
+		}
+		if v.Committed {
+			return nil
+		}
+		return fn(vk, v)
+	})
+	if err != nil {
+		return err
+	}
+	return pending.DeleteBucket([]byte(txID))
+}
+
+func (e *boltEngine) Scan(start, end, readTimestamp string, reverse bool, fn func(Version) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(versionsBucket).Cursor()
+		startPrefix := encodeKeyPrefix(start)
+		var endPrefix []byte
+		if end != "" {
+			endPrefix = encodeKeyPrefix(end)
+		}
+		if reverse {
+			return scanBackward(c, startPrefix, endPrefix, readTimestamp, fn)
+		}
+
+		var (
+			visible Version
+			has     bool
+		)
+		for k, record := c.Seek(startPrefix); k != nil; k, record = c.Next() {
+			if endPrefix != nil && bytes.Compare(k, endPrefix) >= 0 {
+				break
+			}
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			if has && v.Key != visible.Key {
+				if !fn(visible) {
+					return nil
+				}
+				has = false
+			}
+			// Versions arrive oldest first; keep the newest visible one
+			if v.Committed && v.Timestamp <= readTimestamp {
+				visible, has = v, true
+			}
+		}
+		if has {
+			fn(visible)
+		}
+		return nil
+	})
+}
+
+// scanBackward walks keys in [start, end) from the end. Versions of each key
+// arrive newest first, so the first visible one is reported.
+func scanBackward(c *bolt.Cursor, startPrefix, endPrefix []byte, readTimestamp string, fn func(Version) bool) error {
+	var k, record []byte
+	if endPrefix == nil {
+		k, record = c.Last()
+	} else if k, record = c.Seek(endPrefix); k == nil {
+		k, record = c.Last()
+	} else {
+		k, record = c.Prev()
+	}
+
+	var (
+		current string
+		done    bool // whether current already produced a version
+	)
+	for ; k != nil && bytes.Compare(k, startPrefix) >= 0; k, record = c.Prev() {
+		v, err := decodeVersion(k, record)
+		if err != nil {
+			return err
+		}
+		if v.Key != current {
+			current, done = v.Key, false
+		}
+		if done || !v.Committed || v.Timestamp > readTimestamp {
+			continue
+		}
+		done = true
+		if !fn(v) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (e *boltEngine) History(key, from, to string, fn func(Version) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKeyPrefix(key)
+		c := tx.Bucket(versionsBucket).Cursor()
+		// Timestamps are ASCII, so 0xFF sorts after every version of key
+		seek := append(prefix, 0xFF)
+		if to != "" {
+			seek = seekPast(key, to)
+		}
+		k, record := c.Seek(seek)
+		if k == nil {
+			k, record = c.Last()
+		} else {
+			k, record = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, record = c.Prev() {
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			if v.Timestamp < from {
+				return nil
+			}
+			if v.Committed && !fn(v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (e *boltEngine) Changes(start, end string, from uint64, fn func(Version) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucket)
+		startPrefix := encodeKeyPrefix(start)
+		var endPrefix []byte
+		if end != "" {
+			endPrefix = encodeKeyPrefix(end)
+		}
+		c := tx.Bucket(changesBucket).Cursor()
+		for ck, _ := c.Seek(changeIndexKey(from, nil)); ck != nil; ck, _ = c.Next() {
+			_, vk := decodeChangeIndexKey(ck)
+			if bytes.Compare(vk, startPrefix) < 0 || (endPrefix != nil && bytes.Compare(vk, endPrefix) >= 0) {
+				continue
+			}
+			record := versions.Get(vk)
+			if record == nil {
+				return fmt.Errorf("change index entry %q has no version", ck)
+			}
+			v, err := decodeVersion(vk, record)
+			if err != nil {
+				return err
+			}
+			if !fn(v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (e *boltEngine) Since(start, end, timestamp string, fn func(Version) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(versionsBucket).Cursor()
+		var endPrefix []byte
+		if end != "" {
+			endPrefix = encodeKeyPrefix(end)
+		}
+		for k, record := c.Seek(encodeKeyPrefix(start)); k != nil && (endPrefix == nil || bytes.Compare(k, endPrefix) < 0); k, record = c.Next() {
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			if v.Committed && v.Timestamp >= timestamp && !fn(v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (e *boltEngine) Newer(keys []string, timestamp string, fn func(Version) bool) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(versionsBucket).Cursor()
+		for _, key := range keys {
+			prefix := encodeKeyPrefix(key)
+			for k, record := c.Seek(seekPast(key, timestamp)); k != nil && bytes.HasPrefix(k, prefix); k, record = c.Next() {
+				v, err := decodeVersion(k, record)
+				if err != nil {
+					return err
+				}
+				if v.Committed && !fn(v) {
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (e *boltEngine) Versions() ([]Version, error) {
+	var versions []Version
+	err := e.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(versionsBucket).Cursor()
+		for k, record := c.First(); k != nil; k, record = c.Next() {
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			versions = append(versions, v)
+		}
+		return nil
+	})
+	return versions, err
+}
+
+func (e *boltEngine) PutTxn(rec TxnRecord) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(txnsBucket).Put([]byte(rec.TxID), encodeTxnRecord(rec))
+	})
+}
+
+func (e *boltEngine) GetTxn(txID string) (TxnRecord, bool, error) {
+	var (
+		rec TxnRecord
+		ok  bool
+	)
+	err := e.db.View(func(tx *bolt.Tx) error {
+		record := tx.Bucket(txnsBucket).Get([]byte(txID))
+		if record == nil {
+			return nil
+		}
+		var err error
+		rec, err = decodeTxnRecord([]byte(txID), record)
+		ok = err == nil
+		return err
+	})
+	return rec, ok, err
+}
+
+func (e *boltEngine) Snapshot() (EngineSnapshot, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readBoltMeta(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &boltSnapshot{tx: tx, meta: meta}, nil
+}
+
+// boltSnapshot reads from a read-only transaction, which sees the file as
+// of its start
+type boltSnapshot struct {
+	tx   *bolt.Tx
+	meta map[string]string
+}
+
+func (s *boltSnapshot) Meta() map[string]string {
+	return s.meta
+}
+
+func (s *boltSnapshot) Versions(fn func(Version) error) error {
+	c := s.tx.Bucket(versionsBucket).Cursor()
+	for k, record := c.First(); k != nil; k, record = c.Next() {
+		v, err := decodeVersion(k, record)
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *boltSnapshot) Txns(fn func(TxnRecord) error) error {
+	return s.tx.Bucket(txnsBucket).ForEach(func(txID, record []byte) error {
+		rec, err := decodeTxnRecord(txID, record)
+		if err != nil {
+			return err
+		}
+		return fn(rec)
+	})
+}
+
+func (s *boltSnapshot) Close() error {
+	return s.tx.Rollback()
+}
+
+func (e *boltEngine) ReplaceAll(src SnapshotSource) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		if err := resetBuckets(tx); err != nil {
+			return err
+		}
+		for {
+			versions, err := src.NextVersions()
+			if err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				break
+			}
+			if err := loadVersions(tx, versions); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteBucket(txnsBucket); err != nil {
+			return err
+		}
+		records, err := tx.CreateBucket(txnsBucket)
+		if err != nil {
+			return err
+		}
+		for {
+			txns, err := src.NextTxns()
+			if err != nil {
+				return err
+			}
+			if len(txns) == 0 {
+				break
+			}
+			for _, rec := range txns {
+				if err := records.Put([]byte(rec.TxID), encodeTxnRecord(rec)); err != nil {
+					return err
+				}
+			}
+		}
+		meta, err := src.Meta()
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(metaBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(boltSchemaKey), []byte(boltSchemaVersion)); err != nil {
+			return err
+		}
+		for name, value := range meta {
+			if err := bucket.Put([]byte(name), []byte(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// resetBuckets empties every bucket holding versions or indexing them
+func resetBuckets(tx *bolt.Tx) error {
+	for _, name := range [][]byte{versionsBucket, pendingBucket, expiringBucket, changesBucket} {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadVersions stores versions, committed or not, into empty buckets
+func loadVersions(tx *bolt.Tx, versions []Version) error {
+	for _, v := range versions {
+		vk := encodeVersionKey(v.Key, v.Timestamp, v.TxID)
+		if err := putVersion(tx, vk, v); err != nil {
+			return fmt.Errorf("failed to restore version of %q: %w", v.Key, err)
+		}
+		if v.Committed {
+			continue
+		}
+		pending, err := tx.Bucket(pendingBucket).CreateBucketIfNotExists([]byte(v.TxID))
+		if err != nil {
+			return err
+		}
+		if err := pending.Put(vk, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *boltEngine) CollectGarbage(threshold string) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucket)
+		pending := tx.Bucket(pendingBucket)
+
+		// Deleting while a cursor walks the bucket can skip entries, so
+		// collect the garbage first
+		type entry struct {
+			vk []byte
+			v  Version
+		}
+		var (
+			garbage   []entry
+			abandoned = map[string][]entry{} // by txID
+			newest    *entry                 // newest committed version of the current key at threshold
+			current   string
+		)
+		keep := func() {
+			if newest != nil && newest.v.deletedAt(threshold) {
+				garbage = append(garbage, *newest)
+			}
+			newest = nil
+		}
+		c := versions.Cursor()
+		for k, record := c.First(); k != nil; k, record = c.Next() {
+			v, err := decodeVersion(k, record)
+			if err != nil {
+				return err
+			}
+			if v.Key != current {
+				keep()
+				current = v.Key
+			}
+			if v.Timestamp > threshold {
+				continue
+			}
+			e := entry{vk: append([]byte(nil), k...), v: v}
+			switch {
+			case !v.Committed:
+				abandoned[v.TxID] = append(abandoned[v.TxID], e)
+			case newest != nil:
+				// Versions arrive oldest first, so the previous one is shadowed
+				garbage = append(garbage, *newest)
+				newest = &e
+			default:
+				newest = &e
+			}
+		}
+		keep()
+
+		if err := collectTxns(tx, threshold); err != nil {
+			return err
+		}
+
+		meta := tx.Bucket(metaBucket)
+		gcSeq, err := parseSeq(string(meta.Get([]byte(GCSeqMeta))))
+		if err != nil {
+			return err
+		}
+		for _, e := range garbage {
+			if err := deleteVersion(tx, e.vk, e.v); err != nil {
+				return err
+			}
+			gcSeq = max(gcSeq, e.v.CommitSeq)
+		}
+		for txID, entries := range abandoned {
+			for _, e := range entries {
+				if err := deleteVersion(tx, e.vk, e.v); err != nil {
+					return err
+				}
+			}
+			writes := pending.Bucket([]byte(txID))
+			if writes == nil {
+				continue
+			}
+			for _, e := range entries {
+				if err := writes.Delete(e.vk); err != nil {
+					return err
+				}
+			}
+			if k, _ := writes.Cursor().First(); k == nil {
+				if err := pending.DeleteBucket([]byte(txID)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := meta.Put([]byte(GCSeqMeta), []byte(formatSeq(gcSeq))); err != nil {
+			return err
+		}
+		return meta.Put([]byte(GCThresholdMeta), []byte(threshold))
+	})
+}
+
+// collectTxns removes the records of transactions started at or before threshold
+func collectTxns(tx *bolt.Tx, threshold string) error {
+	records := tx.Bucket(txnsBucket)
+	var garbage [][]byte
+	err := records.ForEach(func(txID, record []byte) error {
+		rec, err := decodeTxnRecord(txID, record)
+		if err != nil {
+			return err
+		}
+		if rec.StartTimestamp <= threshold {
+			garbage = append(garbage, append([]byte(nil), txID...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, txID := range garbage {
+		if err := records.Delete(txID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *boltEngine) Expired(now string, limit int) ([]Version, error) {
+	var expired []Version
+	err := e.db.View(func(tx *bolt.Tx) error {
+		versions := tx.Bucket(versionsBucket)
+		c := tx.Bucket(expiringBucket).Cursor()
+		for ik, _ := c.First(); ik != nil && len(expired) < limit; ik, _ = c.Next() {
+			expiresAt, vk := decodeExpiryIndexKey(ik)
+			if expiresAt > now {
+				break
+			}
+			record := versions.Get(vk)
+			if record == nil {
+				continue
+			}
+			v, err := decodeVersion(vk, record)
+			if err != nil {
+				return err
+			}
+			if !v.Committed || v.Tombstone {
+				continue
+			}
+			newer, err := hasNewerCommitted(versions.Cursor(), vk)
+			if err != nil {
+				return err
+			}
+			if !newer {
+				expired = append(expired, v)
+			}
+		}
+		return nil
+	})
+	return expired, err
+}
+
+// hasNewerCommitted reports whether the key of version key vk has a committed
+// version newer than vk
+func hasNewerCommitted(c *bolt.Cursor, vk []byte) (bool, error) {
+	key, _, err := decodeVersionKey(vk)
+	if err != nil {
+		return false, err
+	}
+	prefix := encodeKeyPrefix(key)
+	c.Seek(vk)
+	for k, record := c.Next(); k != nil && bytes.HasPrefix(k, prefix); k, record = c.Next() {
+		if record[0]&recordCommitted != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *boltEngine) Meta() (map[string]string, error) {
+	var meta map[string]string
+	err := e.db.View(func(tx *bolt.Tx) error {
+		var err error
+		meta, err = readBoltMeta(tx)
+		return err
+	})
+	return meta, err
+}
+
+func (e *boltEngine) PutMeta(name, value string) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put([]byte(name), []byte(value))
+	})
+}
+
+// readBoltMeta returns the engine metadata, leaving out the layout version
+func readBoltMeta(tx *bolt.Tx) (map[string]string, error) {
+	meta := make(map[string]string)
+	err := tx.Bucket(metaBucket).ForEach(func(name, value []byte) error {
+		if string(name) != boltSchemaKey {
+			meta[string(name)] = string(value)
+		}
+		return nil
+	})
+	return meta, err
+}
+
+// putVersion stores v under version key vk, replacing the version stored
+// there if any, and indexes its expiry and commit sequence
+func putVersion(tx *bolt.Tx, vk []byte, v Version) error {
+	versions := tx.Bucket(versionsBucket)
+	if record := versions.Get(vk); record != nil {
+		old, err := decodeRecord(record)
+		if err != nil {
+			return err
+		}
+		if err := deleteVersion(tx, vk, old); err != nil {
+			return err
+		}
+	}
+	if err := versions.Put(vk, encodeRecord(v)); err != nil {
+		return err
+	}
+	if v.CommitSeq != 0 {
+		if err := tx.Bucket(changesBucket).Put(changeIndexKey(v.CommitSeq, vk), nil); err != nil {
+			return err
+		}
+	}
+	if v.ExpiresAt == "" {
+		return nil
+	}
+	return tx.Bucket(expiringBucket).Put(expiryIndexKey(v.ExpiresAt, vk), nil)
+}
+
+// deleteVersion removes the version v stored under vk and its index entries
+func deleteVersion(tx *bolt.Tx, vk []byte, v Version) error {
+	if err := tx.Bucket(versionsBucket).Delete(vk); err != nil {
+		return err
+	}
+	if v.CommitSeq != 0 {
+		if err := tx.Bucket(changesBucket).Delete(changeIndexKey(v.CommitSeq, vk)); err != nil {
+			return err
+		}
+	}
+	if v.ExpiresAt == "" {
+		return nil
+	}
+	return tx.Bucket(expiringBucket).Delete(expiryIndexKey(v.ExpiresAt, vk))
+}
+
+// expiryIndexKey sorts index entries by expiry. Timestamps never contain
+// 0x00, so it separates the expiry from the version key.
+func expiryIndexKey(expiresAt string, vk []byte) []byte {
+	buf := make([]byte, 0, len(expiresAt)+1+len(vk))
+	buf = append(buf, expiresAt...)
+	buf = append(buf, 0)
+	return append(buf, vk...)
+}
+
+func decodeExpiryIndexKey(ik []byte) (expiresAt string, vk []byte) {
+	i := bytes.IndexByte(ik, 0)
+	return string(ik[:i]), ik[i+1:]
+}
+
+// changeIndexKey sorts index entries by commit sequence, as 8 big-endian
+// bytes, and then by version key
+func changeIndexKey(seq uint64, vk []byte) []byte {
+	buf := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(vk)), seq)
+	return append(buf, vk...)
+}
+
+func decodeChangeIndexKey(ck []byte) (seq uint64, vk []byte) {
+	return binary.BigEndian.Uint64(ck), ck[8:]
+}
+
+// encodeKeyPrefix escapes key so that it sorts like the raw key and can be
+// followed by a timestamp: 0x00 becomes 0x00 0xFF and the key ends with 0x00 0x01.
+func encodeKeyPrefix(key string) []byte {
+	buf := make([]byte, 0, len(key)+2)
+	for i := 0; i < len(key); i++ {
+		if key[i] == 0 {
+			buf = append(buf, 0, 0xFF)
+		} else {
+			buf = append(buf, key[i])
+		}
+	}
+	return append(buf, 0, 1)
+}
+
+// encodeVersionKey orders the versions of a key by timestamp and then by
+// transaction ID. Neither contains 0x00, which separates them.
+func encodeVersionKey(key, timestamp, txID string) []byte {
+	buf := append(encodeKeyPrefix(key), timestamp...)
+	buf = append(buf, 0)
+	return append(buf, txID...)
+}
+
+// seekPast returns the smallest possible version key after every version of
+// key at or before timestamp
+func seekPast(key, timestamp string) []byte {
+	buf := append(encodeKeyPrefix(key), timestamp...)
+	return append(buf, 1)
+}
+
+// decodeVersionKey splits a version key into the user key and timestamp. The
+// transaction ID is read from the record instead.
+func decodeVersionKey(vk []byte) (key, timestamp string, err error) {
+	buf := make([]byte, 0, len(vk))
+	for i := 0; i < len(vk); i++ {
+		if vk[i] != 0 {
+			buf = append(buf, vk[i])
+			continue
+		}
+		if i+1 >= len(vk) {
+			break
+		}
+		switch vk[i+1] {
+		case 0xFF:
+			buf = append(buf, 0)
+			i++
+		case 1:
+			rest := vk[i+2:]
+			if j := bytes.IndexByte(rest, 0); j >= 0 {
+				rest = rest[:j]
+			}
+			return string(buf), string(rest), nil
+		default:
+			return "", "", fmt.Errorf("malformed version key %q", vk)
+		}
+	}
+	return "", "", fmt.Errorf("malformed version key %q", vk)
+}
+
+// decodeVersion decodes a version key and its record
+func decodeVersion(k, record []byte) (Version, error) {
+	key, ts, err := decodeVersionKey(k)
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := decodeRecord(record)
+	if err != nil {
+		return Version{}, err
+	}
+	v.Key, v.Timestamp = key, ts
+	return v, nil
+}
+
+// Flags of the first byte of a record
+const (
+	recordCommitted byte = 1 << iota
+	recordTombstone
+	recordExpires
+	recordSequenced
+)
+
+// encodeRecord lays out the value, transaction and state of a version as:
+// flags, uvarint length of txID, txID, [uvarint length of expiry, expiry],
+// [uvarint commit sequence], value. The expiry is present only with
+// recordExpires and the commit sequence only with recordSequenced.
+func encodeRecord(v Version) []byte {
+	buf := make([]byte, 1, 1+3*binary.MaxVarintLen64+len(v.TxID)+len(v.ExpiresAt)+len(v.Value))
+	if v.Committed {
+		buf[0] |= recordCommitted
+	}
+	if v.Tombstone {
+		buf[0] |= recordTombstone
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(v.TxID)))
+	buf = append(buf, v.TxID...)
+	if v.ExpiresAt != "" {
+		buf[0] |= recordExpires
+		buf = binary.AppendUvarint(buf, uint64(len(v.ExpiresAt)))
+		buf = append(buf, v.ExpiresAt...)
+	}
+	if v.CommitSeq != 0 {
+		buf[0] |= recordSequenced
+		buf = binary.AppendUvarint(buf, v.CommitSeq)
+	}
+	return append(buf, v.Value...)
+}
+
+// decodeRecord returns the version stored in record, without key and timestamp
+func decodeRecord(record []byte) (Version, error) {
+	if len(record) < 1 {
+		return Version{}, fmt.Errorf("empty version record")
+	}
+	v := Version{
+		Committed: record[0]&recordCommitted != 0,
+		Tombstone: record[0]&recordTombstone != 0,
+	}
+	txID, rest, err := readField(record[1:])
+	if err != nil {
+		return Version{}, err
+	}
+	v.TxID = txID
+	if record[0]&recordExpires != 0 {
+		if v.ExpiresAt, rest, err = readField(rest); err != nil {
+			return Version{}, err
+		}
+	}
+	if record[0]&recordSequenced != 0 {
+		n, size := binary.Uvarint(rest)
+		if size <= 0 {
+			return Version{}, fmt.Errorf("malformed version record")
+		}
+		v.CommitSeq, rest = n, rest[size:]
+	}
+	v.Value = string(rest)
+	return v, nil
+}
+
+// readField reads a uvarint length-prefixed field and returns the remainder
+func readField(buf []byte) (string, []byte, error) {
+	n, size := binary.Uvarint(buf)
+	if size <= 0 || uint64(len(buf)-size) < n {
+		return "", nil, fmt.Errorf("malformed version record")
+	}
+	buf = buf[size:]
+	return string(buf[:n]), buf[n:], nil
+}
+
+// encodeTxnRecord lays out a transaction record as: uvarint length of the
+// start timestamp, start timestamp, uvarint start sequence, uvarint
+// isolation, uvarint status, then the owner and the commit timestamp, each
+// preceded by its uvarint length. Records written before isolation existed
+// end after the start sequence and are SnapshotIsolation; those written
+// before the status existed end after the isolation and are pending.
+func encodeTxnRecord(rec TxnRecord) []byte {
+	buf := make([]byte, 0, 6*binary.MaxVarintLen64+len(rec.StartTimestamp)+len(rec.Owner)+len(rec.CommitTimestamp))
+	buf = binary.AppendUvarint(buf, uint64(len(rec.StartTimestamp)))
+	buf = append(buf, rec.StartTimestamp...)
+	buf = binary.AppendUvarint(buf, rec.StartSeq)
+	buf = binary.AppendUvarint(buf, uint64(rec.Isolation))
+	buf = binary.AppendUvarint(buf, uint64(rec.Status))
+	buf = binary.AppendUvarint(buf, uint64(len(rec.Owner)))
+	buf = append(buf, rec.Owner...)
+	buf = binary.AppendUvarint(buf, uint64(len(rec.CommitTimestamp)))
+	return append(buf, rec.CommitTimestamp...)
+}
+
+// decodeTxnRecord returns the record of transaction txID stored in record
+func decodeTxnRecord(txID, record []byte) (TxnRecord, error) {
+	rec := TxnRecord{TxID: string(txID)}
+	startTs, rest, err := readField(record)
+	if err != nil {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	rec.StartTimestamp = startTs
+	n, size := binary.Uvarint(rest)
+	if size <= 0 {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	rec.StartSeq = n
+	if rest = rest[size:]; len(rest) == 0 {
+		return rec, nil
+	}
+	isolation, size := binary.Uvarint(rest)
+	if size <= 0 {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	rec.Isolation = Isolation(isolation)
+	if rest = rest[size:]; len(rest) == 0 {
+		return rec, nil
+	}
+	txnStatus, size := binary.Uvarint(rest)
+	if size <= 0 {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	rec.Status = TxnStatus(txnStatus)
+	if rec.Owner, rest, err = readField(rest[size:]); err != nil {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	if rec.CommitTimestamp, _, err = readField(rest); err != nil {
+		return TxnRecord{}, fmt.Errorf("malformed transaction record")
+	}
+	return rec, nil
+}
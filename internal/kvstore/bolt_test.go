@@ -0,0 +1,75 @@
+package kvstore_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrateBoltLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Build a file the way releases before the transaction ID joined the
+	// version key did: escaped key, 0x00 0x01, timestamp
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		versions, err := tx.CreateBucket([]byte("versions"))
+		if err != nil {
+			return err
+		}
+		pending, err := tx.CreateBucket([]byte("pending"))
+		if err != nil {
+			return err
+		}
+		writes, err := pending.CreateBucket([]byte("tx2"))
+		if err != nil {
+			return err
+		}
+		// Records: flags (1 = committed), txID length, txID, value
+		if err := versions.Put([]byte("k\x00\x01010"), []byte("\x01\x03tx1v1")); err != nil {
+			return err
+		}
+		if err := versions.Put([]byte("k\x00\x01020"), []byte("\x00\x03tx2v2")); err != nil {
+			return err
+		}
+		return writes.Put([]byte("k\x00\x01020"), nil)
+	})
+	if err != nil {
+		t.Fatalf("legacy setup error: %v", err)
+	}
+	db.Close()
+
+	store, err := kvstore.Open(kvstore.EngineBolt, path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer store.Close()
+	if got, _ := store.Read("k", "999"); got != "v1" {
+		t.Errorf("expected migrated committed value v1, got %q", got)
+	}
+	// A pending write from before transaction records has none to commit by
+	if err := store.Commit("tx2"); !errors.Is(err, kvstore.ErrTxnNotFound) {
+		t.Fatalf("expected ErrTxnNotFound committing a legacy write, got %v", err)
+	}
+	if got, _ := store.Read("k", "999"); got != "v1" {
+		t.Errorf("expected the legacy pending write to stay invisible, got %q", got)
+	}
+	versions, err := store.Versions()
+	if err != nil {
+		t.Fatalf("Versions error: %v", err)
+	}
+	want := []kvstore.Version{
+		{Key: "k", Value: "v1", Timestamp: "010", TxID: "tx1", Committed: true},
+		{Key: "k", Value: "v2", Timestamp: "020", TxID: "tx2"},
+	}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("versions after migration:\ngot  %+v\nwant %+v", versions, want)
+	}
+}
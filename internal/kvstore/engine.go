@@ -0,0 +1,211 @@
+// internal/kvstore/engine.go
+package kvstore
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Engine is the storage backend behind Store. An engine keeps every version
+// of every key; a version becomes visible to reads once the transaction that
+// wrote it commits. Timestamps are compared as strings, so callers must use a
+// fixed-width format such as the one produced by hlc.Clock. Versions of a key
+// at the same timestamp are ordered by transaction ID, so the newest of them
+// is the one with the greatest ID.
+type Engine interface {
+	// Put stores pairs as uncommitted versions written by txID at timestamp,
+	// with their expiry if set. Keys must be unique within pairs.
+	Put(pairs []KeyValue, txID, timestamp string) error
+	// Delete stores a tombstone for each key as an uncommitted version
+	// written by txID at timestamp. Keys must be unique.
+	Delete(keys []string, txID, timestamp string) error
+	// PutCommitted stores versions as committed at commit sequence seq in a
+	// single transaction, replacing any version with the same key, timestamp
+	// and transaction, and records seq as the last commit sequence.
+	PutCommitted(versions []Version, seq uint64) error
+	// Get returns the newest committed version of key at or before
+	// readTimestamp, which may be a tombstone.
+	Get(key, readTimestamp string) (Version, bool, error)
+	// Uncommitted returns the versions written by txID that are not
+	// committed yet, ordered by key.
+	Uncommitted(txID string) ([]Version, error)
+	// Commit makes every uncommitted version written by rec.TxID visible at
+	// commit sequence seq and returns them, ordered by key. It stores rec,
+	// and if there are any versions records seq as the last commit sequence,
+	// in the same transaction.
+	Commit(rec TxnRecord, seq uint64) ([]Version, error)
+	// Abort discards the uncommitted versions written by rec.TxID and
+	// stores rec in a single transaction.
+	Abort(rec TxnRecord) error
+	// Scan calls fn in key order, or reverse key order, with the newest
+	// committed version at or before readTimestamp, tombstones included, of
+	// every key in [start, end). An empty end means no upper bound. Scan stops early when
+	// fn returns false.
+	Scan(start, end, readTimestamp string, reverse bool, fn func(Version) bool) error
+	// History calls fn, newest first, with every committed version of key,
+	// tombstones included, whose timestamp is in [from, to]. An empty to
+	// means no upper bound. History stops early when fn returns false.
+	History(key, from, to string, fn func(Version) bool) error
+	// Changes calls fn with every committed version, tombstones included, of
+	// every key in [start, end) whose commit sequence is at or after from,
+	// ordered by commit sequence and then key. An empty end means no upper
+	// bound. from must be at least 1. Changes stops early when fn returns false.
+	Changes(start, end string, from uint64, fn func(Version) bool) error
+	// Since calls fn, in key order, with every committed version,
+	// tombstones included, of every key in [start, end) whose timestamp is
+	// at or after timestamp. An empty end means no upper bound. Since stops
+	// early when fn returns false.
+	Since(start, end, timestamp string, fn func(Version) bool) error
+	// Newer calls fn, in key order, with every committed version, tombstones
+	// included, of each of keys whose timestamp is after timestamp. keys must
+	// be sorted and unique. Newer stops early when fn returns false.
+	Newer(keys []string, timestamp string, fn func(Version) bool) error
+	// Versions returns every version, committed or not, ordered by key,
+	// timestamp and transaction ID.
+	Versions() ([]Version, error)
+	// PutTxn stores the record of a transaction, replacing the previous one.
+	PutTxn(rec TxnRecord) error
+	// GetTxn returns the record of txID.
+	GetTxn(txID string) (TxnRecord, bool, error)
+	// Snapshot returns a read-only view of the versions, transaction records
+	// and metadata as of the call. Writes may continue while it is read; it
+	// must be closed.
+	Snapshot() (EngineSnapshot, error)
+	// ReplaceAll atomically replaces the versions, transaction records and
+	// metadata of the engine with those read from src, a batch at a time.
+	ReplaceAll(src SnapshotSource) error
+	// CollectGarbage removes every version that no read at or after threshold
+	// can observe: committed versions shadowed by a newer committed version at
+	// or before threshold, tombstones and versions expired by threshold that
+	// are the newest such version, and
+	// uncommitted versions at or before threshold left by abandoned
+	// transactions, along with the records of transactions started at or
+	// before threshold. It records threshold, and the greatest commit
+	// sequence removed so far, atomically with the removal.
+	CollectGarbage(threshold string) error
+	// Meta returns the engine metadata, such as the GC threshold, by name.
+	Meta() (map[string]string, error)
+	// PutMeta sets the engine metadata name to value.
+	PutMeta(name, value string) error
+	// Expired returns up to limit committed, non-tombstone versions that are
+	// the newest committed version of their key and expire at or before now.
+	Expired(now string, limit int) ([]Version, error)
+	Close() error
+}
+
+// EngineSnapshot is a consistent point-in-time view of an engine
+type EngineSnapshot interface {
+	// Meta returns the engine metadata, such as the GC threshold, by name
+	Meta() map[string]string
+	// Versions calls fn with every version, committed or not, ordered by
+	// key, timestamp and transaction ID, and stops at the first error.
+	Versions(fn func(Version) error) error
+	// Txns calls fn with every transaction record, ordered by transaction
+	// ID, and stops at the first error.
+	Txns(fn func(TxnRecord) error) error
+	Close() error
+}
+
+// SnapshotSource supplies the contents installed by ReplaceAll in batches,
+// so that a large snapshot never has to fit in memory. The versions are read
+// first, then the transaction records, then the metadata.
+type SnapshotSource interface {
+	// NextVersions returns the next batch of versions, or an empty batch
+	// once there are no more.
+	NextVersions() ([]Version, error)
+	// NextTxns returns the next batch of transaction records, or an empty
+	// batch once there are no more.
+	NextTxns() ([]TxnRecord, error)
+	// Meta returns the engine metadata, such as the GC threshold.
+	Meta() (map[string]string, error)
+}
+
+// NewSnapshotSource returns a SnapshotSource reading versions, txns and meta
+// in a single batch each
+func NewSnapshotSource(versions []Version, txns []TxnRecord, meta map[string]string) SnapshotSource {
+	return &sliceSource{versions: versions, txns: txns, meta: meta}
+}
+
+type sliceSource struct {
+	versions []Version
+	txns     []TxnRecord
+	meta     map[string]string
+}
+
+func (s *sliceSource) NextVersions() ([]Version, error) {
+	batch := s.versions
+	s.versions = nil
+	return batch, nil
+}
+
+func (s *sliceSource) NextTxns() ([]TxnRecord, error) {
+	batch := s.txns
+	s.txns = nil
+	return batch, nil
+}
+
+func (s *sliceSource) Meta() (map[string]string, error) {
+	return s.meta, nil
+}
+
+// Names of engine metadata
+const (
+	// GCThresholdMeta is the threshold of the last CollectGarbage
+	GCThresholdMeta = "gc_threshold"
+	// CommitSeqMeta is the commit sequence of the last commit
+	CommitSeqMeta = "commit_seq"
+	// GCSeqMeta is the greatest commit sequence of a version removed by
+	// CollectGarbage, below which changes can no longer be replayed
+	GCSeqMeta = "gc_seq"
+	// NodeAddrMetaPrefix followed by a node ID names the gRPC address of
+	// the node
+	NodeAddrMetaPrefix = "node_addr/"
+)
+
+// parseSeq reads a commit sequence stored in engine metadata; "" is 0
+func parseSeq(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed commit sequence %q", s)
+	}
+	return seq, nil
+}
+
+// formatSeq is the inverse of parseSeq
+func formatSeq(seq uint64) string {
+	return strconv.FormatUint(seq, 10)
+}
+
+// Engine names accepted by Open
+const (
+	EngineSQLite = "sqlite"
+	EngineBolt   = "bolt"
+)
+
+// Open creates a Store backed by the named engine at path.
+func Open(engine, path string) (*Store, error) {
+	var (
+		e   Engine
+		err error
+	)
+	switch engine {
+	case EngineSQLite, "":
+		e, err = NewSQLiteEngine(path)
+	case EngineBolt:
+		e, err = NewBoltEngine(path)
+	default:
+		return nil, fmt.Errorf("unknown storage engine %q, expected %s or %s", engine, EngineSQLite, EngineBolt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStoreWithEngine(e)
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	return store, nil
+}
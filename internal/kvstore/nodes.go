@@ -0,0 +1,43 @@
+// internal/kvstore/nodes.go
+package kvstore
+
+import "strings"
+
+// RegisterNode records grpcAddr as the gRPC address of the node with the
+// given ID, replacing the previous one. The directory is kept in engine
+// metadata, so it is replicated through the Raft log and snapshots like the
+// data, and lets followers reach a leader that joined after they started.
+func (s *Store) RegisterNode(id, grpcAddr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nodes[id] == grpcAddr {
+		return nil
+	}
+	if err := s.engine.PutMeta(NodeAddrMetaPrefix+id, grpcAddr); err != nil {
+		return err
+	}
+	if s.nodes == nil {
+		s.nodes = make(map[string]string)
+	}
+	s.nodes[id] = grpcAddr
+	return nil
+}
+
+// NodeAddress returns the registered gRPC address of the node with the given ID
+func (s *Store) NodeAddress(id string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	addr, ok := s.nodes[id]
+	return addr, ok
+}
+
+// nodeAddrs extracts the node directory from engine metadata
+func nodeAddrs(meta map[string]string) map[string]string {
+	nodes := make(map[string]string)
+	for name, value := range meta {
+		if id, ok := strings.CutPrefix(name, NodeAddrMetaPrefix); ok {
+			nodes[id] = value
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,761 @@
+// internal/kvstore/sqlite.go
+package kvstore
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteEngine stores versions as rows of a single kv table
+type sqliteEngine struct {
+	db *sql.DB
+}
+
+// NewSQLiteEngine opens (or creates) a SQLite database at path.
+func NewSQLiteEngine(path string) (Engine, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Validate connection
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// In WAL mode a snapshot's read transaction does not block the writes
+	// the FSM keeps applying meanwhile
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+		return nil, fmt.Errorf("failed to enable WAL: %w", err)
+	}
+
+	e := &sqliteEngine{db: db}
+
+	// Now safely initialize schema
+	if err := e.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to init schema: %w", err)
+	}
+
+	return e, nil
+}
+
+// migrations upgrade the schema one step at a time: migrations[i] moves a
+// database from user_version i to i+1. Released migrations must never be
+// edited; append a new one instead.
+var migrations = []string{
+	// 1: original unindexed table
+	`CREATE TABLE IF NOT EXISTS kv (
+		key TEXT,
+		value TEXT,
+		timestamp TEXT,
+		tx_id TEXT,
+		is_committed BOOLEAN
+	);`,
+	// 2: primary key on (key, timestamp, tx_id), which also serves reads of a
+	// key at a timestamp, and an index for Commit/Abort by transaction.
+	// Exact duplicate versions left by older builds collapse into one row.
+	`CREATE TABLE kv_v2 (
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		timestamp TEXT NOT NULL,
+		tx_id TEXT NOT NULL,
+		is_committed BOOLEAN NOT NULL DEFAULT false,
+		PRIMARY KEY (key, timestamp, tx_id)
+	) WITHOUT ROWID;
+	INSERT OR REPLACE INTO kv_v2 (key, value, timestamp, tx_id, is_committed)
+		SELECT key, COALESCE(value, ''), COALESCE(timestamp, ''), COALESCE(tx_id, ''), COALESCE(is_committed, false)
+		FROM kv WHERE key IS NOT NULL;
+	DROP TABLE kv;
+	ALTER TABLE kv_v2 RENAME TO kv;
+	CREATE INDEX kv_tx_id ON kv (tx_id, is_committed);`,
+	// 3: tombstone versions written by deletes
+	`ALTER TABLE kv ADD COLUMN is_tombstone BOOLEAN NOT NULL DEFAULT false;`,
+	// 4: engine metadata such as the GC threshold
+	`CREATE TABLE meta (
+		name TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	) WITHOUT ROWID;`,
+	// 5: binary-safe keys and values. SQLite sorts every TEXT value before
+	// every BLOB, so existing text is converted rather than mixed with blobs.
+	`CREATE TABLE kv_v5 (
+		key BLOB NOT NULL,
+		value BLOB NOT NULL,
+		timestamp TEXT NOT NULL,
+		tx_id TEXT NOT NULL,
+		is_committed BOOLEAN NOT NULL DEFAULT false,
+		is_tombstone BOOLEAN NOT NULL DEFAULT false,
+		PRIMARY KEY (key, timestamp, tx_id)
+	) WITHOUT ROWID;
+	INSERT INTO kv_v5 (key, value, timestamp, tx_id, is_committed, is_tombstone)
+		SELECT CAST(key AS BLOB), CAST(value AS BLOB), timestamp, tx_id, is_committed, is_tombstone FROM kv;
+	DROP TABLE kv;
+	ALTER TABLE kv_v5 RENAME TO kv;
+	CREATE INDEX kv_tx_id ON kv (tx_id, is_committed);`,
+	// 6: per-version expiry, indexed for the expiration sweep
+	`ALTER TABLE kv ADD COLUMN expires_at TEXT NOT NULL DEFAULT '';
+	CREATE INDEX kv_expires_at ON kv (expires_at) WHERE expires_at != '';`,
+	// 7: commit sequence of committed versions, indexed for Changes. Versions
+	// committed before it keep 0 and are never replayed.
+	`ALTER TABLE kv ADD COLUMN commit_seq INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX kv_commit_seq ON kv (commit_seq) WHERE commit_seq != 0;`,
+	// 8: records of transactions begun with Begin
+	`CREATE TABLE txns (
+		tx_id TEXT PRIMARY KEY,
+		start_ts TEXT NOT NULL,
+		start_seq INTEGER NOT NULL
+	) WITHOUT ROWID;`,
+	// 9: isolation level of transactions, 0 being snapshot isolation
+	`ALTER TABLE txns ADD COLUMN isolation INTEGER NOT NULL DEFAULT 0;`,
+	// 10: owner node, status (0 pending, 1 committed, 2 aborted) and commit
+	// time of transactions
+	`ALTER TABLE txns ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+	ALTER TABLE txns ADD COLUMN status INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE txns ADD COLUMN commit_ts TEXT NOT NULL DEFAULT '';`,
+}
+
+// blob binds s as a BLOB. Keys and values must never be bound as TEXT, which
+// would sort apart from them, or as a nil slice, which binds NULL.
+func blob(s string) []byte {
+	return append([]byte{}, s...)
+}
+
+// initSchema brings the database up to the latest schema version
+func (e *sqliteEngine) initSchema() error {
+	if e.db == nil {
+		return fmt.Errorf("db connection is nil in initSchema")
+	}
+
+	var version int
+	if err := e.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+	for ; version < len(migrations); version++ {
+		if err := e.migrate(version); err != nil {
+			return fmt.Errorf("failed to migrate schema to version %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+// migrate applies migrations[from] and records the new version atomically
+func (e *sqliteEngine) migrate(from int) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrations[from]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, from+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *sqliteEngine) Close() error {
+	if e.db != nil {
+		return e.db.Close()
+	}
+	return nil
+}
+
+func (e *sqliteEngine) Put(pairs []KeyValue, txID, timestamp string) error {
+	return e.put(pairs, txID, timestamp, false)
+}
+
+func (e *sqliteEngine) Delete(keys []string, txID, timestamp string) error {
+	pairs := make([]KeyValue, len(keys))
+	for i, key := range keys {
+		pairs[i] = KeyValue{Key: key}
+	}
+	return e.put(pairs, txID, timestamp, true)
+}
+
+func (e *sqliteEngine) put(pairs []KeyValue, txID, timestamp string, tombstone bool) error {
+	query := `INSERT OR REPLACE INTO kv (key, value, timestamp, tx_id, is_committed, is_tombstone, expires_at) VALUES (?, ?, ?, ?, false, ?, ?)`
+	if len(pairs) == 1 {
+		_, err := e.db.Exec(query, blob(pairs[0].Key), blob(pairs[0].Value), timestamp, txID, tombstone, pairs[0].ExpiresAt)
+		return err
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, kv := range pairs {
+		if _, err := stmt.Exec(blob(kv.Key), blob(kv.Value), timestamp, txID, tombstone, kv.ExpiresAt); err != nil {
+			return fmt.Errorf("failed to write %q: %w", kv.Key, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (e *sqliteEngine) PutCommitted(versions []Version, seq uint64) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO kv (key, value, timestamp, tx_id, is_committed, is_tombstone, expires_at, commit_seq) VALUES (?, ?, ?, ?, true, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, v := range versions {
+		if _, err := stmt.Exec(blob(v.Key), blob(v.Value), v.Timestamp, v.TxID, v.Tombstone, v.ExpiresAt, seq); err != nil {
+			return fmt.Errorf("failed to write %q: %w", v.Key, err)
+		}
+	}
+	if err := putMeta(tx, CommitSeqMeta, formatSeq(seq)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *sqliteEngine) Get(key, readTimestamp string) (Version, bool, error) {
+	query := `SELECT value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE key = ? AND timestamp <= ? AND is_committed = true ORDER BY timestamp DESC, tx_id DESC LIMIT 1`
+	row := e.db.QueryRow(query, blob(key), readTimestamp)
+	v := Version{Key: key, Committed: true}
+	err := row.Scan(&v.Value, &v.Timestamp, &v.TxID, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq)
+	if err == sql.ErrNoRows {
+		return Version{}, false, nil
+	}
+	if err != nil {
+		return Version{}, false, err
+	}
+	return v, true, nil
+}
+
+func (e *sqliteEngine) Uncommitted(txID string) ([]Version, error) {
+	query := `SELECT key, value, timestamp, is_tombstone, expires_at FROM kv WHERE tx_id = ? AND is_committed = false ORDER BY key`
+	rows, err := e.db.Query(query, txID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []Version
+	for rows.Next() {
+		v := Version{TxID: txID}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.Tombstone, &v.ExpiresAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (e *sqliteEngine) Commit(rec TxnRecord, seq uint64) ([]Version, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txID := rec.TxID
+
+	query := `UPDATE kv SET is_committed = true, commit_seq = ? WHERE tx_id = ? AND is_committed = false
+		RETURNING key, value, timestamp, is_tombstone, expires_at`
+	rows, err := tx.Query(query, seq, txID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var committed []Version
+	for rows.Next() {
+		v := Version{TxID: txID, Committed: true, CommitSeq: seq}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.Tombstone, &v.ExpiresAt); err != nil {
+			return nil, err
+		}
+		committed = append(committed, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := putTxn(tx, rec); err != nil {
+		return nil, err
+	}
+	if len(committed) > 0 {
+		if err := putMeta(tx, CommitSeqMeta, formatSeq(seq)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	// RETURNING comes in no particular order
+	sort.Slice(committed, func(i, j int) bool {
+		a, b := committed[i], committed[j]
+		return a.Key < b.Key || (a.Key == b.Key && a.Timestamp < b.Timestamp)
+	})
+	return committed, nil
+}
+
+func (e *sqliteEngine) PutMeta(name, value string) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := putMeta(tx, name, value); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// putMeta sets the engine metadata name to value
+func putMeta(tx *sql.Tx, name, value string) error {
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO meta (name, value) VALUES (?, ?)`, name, value); err != nil {
+		return fmt.Errorf("failed to record %s: %w", name, err)
+	}
+	return nil
+}
+
+func (e *sqliteEngine) Abort(rec TxnRecord) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM kv WHERE tx_id = ? AND is_committed = false`, rec.TxID); err != nil {
+		return err
+	}
+	if err := putTxn(tx, rec); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *sqliteEngine) Scan(start, end, readTimestamp string, reverse bool, fn func(Version) bool) error {
+	order := "key"
+	if reverse {
+		order = "key DESC"
+	}
+	query := `SELECT key, value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE key >= ? AND (length(?) = 0 OR key < ?) AND timestamp <= ? AND is_committed = true ORDER BY ` + order + `, timestamp DESC, tx_id DESC`
+	rows, err := e.db.Query(query, blob(start), blob(end), blob(end), readTimestamp)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var last string
+	first := true
+	for rows.Next() {
+		v := Version{Committed: true}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return err
+		}
+		// Rows of a key arrive newest first; only the first one is visible
+		if !first && v.Key == last {
+			continue
+		}
+		first, last = false, v.Key
+		if !fn(v) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func (e *sqliteEngine) History(key, from, to string, fn func(Version) bool) error {
+	query := `SELECT value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE key = ? AND timestamp >= ? AND (length(?) = 0 OR timestamp <= ?) AND is_committed = true ORDER BY timestamp DESC, tx_id DESC`
+	rows, err := e.db.Query(query, blob(key), from, to, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := Version{Key: key, Committed: true}
+		if err := rows.Scan(&v.Value, &v.Timestamp, &v.TxID, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return err
+		}
+		if !fn(v) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func (e *sqliteEngine) Changes(start, end string, from uint64, fn func(Version) bool) error {
+	// commit_seq != 0 lets SQLite use the partial index kv_commit_seq
+	query := `SELECT key, value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE commit_seq != 0 AND commit_seq >= ? AND key >= ? AND (length(?) = 0 OR key < ?) AND is_committed = true ORDER BY commit_seq, key, timestamp, tx_id`
+	rows, err := e.db.Query(query, from, blob(start), blob(end), blob(end))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := Version{Committed: true}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return err
+		}
+		if !fn(v) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+func (e *sqliteEngine) Since(start, end, timestamp string, fn func(Version) bool) error {
+	query := `SELECT key, value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE key >= ? AND (length(?) = 0 OR key < ?) AND timestamp >= ? AND is_committed = true ORDER BY key, timestamp, tx_id`
+	_, err := e.newer(query, []any{blob(start), blob(end), blob(end), timestamp}, fn)
+	return err
+}
+
+// newerBatch is the number of keys Newer looks up per query, well below the
+// SQLite limit on bound parameters
+const newerBatch = 500
+
+func (e *sqliteEngine) Newer(keys []string, timestamp string, fn func(Version) bool) error {
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > newerBatch {
+			batch = batch[:newerBatch]
+		}
+		keys = keys[len(batch):]
+
+		args := []any{timestamp}
+		for _, key := range batch {
+			args = append(args, blob(key))
+		}
+		query := `SELECT key, value, timestamp, tx_id, is_tombstone, expires_at, commit_seq FROM kv WHERE timestamp > ? AND is_committed = true AND key IN (?` + strings.Repeat(", ?", len(batch)-1) + `) ORDER BY key, timestamp, tx_id`
+		more, err := e.newer(query, args, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// newer runs a query of Newer or Since and reports whether fn asked for more
+func (e *sqliteEngine) newer(query string, args []any, fn func(Version) bool) (bool, error) {
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := Version{Committed: true}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return false, err
+		}
+		if !fn(v) {
+			return false, nil
+		}
+	}
+	return true, rows.Err()
+}
+
+func (e *sqliteEngine) Versions() ([]Version, error) {
+	query := `SELECT key, value, timestamp, tx_id, is_committed, is_tombstone, expires_at, commit_seq FROM kv ORDER BY key, timestamp, tx_id`
+	rows, err := e.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []Version
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.Committed, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (e *sqliteEngine) PutTxn(rec TxnRecord) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := putTxn(tx, rec); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// putTxn stores the record of a transaction, replacing the previous one
+func putTxn(tx *sql.Tx, rec TxnRecord) error {
+	_, err := tx.Exec(`INSERT OR REPLACE INTO txns (tx_id, start_ts, start_seq, isolation, owner, status, commit_ts) VALUES (?, ?, ?, ?, ?, ?, ?)`, rec.TxID, rec.StartTimestamp, rec.StartSeq, rec.Isolation, rec.Owner, rec.Status, rec.CommitTimestamp)
+	return err
+}
+
+func (e *sqliteEngine) GetTxn(txID string) (TxnRecord, bool, error) {
+	rec := TxnRecord{TxID: txID}
+	err := e.db.QueryRow(`SELECT start_ts, start_seq, isolation, owner, status, commit_ts FROM txns WHERE tx_id = ?`, txID).Scan(&rec.StartTimestamp, &rec.StartSeq, &rec.Isolation, &rec.Owner, &rec.Status, &rec.CommitTimestamp)
+	if err == sql.ErrNoRows {
+		return TxnRecord{}, false, nil
+	}
+	if err != nil {
+		return TxnRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (e *sqliteEngine) Snapshot() (EngineSnapshot, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// The transaction sees the database as of its first read
+	meta, err := readMeta(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &sqliteSnapshot{tx: tx, meta: meta}, nil
+}
+
+// readMeta returns every row of the meta table
+func readMeta(tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.Query(`SELECT name, value FROM meta`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		meta[name] = value
+	}
+	return meta, rows.Err()
+}
+
+// sqliteSnapshot reads from a transaction that is never written to, so it
+// keeps seeing the database as of its first read
+type sqliteSnapshot struct {
+	tx   *sql.Tx
+	meta map[string]string
+}
+
+func (s *sqliteSnapshot) Meta() map[string]string {
+	return s.meta
+}
+
+func (s *sqliteSnapshot) Versions(fn func(Version) error) error {
+	query := `SELECT key, value, timestamp, tx_id, is_committed, is_tombstone, expires_at, commit_seq FROM kv ORDER BY key, timestamp, tx_id`
+	rows, err := s.tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.Committed, &v.Tombstone, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqliteSnapshot) Txns(fn func(TxnRecord) error) error {
+	rows, err := s.tx.Query(`SELECT tx_id, start_ts, start_seq, isolation, owner, status, commit_ts FROM txns ORDER BY tx_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec TxnRecord
+		if err := rows.Scan(&rec.TxID, &rec.StartTimestamp, &rec.StartSeq, &rec.Isolation, &rec.Owner, &rec.Status, &rec.CommitTimestamp); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqliteSnapshot) Close() error {
+	return s.tx.Rollback()
+}
+
+func (e *sqliteEngine) ReplaceAll(src SnapshotSource) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM kv`); err != nil {
+		return fmt.Errorf("failed to clear kv table: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM meta`); err != nil {
+		return fmt.Errorf("failed to clear meta table: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM txns`); err != nil {
+		return fmt.Errorf("failed to clear txns table: %w", err)
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO kv (key, value, timestamp, tx_id, is_committed, is_tombstone, expires_at, commit_seq) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for {
+		versions, err := src.NextVersions()
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			break
+		}
+		for _, v := range versions {
+			if _, err := stmt.Exec(blob(v.Key), blob(v.Value), v.Timestamp, v.TxID, v.Committed, v.Tombstone, v.ExpiresAt, v.CommitSeq); err != nil {
+				return fmt.Errorf("failed to restore version of %q: %w", v.Key, err)
+			}
+		}
+	}
+	for {
+		txns, err := src.NextTxns()
+		if err != nil {
+			return err
+		}
+		if len(txns) == 0 {
+			break
+		}
+		for _, rec := range txns {
+			if _, err := tx.Exec(`INSERT INTO txns (tx_id, start_ts, start_seq, isolation, owner, status, commit_ts) VALUES (?, ?, ?, ?, ?, ?, ?)`, rec.TxID, rec.StartTimestamp, rec.StartSeq, rec.Isolation, rec.Owner, rec.Status, rec.CommitTimestamp); err != nil {
+				return fmt.Errorf("failed to restore transaction %s: %w", rec.TxID, err)
+			}
+		}
+	}
+	meta, err := src.Meta()
+	if err != nil {
+		return err
+	}
+	for name, value := range meta {
+		if _, err := tx.Exec(`INSERT INTO meta (name, value) VALUES (?, ?)`, name, value); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (e *sqliteEngine) CollectGarbage(threshold string) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Order matters: shadowed versions go first, so a tombstone or expired
+	// version left as the newest version at threshold can then be dropped
+	// without older values reappearing.
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"shadowed versions", `DELETE FROM kv WHERE is_committed = true AND timestamp <= ?1 AND EXISTS (
+			SELECT 1 FROM kv AS newer WHERE newer.key = kv.key AND newer.is_committed = true
+				AND (newer.timestamp, newer.tx_id) > (kv.timestamp, kv.tx_id) AND newer.timestamp <= ?1)`},
+		{"tombstones", `DELETE FROM kv WHERE is_committed = true AND is_tombstone = true AND timestamp <= ?1`},
+		{"expired versions", `DELETE FROM kv WHERE is_committed = true AND timestamp <= ?1 AND expires_at != '' AND expires_at <= ?1`},
+		{"abandoned writes", `DELETE FROM kv WHERE is_committed = false AND timestamp <= ?1`},
+	}
+	meta, err := readMeta(tx)
+	if err != nil {
+		return err
+	}
+	gcSeq, err := parseSeq(meta[GCSeqMeta])
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if gcSeq, err = deleteVersions(tx, step.query, threshold, gcSeq); err != nil {
+			return fmt.Errorf("failed to collect %s: %w", step.name, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM txns WHERE start_ts <= ?`, threshold); err != nil {
+		return fmt.Errorf("failed to collect transaction records: %w", err)
+	}
+	if err := putMeta(tx, GCSeqMeta, formatSeq(gcSeq)); err != nil {
+		return err
+	}
+	if err := putMeta(tx, GCThresholdMeta, threshold); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteVersions runs the DELETE query and returns the greater of gcSeq and
+// the commit sequences of the deleted versions
+func deleteVersions(tx *sql.Tx, query, threshold string, gcSeq uint64) (uint64, error) {
+	rows, err := tx.Query(query+` RETURNING commit_seq`, threshold)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq uint64
+		if err := rows.Scan(&seq); err != nil {
+			return 0, err
+		}
+		gcSeq = max(gcSeq, seq)
+	}
+	return gcSeq, rows.Err()
+}
+
+func (e *sqliteEngine) Meta() (map[string]string, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return readMeta(tx)
+}
+
+func (e *sqliteEngine) Expired(now string, limit int) ([]Version, error) {
+	query := `SELECT key, value, timestamp, tx_id, expires_at, commit_seq FROM kv
+		WHERE expires_at != '' AND expires_at <= ? AND is_committed = true AND is_tombstone = false
+		AND NOT EXISTS (SELECT 1 FROM kv AS newer WHERE newer.key = kv.key AND newer.is_committed = true
+			AND (newer.timestamp, newer.tx_id) > (kv.timestamp, kv.tx_id))
+		ORDER BY expires_at LIMIT ?`
+	rows, err := e.db.Query(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []Version
+	for rows.Next() {
+		v := Version{Committed: true}
+		if err := rows.Scan(&v.Key, &v.Value, &v.Timestamp, &v.TxID, &v.ExpiresAt, &v.CommitSeq); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,137 @@
+package kvstore_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+)
+
+func TestMigrateLegacySchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Build a database the way releases before schema versioning did
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	legacy := []string{
+		`CREATE TABLE kv (key TEXT, value TEXT, timestamp TEXT, tx_id TEXT, is_committed BOOLEAN)`,
+		`INSERT INTO kv VALUES ('k', 'v1', '010', 'tx1', true)`,
+		`INSERT INTO kv VALUES ('k', 'v1', '010', 'tx1', true)`,
+		`INSERT INTO kv VALUES ('k', 'v2', '020', 'tx2', false)`,
+	}
+	for _, stmt := range legacy {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("legacy setup error: %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := kvstore.NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore error: %v", err)
+	}
+	if got, _ := store.Read("k", "999"); got != "v1" {
+		t.Errorf("expected migrated committed value v1, got %q", got)
+	}
+	// A pending write from before transaction records has none to commit by
+	if err := store.Commit("tx2"); !errors.Is(err, kvstore.ErrTxnNotFound) {
+		t.Fatalf("expected ErrTxnNotFound committing a legacy write, got %v", err)
+	}
+	if got, _ := store.Read("k", "999"); got != "v1" {
+		t.Errorf("expected the legacy pending write to stay invisible, got %q", got)
+	}
+	versions, _ := store.Versions()
+	if len(versions) != 2 {
+		t.Errorf("expected duplicate legacy rows to collapse, got %+v", versions)
+	}
+	// Migrated text keys must sort together with keys written as blobs
+	if err := store.Begin("tx3", "030", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := store.WriteWithTimestamp("j", "new", "tx3", "030"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	store.Commit("tx3")
+	var keys []string
+	store.Scan("", "", "999", false, func(v kvstore.Version) bool {
+		keys = append(keys, v.Key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []string{"j", "k"}) {
+		t.Errorf("expected migrated and new keys in order, got %q", keys)
+	}
+	store.Close()
+
+	db, err = sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer db.Close()
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("user_version error: %v", err)
+	}
+	if version != 10 {
+		t.Errorf("expected schema version 10, got %d", version)
+	}
+	var textRows int
+	if err := db.QueryRow(`SELECT count(*) FROM kv WHERE typeof(key) != 'blob' OR typeof(value) != 'blob'`).Scan(&textRows); err != nil {
+		t.Fatalf("typeof error: %v", err)
+	}
+	if textRows != 0 {
+		t.Errorf("expected legacy text keys and values to become blobs, %d rows left", textRows)
+	}
+
+	// Reads and transaction lookups must not scan the whole table
+	for _, query := range []string{
+		`SELECT value FROM kv WHERE key = 'k' AND timestamp <= '999' AND is_committed = true ORDER BY timestamp DESC LIMIT 1`,
+		`UPDATE kv SET is_committed = true WHERE tx_id = 'tx1'`,
+		`DELETE FROM kv WHERE tx_id = 'tx1' AND is_committed = false`,
+	} {
+		plan := queryPlan(t, db, query)
+		if strings.Contains(plan, "SCAN") {
+			t.Errorf("expected indexed plan for %q, got %q", query, plan)
+		}
+	}
+}
+
+func TestRejectNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "future.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	if _, err := db.Exec(`PRAGMA user_version = 99`); err != nil {
+		t.Fatalf("pragma error: %v", err)
+	}
+	db.Close()
+
+	if _, err := kvstore.NewStore(path); err == nil {
+		t.Fatal("expected error opening a database with a newer schema")
+	}
+}
+
+func queryPlan(t *testing.T, db *sql.DB, query string) string {
+	t.Helper()
+	rows, err := db.Query(`EXPLAIN QUERY PLAN ` + query)
+	if err != nil {
+		t.Fatalf("explain error: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("explain scan error: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	return strings.Join(plan, "; ")
+}
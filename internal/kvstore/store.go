@@ -1,73 +1,242 @@
 package kvstore
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrBelowGCThreshold is returned by reads at a timestamp older than the GC
+// threshold, whose versions may already have been collected.
+var ErrBelowGCThreshold = errors.New("read timestamp is below the GC threshold")
+
+// ErrWriteTooOld is returned, wrapped in a *WriteTooOldError, when a
+// conditional write finds a version committed at or after its own timestamp.
+// Writing anyway would place the new version behind the one the condition
+// was checked against, so the operation must be retried at a newer timestamp.
+var ErrWriteTooOld = errors.New("a newer version is already committed")
+
+// WriteTooOldError reports the key and timestamp of the newer version
+type WriteTooOldError struct {
+	Key       string
+	Timestamp string // of the write that was refused
+	Newer     string // timestamp of the committed version
+}
+
+func (e *WriteTooOldError) Error() string {
+	return fmt.Sprintf("%v: %q has a version at %s, not before %s", ErrWriteTooOld, e.Key, e.Newer, e.Timestamp)
+}
+
+func (e *WriteTooOldError) Unwrap() error {
+	return ErrWriteTooOld
+}
+
+// latestTimestamp sorts after every timestamp, to read the newest version
+const latestTimestamp = "\xff"
+
+// Store is the MVCC key-value store replicated by the Raft FSM. It delegates
+// storage to an Engine.
 type Store struct {
-	db *sql.DB
+	engine Engine
+
+	mu          sync.RWMutex
+	gcThreshold string            // cached from the engine
+	gcSeq       uint64            // cached from the engine
+	nodes       map[string]string // gRPC address by node ID, cached from the engine
+
+	// watchMu serializes commits with changes to watchers, see commit
+	watchMu   sync.Mutex
+	watchers  map[*Watcher]struct{}
+	commitSeq uint64 // of the last commit, cached from the engine
+	index     uint64 // Raft index of the entry being applied, see Advance
 }
 
+// NewStore opens a SQLite-backed store at path.
 func NewStore(path string) (*Store, error) {
-	db, err := sql.Open("sqlite3", path)
+	return Open(EngineSQLite, path)
+}
+
+// NewStoreWithEngine wraps an already opened engine.
+func NewStoreWithEngine(engine Engine) (*Store, error) {
+	meta, err := engine.Meta()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to read engine metadata: %w", err)
 	}
+	gcSeq, commitSeq, err := parseMeta(meta)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{engine: engine, gcThreshold: meta[GCThresholdMeta], gcSeq: gcSeq, commitSeq: commitSeq, nodes: nodeAddrs(meta)}, nil
+}
 
-	// Validate connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+// parseMeta reads the commit sequences the store caches from engine metadata
+func parseMeta(meta map[string]string) (gcSeq, commitSeq uint64, err error) {
+	if gcSeq, err = parseSeq(meta[GCSeqMeta]); err != nil {
+		return 0, 0, err
 	}
+	if commitSeq, err = parseSeq(meta[CommitSeqMeta]); err != nil {
+		return 0, 0, err
+	}
+	return gcSeq, commitSeq, nil
+}
 
-	s := &Store{db: db}
+// Advance tells the store the Raft index of the entry about to be applied.
+// An entry commits at most once, and that commit takes the index as its
+// commit sequence, which is thus the same on every replica and when the
+// entry is applied again. Without Advance, commit sequences count commits.
+func (s *Store) Advance(index uint64) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.index = index
+}
 
-	// Now safely initialize schema
-	if err := s.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to init schema: %w", err)
+func (s *Store) Close() error {
+	return s.engine.Close()
+}
+
+// BeginTransaction returns a new transaction ID. The transaction exists once
+// Begin recorded it.
+func (s *Store) BeginTransaction() string {
+	return uuid.New().String()
+}
+
+// WriteWithTimestamp writes a versioned value using the provided timestamp (for HLC ordering)
+func (s *Store) WriteWithTimestamp(key, value, txID, timestamp string) error {
+	if err := s.checkWritable(txID); err != nil {
+		return err
 	}
+	return s.engine.Put([]KeyValue{{Key: key, Value: value}}, txID, timestamp)
+}
 
-	return s, nil
+// KeyValue is a single key/value pair of a batch write
+type KeyValue struct {
+	Key       string
+	Value     string
+	ExpiresAt string // HLC timestamp from which the value reads as deleted; "" never expires
 }
 
-func (s *Store) initSchema() error {
-	if s.db == nil {
-		return fmt.Errorf("db connection is nil in initSchema")
+// WriteBatchWithTimestamp writes every pair as a version of txID at timestamp,
+// atomically. If a key appears more than once, the last value wins, as it
+// would for consecutive writes.
+func (s *Store) WriteBatchWithTimestamp(pairs []KeyValue, txID, timestamp string) error {
+	if err := s.checkWritable(txID); err != nil {
+		return err
+	}
+	last := make(map[string]int, len(pairs))
+	for i, kv := range pairs {
+		last[kv.Key] = i
 	}
+	unique := make([]KeyValue, 0, len(last))
+	for i, kv := range pairs {
+		if last[kv.Key] == i {
+			unique = append(unique, kv)
+		}
+	}
+	return s.engine.Put(unique, txID, timestamp)
+}
 
-	query := `
-	CREATE TABLE IF NOT EXISTS kv (
-		key TEXT,
-		value TEXT,
-		timestamp TEXT,
-		tx_id TEXT,
-		is_committed BOOLEAN
-	);
-	`
-	_, err := s.db.Exec(query)
-	return err
+// DeleteWithTimestamp writes a tombstone for key as a version of txID at
+// timestamp. Once committed, reads at or after timestamp see no value.
+func (s *Store) DeleteWithTimestamp(key, txID, timestamp string) error {
+	if err := s.checkWritable(txID); err != nil {
+		return err
+	}
+	return s.engine.Delete([]string{key}, txID, timestamp)
 }
 
-func (s *Store) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+// ConditionKind selects what a Condition compares
+type ConditionKind int
+
+const (
+	// ValueEquals requires the key to exist with Condition.Value
+	ValueEquals ConditionKind = iota + 1
+	// VersionEquals requires the key's newest version to have timestamp Condition.Version
+	VersionEquals
+	// NotExists requires the key to be absent, deleted or expired
+	NotExists
+	// Exists requires the key to have a live version
+	Exists
+)
+
+// Condition is the precondition of a CompareAndSwap or a Txn comparison
+type Condition struct {
+	Kind    ConditionKind
+	Value   string
+	Version string
+}
+
+// holds reports whether c is met by current, the newest live version of a
+// key if exists is true
+func (c Condition) holds(current Version, exists bool) (bool, error) {
+	switch c.Kind {
+	case ValueEquals:
+		return exists && current.Value == c.Value, nil
+	case VersionEquals:
+		return exists && current.Timestamp == c.Version, nil
+	case NotExists:
+		return !exists, nil
+	case Exists:
+		return exists, nil
 	}
-	return nil
+	return false, fmt.Errorf("unknown condition kind %d", c.Kind)
 }
 
-func (s *Store) BeginTransaction() string {
-	return uuid.New().String()
+// CASResult reports the outcome of a CompareAndSwap
+type CASResult struct {
+	Succeeded bool
+	// Current is the version the condition was checked against, or the one
+	// written if Succeeded. Exists is false if the key had no live version.
+	Current Version
+	Exists  bool
 }
 
-// WriteWithTimestamp writes a versioned value using the provided timestamp (for HLC ordering)
-func (s *Store) WriteWithTimestamp(key, value, txID, timestamp string) error {
-	query := `INSERT INTO kv (key, value, timestamp, tx_id, is_committed) VALUES (?, ?, ?, ?, false)`
-	_, err := s.db.Exec(query, key, value, timestamp, txID)
-	return err
+// latest returns the newest committed version of key, unless the key is
+// absent, deleted or expired at timestamp. It fails with a *WriteTooOldError
+// if that version is at or after timestamp.
+func (s *Store) latest(key, timestamp string) (Version, bool, error) {
+	v, exists, err := s.engine.Get(key, latestTimestamp)
+	if err != nil || !exists {
+		return Version{}, false, err
+	}
+	if v.Timestamp >= timestamp {
+		return Version{}, false, &WriteTooOldError{Key: key, Timestamp: timestamp, Newer: v.Timestamp}
+	}
+	if v.deletedAt(timestamp) {
+		return Version{}, false, nil
+	}
+	return v, true, nil
+}
+
+// CompareAndSwap writes kv at timestamp and commits it as transaction txID,
+// but only if cond holds for the newest committed version of the key. If that
+// version is not older than timestamp, CompareAndSwap fails with a
+// *WriteTooOldError instead. The check and the write, which is stored in a
+// single engine transaction, are atomic as long as calls are serialized, as
+// they are by the Raft FSM.
+func (s *Store) CompareAndSwap(kv KeyValue, cond Condition, txID, timestamp string) (CASResult, error) {
+	if err := s.checkReadTimestamp(timestamp); err != nil {
+		return CASResult{}, err
+	}
+	current, exists, err := s.latest(kv.Key, timestamp)
+	if err != nil {
+		return CASResult{}, err
+	}
+	ok, err := cond.holds(current, exists)
+	if err != nil {
+		return CASResult{}, err
+	}
+	if !ok {
+		return CASResult{Current: current, Exists: exists}, nil
+	}
+
+	written := Version{Key: kv.Key, Value: kv.Value, Timestamp: timestamp, TxID: txID, Committed: true, ExpiresAt: kv.ExpiresAt}
+	if err := s.putCommitted([]Version{written}); err != nil {
+		return CASResult{}, err
+	}
+	return CASResult{Succeeded: true, Current: written, Exists: true}, nil
 }
 
 // Write is maintained for compatibility but uses system time
@@ -76,25 +245,376 @@ func (s *Store) Write(key, value, txID string) error {
 	return s.WriteWithTimestamp(key, value, txID, now)
 }
 
+// Read returns the value of key at readTimestamp, or "" if the key did not
+// exist or was deleted.
 func (s *Store) Read(key, readTimestamp string) (string, error) {
-	query := `SELECT value FROM kv WHERE key = ? AND timestamp <= ? AND is_committed = true ORDER BY timestamp DESC LIMIT 1`
-	row := s.db.QueryRow(query, key, readTimestamp)
-	var value string
-	err := row.Scan(&value)
-	if err == sql.ErrNoRows {
-		return "", nil
+	v, _, err := s.Get(key, readTimestamp)
+	return v.Value, err
+}
+
+// Get returns the version of key visible at readTimestamp and whether there
+// is one. A key that was deleted or has expired has none, which tells it
+// apart from a key holding an empty value.
+func (s *Store) Get(key, readTimestamp string) (Version, bool, error) {
+	return s.GetInTxn(key, "", readTimestamp)
+}
+
+// GetInTxn is like Get, but if txID is not empty the newest uncommitted
+// write of the transaction to key, if any, is returned in place of the
+// committed version, so a transaction reads its own writes.
+func (s *Store) GetInTxn(key, txID, readTimestamp string) (Version, bool, error) {
+	// GC waits until the version visible at readTimestamp was read
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.belowThreshold(readTimestamp); err != nil {
+		return Version{}, false, err
+	}
+	own, err := s.ownWrites(txID, key, key+"\x00")
+	if err != nil {
+		return Version{}, false, err
+	}
+	v, ok := Version{}, false
+	if len(own) > 0 {
+		v, ok = own[0], true
+	} else if v, ok, err = s.engine.Get(key, readTimestamp); err != nil {
+		return Version{}, false, err
+	}
+	if !ok || v.deletedAt(readTimestamp) {
+		return Version{}, false, nil
+	}
+	return v, true, nil
+}
+
+// Scan calls fn in key order (or reverse key order) with the latest committed
+// version, at readTimestamp, of every key in [start, end). Deleted and expired
+// keys are skipped. An empty end means no upper bound.
+func (s *Store) Scan(start, end, readTimestamp string, reverse bool, fn func(Version) bool) error {
+	return s.ScanInTxn(start, end, "", readTimestamp, reverse, fn)
+}
+
+// ScanInTxn is like Scan, but if txID is not empty the uncommitted writes
+// of the transaction in [start, end) are overlaid on the committed versions,
+// as GetInTxn does for a single key.
+func (s *Store) ScanInTxn(start, end, txID, readTimestamp string, reverse bool, fn func(Version) bool) error {
+	// GC waits until the engine began reading, from then on it reads a
+	// snapshot GC cannot change. fn, which may be slow, runs unlocked.
+	s.mu.RLock()
+	locked := true
+	unlock := func() {
+		if locked {
+			locked = false
+			s.mu.RUnlock()
+		}
+	}
+	defer unlock()
+	if err := s.belowThreshold(readTimestamp); err != nil {
+		return err
+	}
+	own, err := s.ownWrites(txID, start, end)
+	if err != nil {
+		return err
+	}
+	if reverse {
+		for i, j := 0, len(own)-1; i < j; i, j = i+1, j-1 {
+			own[i], own[j] = own[j], own[i]
+		}
+	}
+	// before reports whether a comes before b in scan order
+	before := func(a, b string) bool {
+		if reverse {
+			return a > b
+		}
+		return a < b
+	}
+	visit := func(v Version) bool {
+		return v.deletedAt(readTimestamp) || fn(v)
+	}
+
+	stopped := false
+	err = s.engine.Scan(start, end, readTimestamp, reverse, func(v Version) bool {
+		unlock()
+		for len(own) > 0 && !before(v.Key, own[0].Key) {
+			next := own[0]
+			own = own[1:]
+			if next.Key == v.Key {
+				v = next
+				break
+			}
+			if !visit(next) {
+				stopped = true
+				return false
+			}
+		}
+		if !visit(v) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	unlock()
+	if err != nil || stopped {
+		return err
+	}
+	for _, v := range own {
+		if !visit(v) {
+			break
+		}
+	}
+	return nil
+}
+
+// ownWrites returns the newest uncommitted write of txID to every key in
+// [start, end), in key order. An empty end means no upper bound, and an
+// empty txID has no writes.
+func (s *Store) ownWrites(txID, start, end string) ([]Version, error) {
+	if txID == "" {
+		return nil, nil
+	}
+	pending, err := s.engine.Uncommitted(txID)
+	if err != nil {
+		return nil, err
+	}
+	var own []Version
+	for _, v := range pending {
+		if v.Key < start || (end != "" && v.Key >= end) {
+			continue
+		}
+		if n := len(own); n > 0 && own[n-1].Key == v.Key {
+			if v.Timestamp > own[n-1].Timestamp {
+				own[n-1] = v
+			}
+			continue
+		}
+		own = append(own, v)
+	}
+	return own, nil
+}
+
+// History calls fn, newest first, with every committed version of key whose
+// timestamp is in [from, to], including tombstones. An empty to means no
+// upper bound. Versions older than the GC threshold may have been collected,
+// so History does not check it.
+func (s *Store) History(key, from, to string, fn func(Version) bool) error {
+	return s.engine.History(key, from, to, fn)
+}
+
+// PrefixEnd returns the smallest key greater than every key starting with
+// prefix, or "" if there is none, for use as the end of a prefix scan.
+func PrefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return string(end[:i+1])
+		}
 	}
-	return value, err
+	return ""
 }
 
+// Commit makes the writes of transaction txID visible. If another
+// transaction has since committed a write to one of its keys, it is aborted
+// instead and Commit fails with a *ConflictError. A transaction without a
+// record fails with ErrTxnNotFound, one that already finished with a
+// *TxnStateError.
 func (s *Store) Commit(txID string) error {
-	query := `UPDATE kv SET is_committed = true WHERE tx_id = ?`
-	_, err := s.db.Exec(query, txID)
-	return err
+	return s.CommitReads(txID, "", nil)
 }
 
+// CommitReads is Commit for a transaction that read reads, recording
+// commitTimestamp as its commit time. If it was begun Serializable and
+// another transaction has since committed a write to one of them, it is
+// aborted instead and CommitReads fails with a *SerializationError. The
+// writes become visible in the same engine transaction that marks the
+// record committed.
+func (s *Store) CommitReads(txID, commitTimestamp string, reads []KeyRange) error {
+	rec, err := s.PendingTransaction(txID, "commit")
+	if err != nil {
+		return err
+	}
+	err = s.checkConflicts(rec)
+	if err == nil && rec.Isolation == Serializable {
+		err = s.checkReads(rec, reads)
+	}
+	if err != nil {
+		if errors.Is(err, ErrConflict) || errors.Is(err, ErrSerialization) {
+			if abortErr := s.Abort(txID); abortErr != nil {
+				return abortErr
+			}
+		}
+		return err
+	}
+	rec.Status = TxnCommitted
+	rec.CommitTimestamp = commitTimestamp
+	return s.commit(rec)
+}
+
+// Abort discards the writes of transaction txID and marks its record
+// aborted, in one engine transaction. A transaction without a record fails
+// with ErrTxnNotFound, one that already finished with a *TxnStateError.
 func (s *Store) Abort(txID string) error {
-	query := `DELETE FROM kv WHERE tx_id = ? AND is_committed = false`
-	_, err := s.db.Exec(query, txID)
-	return err
+	rec, err := s.PendingTransaction(txID, "abort")
+	if err != nil {
+		return err
+	}
+	rec.Status = TxnAborted
+	return s.engine.Abort(rec)
+}
+
+// Version is a single row of the kv table: one MVCC version of a key together
+// with the transaction that wrote it.
+type Version struct {
+	Key       string
+	Value     string
+	Timestamp string
+	TxID      string
+	Committed bool
+	Tombstone bool   // the version deletes the key
+	ExpiresAt string // HLC timestamp from which the version reads as deleted; "" never expires
+	CommitSeq uint64 // commit sequence of the commit that made it visible; 0 if uncommitted or committed before sequences existed
+}
+
+// deletedAt reports whether the version hides its key from reads at readTimestamp
+func (v Version) deletedAt(readTimestamp string) bool {
+	return v.Tombstone || (v.ExpiresAt != "" && readTimestamp >= v.ExpiresAt)
+}
+
+// Versions returns every version in the store, committed or not, ordered by
+// key, timestamp and transaction ID.
+func (s *Store) Versions() ([]Version, error) {
+	return s.engine.Versions()
+}
+
+// Snapshot returns a point-in-time view of the store, used to take Raft
+// snapshots without holding up the FSM while they are written out. The view
+// must be closed.
+func (s *Store) Snapshot() (EngineSnapshot, error) {
+	return s.engine.Snapshot()
+}
+
+// ReplaceAll atomically replaces the versions, transaction records and
+// engine metadata, such as the GC threshold, of the store with those read
+// from src. It is used to restore a Raft snapshot. Watchers are closed with
+// ErrWatchReset, since they would miss the changes.
+func (s *Store) ReplaceAll(src SnapshotSource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	checked := &checkedSource{SnapshotSource: src}
+	if err := s.engine.ReplaceAll(checked); err != nil {
+		return err
+	}
+	meta := checked.meta
+	s.gcThreshold, s.gcSeq, s.commitSeq = meta[GCThresholdMeta], checked.gcSeq, checked.commitSeq
+	s.nodes = nodeAddrs(meta)
+	for w := range s.watchers {
+		s.removeWatcher(w, ErrWatchReset)
+	}
+	return nil
+}
+
+// checkedSource parses the metadata of a snapshot before the engine installs
+// it, so that malformed metadata fails the restore
+type checkedSource struct {
+	SnapshotSource
+	meta             map[string]string
+	gcSeq, commitSeq uint64
+}
+
+func (c *checkedSource) Meta() (map[string]string, error) {
+	meta, err := c.SnapshotSource.Meta()
+	if err != nil {
+		return nil, err
+	}
+	if c.gcSeq, c.commitSeq, err = parseMeta(meta); err != nil {
+		return nil, err
+	}
+	c.meta = meta
+	return meta, nil
+}
+
+// GC removes versions that no read at or after threshold can observe and
+// refuses reads older than threshold from then on. A threshold at or below
+// the current one is a no-op, so replaying GC commands is safe.
+func (s *Store) GC(threshold string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if threshold <= s.gcThreshold {
+		return nil
+	}
+	if err := s.engine.CollectGarbage(threshold); err != nil {
+		return err
+	}
+	meta, err := s.engine.Meta()
+	if err != nil {
+		return err
+	}
+	gcSeq, err := parseSeq(meta[GCSeqMeta])
+	if err != nil {
+		return err
+	}
+	s.gcThreshold, s.gcSeq = threshold, gcSeq
+	return nil
+}
+
+// GCThreshold returns the oldest timestamp reads may use, or "" if GC never ran.
+func (s *Store) GCThreshold() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.gcThreshold
+}
+
+// Expired returns up to limit keys whose newest committed version expired at
+// or before now and has not been replaced by a tombstone yet.
+func (s *Store) Expired(now string, limit int) ([]Version, error) {
+	return s.engine.Expired(now, limit)
+}
+
+// expiryTxPrefix followed by the expiry time is the transaction ID of the
+// tombstones written by Expire
+const expiryTxPrefix = "expire-"
+
+// Expire makes the expiry of each key permanent by writing a committed
+// tombstone at its ExpiresAt, unless the key was written again since the
+// version expiring then, in which case the key is skipped. The tombstones
+// are written in a single engine transaction.
+func (s *Store) Expire(expired []KeyValue) error {
+	var tombstones []Version
+	for _, kv := range expired {
+		v, ok, err := s.engine.Get(kv.Key, kv.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if !ok || v.Tombstone || v.ExpiresAt != kv.ExpiresAt {
+			continue
+		}
+		tombstones = append(tombstones, Version{
+			Key:       kv.Key,
+			Timestamp: kv.ExpiresAt,
+			TxID:      expiryTxPrefix + kv.ExpiresAt,
+			Committed: true,
+			Tombstone: true,
+		})
+	}
+	if len(tombstones) == 0 {
+		return nil
+	}
+	return s.putCommitted(tombstones)
+}
+
+// checkReadTimestamp fails with ErrBelowGCThreshold if readTimestamp is
+// older than the GC threshold. Only callers serialized with GC by the FSM may
+// rely on it after it returns; reads take s.mu and call belowThreshold.
+func (s *Store) checkReadTimestamp(readTimestamp string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.belowThreshold(readTimestamp)
+}
+
+// belowThreshold is checkReadTimestamp for callers holding s.mu
+func (s *Store) belowThreshold(readTimestamp string) error {
+	if readTimestamp < s.gcThreshold {
+		return fmt.Errorf("%w: %s is older than %s", ErrBelowGCThreshold, readTimestamp, s.gcThreshold)
+	}
+	return nil
 }
@@ -0,0 +1,924 @@
+package kvstore_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+)
+
+// forEachEngine runs test against a fresh store of every engine
+func forEachEngine(t *testing.T, test func(t *testing.T, store *kvstore.Store)) {
+	for _, engine := range []string{kvstore.EngineSQLite, kvstore.EngineBolt} {
+		t.Run(engine, func(t *testing.T) {
+			store, err := kvstore.Open(engine, filepath.Join(t.TempDir(), "kv.db"))
+			if err != nil {
+				t.Fatalf("Open(%s) error: %v", engine, err)
+			}
+			t.Cleanup(func() { store.Close() })
+			test(t, store)
+		})
+	}
+}
+
+// mustCommit writes pairs in a new transaction at ts and commits it
+func mustCommit(t *testing.T, store *kvstore.Store, txID, ts string, pairs ...kvstore.KeyValue) {
+	t.Helper()
+	if err := store.Begin(txID, ts, kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin(%s) error: %v", txID, err)
+	}
+	if err := store.WriteBatchWithTimestamp(pairs, txID, ts); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := store.Commit(txID); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+}
+
+func TestReadAtTimestamp(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010", kvstore.KeyValue{Key: "k", Value: "v1"})
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "k", Value: "v2"})
+		if err := store.WriteWithTimestamp("k", "pending", "tx3", "030"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+
+		for ts, want := range map[string]string{"005": "", "010": "v1", "015": "v1", "020": "v2", "999": "v2"} {
+			got, err := store.Read("k", ts)
+			if err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+			if got != want {
+				t.Errorf("Read at %s: got %q, want %q", ts, got, want)
+			}
+		}
+		if got, _ := store.Read("missing", "999"); got != "" {
+			t.Errorf("expected empty value for missing key, got %q", got)
+		}
+	})
+}
+
+func TestReadOwnWrites(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010", kvstore.KeyValue{Key: "a", Value: "a1"}, kvstore.KeyValue{Key: "c", Value: "c1"}, kvstore.KeyValue{Key: "e", Value: "e1"})
+		// tx2 inserts b and d, updates c twice and deletes e
+		if err := store.WriteBatchWithTimestamp([]kvstore.KeyValue{{Key: "b", Value: "b2"}, {Key: "c", Value: "c2"}, {Key: "d", Value: "d2"}}, "tx2", "020"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		if err := store.WriteWithTimestamp("c", "c3", "tx2", "030"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		if err := store.DeleteWithTimestamp("e", "tx2", "030"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+		// Writes of other transactions stay invisible
+		if err := store.WriteWithTimestamp("a", "other", "tx3", "030"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+
+		for key, want := range map[string]string{"a": "a1", "b": "b2", "c": "c3", "e": ""} {
+			v, exists, err := store.GetInTxn(key, "tx2", "040")
+			if err != nil {
+				t.Fatalf("GetInTxn(%q) error: %v", key, err)
+			}
+			if v.Value != want || exists != (want != "") {
+				t.Errorf("GetInTxn(%q): got %q, %v, want %q", key, v.Value, exists, want)
+			}
+		}
+		if v, _, _ := store.Get("c", "040"); v.Value != "c1" {
+			t.Errorf("expected Get outside the transaction to see c1, got %q", v.Value)
+		}
+
+		scan := func(start, end string, reverse bool, limit int) []string {
+			var got []string
+			err := store.ScanInTxn(start, end, "tx2", "040", reverse, func(v kvstore.Version) bool {
+				got = append(got, v.Key+"="+v.Value)
+				return len(got) < limit
+			})
+			if err != nil {
+				t.Fatalf("ScanInTxn error: %v", err)
+			}
+			return got
+		}
+		for _, tt := range []struct {
+			start, end string
+			reverse    bool
+			limit      int
+			want       []string
+		}{
+			{"", "", false, 10, []string{"a=a1", "b=b2", "c=c3", "d=d2"}},
+			{"", "", true, 10, []string{"d=d2", "c=c3", "b=b2", "a=a1"}},
+			{"b", "d", false, 10, []string{"b=b2", "c=c3"}},
+			{"", "", false, 2, []string{"a=a1", "b=b2"}},
+			{"", "", true, 1, []string{"d=d2"}},
+		} {
+			if got := scan(tt.start, tt.end, tt.reverse, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanInTxn(%q, %q, reverse %v, limit %d) = %v, want %v", tt.start, tt.end, tt.reverse, tt.limit, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestCommitAndAbort(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustBegin(t, store, "tx1", "010")
+		mustBegin(t, store, "tx2", "011")
+		store.WriteWithTimestamp("a", "1", "tx1", "010")
+		store.WriteWithTimestamp("b", "2", "tx2", "011")
+		if err := store.Abort("tx2"); err != nil {
+			t.Fatalf("Abort error: %v", err)
+		}
+		if err := store.Commit("tx1"); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+		// Aborting after commit must not remove committed data
+		expectState(t, store.Abort("tx1"), "tx1", "abort", kvstore.TxnCommitted)
+		if got, _ := store.Read("a", "999"); got != "1" {
+			t.Errorf("expected committed value, got %q", got)
+		}
+		if got, _ := store.Read("b", "999"); got != "" {
+			t.Errorf("expected aborted value to be gone, got %q", got)
+		}
+		versions, err := store.Versions()
+		if err != nil {
+			t.Fatalf("Versions error: %v", err)
+		}
+		if len(versions) != 1 {
+			t.Errorf("expected 1 version after abort, got %+v", versions)
+		}
+	})
+}
+
+func TestScan(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "a", Value: "a1"},
+			kvstore.KeyValue{Key: "b", Value: "b1"},
+			kvstore.KeyValue{Key: "b\x00x", Value: "nul"},
+			kvstore.KeyValue{Key: "c", Value: "c1"},
+			kvstore.KeyValue{Key: "d", Value: "d1"},
+		)
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "b", Value: "b2"})
+		store.WriteWithTimestamp("c", "pending", "tx3", "015")
+
+		scan := func(start, end, ts string) map[string]string {
+			got := map[string]string{}
+			err := store.Scan(start, end, ts, false, func(v kvstore.Version) bool {
+				got[v.Key] = v.Value
+				return true
+			})
+			if err != nil {
+				t.Fatalf("Scan error: %v", err)
+			}
+			return got
+		}
+		if got, want := scan("b", "d", "015"), map[string]string{"b": "b1", "b\x00x": "nul", "c": "c1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Scan [b,d) at 015: got %v, want %v", got, want)
+		}
+		if got, want := scan("", "", "999"), map[string]string{"a": "a1", "b": "b2", "b\x00x": "nul", "c": "c1", "d": "d1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("full Scan: got %v, want %v", got, want)
+		}
+
+		var keys []string
+		store.Scan("", "", "999", false, func(v kvstore.Version) bool {
+			keys = append(keys, v.Key)
+			return len(keys) < 2
+		})
+		if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+			t.Errorf("expected scan to stop after 2 keys in order, got %q", keys)
+		}
+	})
+}
+
+func TestScanReverse(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "a", Value: "a1"},
+			kvstore.KeyValue{Key: "b", Value: "b1"},
+			kvstore.KeyValue{Key: "c", Value: "c1"},
+			kvstore.KeyValue{Key: "d", Value: "d1"},
+		)
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "b", Value: "b2"})
+		store.WriteWithTimestamp("c", "pending", "tx3", "030")
+
+		var got []string
+		err := store.Scan("b", "d", "999", true, func(v kvstore.Version) bool {
+			got = append(got, v.Key+"="+v.Value)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Scan error: %v", err)
+		}
+		if want := []string{"c=c1", "b=b2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reverse Scan [b,d): got %q, want %q", got, want)
+		}
+
+		got = nil
+		store.Scan("", "", "015", true, func(v kvstore.Version) bool {
+			got = append(got, v.Key+"="+v.Value)
+			return true
+		})
+		if want := []string{"d=d1", "c=c1", "b=b1", "a=a1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reverse full Scan at 015: got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestBinaryKeysAndValues(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		pairs := []kvstore.KeyValue{
+			{Key: "\xff\x00\x01", Value: "\x00\xfe\xff"},
+			{Key: "\x80", Value: ""},
+			{Key: "\x00", Value: "\xc3\x28"}, // invalid UTF-8
+			{Key: "a", Value: "text"},
+		}
+		mustCommit(t, store, "tx1", "010", pairs...)
+		for _, kv := range pairs {
+			if got, err := store.Read(kv.Key, "999"); err != nil || got != kv.Value {
+				t.Errorf("Read(%q): got %q, %v, want %q", kv.Key, got, err, kv.Value)
+			}
+		}
+
+		var keys []string
+		store.Scan("", "", "999", false, func(v kvstore.Version) bool {
+			keys = append(keys, v.Key)
+			return true
+		})
+		if want := []string{"\x00", "a", "\x80", "\xff\x00\x01"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("expected bytewise key order %q, got %q", want, keys)
+		}
+	})
+}
+
+func TestGetExists(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "empty", Value: ""},
+			kvstore.KeyValue{Key: "deleted", Value: "v"},
+			kvstore.KeyValue{Key: "expiring", Value: "v", ExpiresAt: "030"})
+		mustBegin(t, store, "tx2", "020")
+		if err := store.DeleteWithTimestamp("deleted", "tx2", "020"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+
+		tests := []struct {
+			key, ts string
+			want    bool
+		}{
+			{"empty", "999", true},
+			{"missing", "999", false},
+			{"deleted", "015", true},
+			{"deleted", "020", false},
+			{"expiring", "029", true},
+			{"expiring", "030", false},
+		}
+		for _, tt := range tests {
+			v, exists, err := store.Get(tt.key, tt.ts)
+			if err != nil {
+				t.Fatalf("Get error: %v", err)
+			}
+			if exists != tt.want {
+				t.Errorf("Get(%q, %s): exists %v, want %v", tt.key, tt.ts, exists, tt.want)
+			}
+			if !exists && v != (kvstore.Version{}) {
+				t.Errorf("Get(%q, %s): expected no version, got %+v", tt.key, tt.ts, v)
+			}
+		}
+	})
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"user/":    "user0",
+		"a\xff":    "b",
+		"\xff\xff": "",
+		"":         "",
+	} {
+		if got := kvstore.PrefixEnd(prefix); got != want {
+			t.Errorf("PrefixEnd(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "a", Value: "a1"},
+			kvstore.KeyValue{Key: "b", Value: "b1"},
+			kvstore.KeyValue{Key: "c", Value: "c1"},
+		)
+		mustBegin(t, store, "tx2", "020")
+		if err := store.DeleteWithTimestamp("b", "tx2", "020"); err != nil {
+			t.Fatalf("Delete error: %v", err)
+		}
+		if got, _ := store.Read("b", "999"); got != "b1" {
+			t.Errorf("expected uncommitted delete to be invisible, got %q", got)
+		}
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+		mustCommit(t, store, "tx3", "030", kvstore.KeyValue{Key: "c", Value: "c3"})
+		mustBegin(t, store, "tx4", "040")
+		if err := store.DeleteWithTimestamp("c", "tx4", "040"); err != nil {
+			t.Fatalf("Delete error: %v", err)
+		}
+		if err := store.Abort("tx4"); err != nil {
+			t.Fatalf("Abort error: %v", err)
+		}
+
+		for ts, want := range map[string]string{"015": "b1", "020": "", "999": ""} {
+			if got, _ := store.Read("b", ts); got != want {
+				t.Errorf("Read b at %s: got %q, want %q", ts, got, want)
+			}
+		}
+		if got, _ := store.Read("c", "999"); got != "c3" {
+			t.Errorf("expected aborted delete to leave c3, got %q", got)
+		}
+
+		for _, reverse := range []bool{false, true} {
+			got := map[string]string{}
+			store.Scan("", "", "999", reverse, func(v kvstore.Version) bool {
+				got[v.Key] = v.Value
+				return true
+			})
+			if want := map[string]string{"a": "a1", "c": "c3"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Scan (reverse=%v) after delete: got %v, want %v", reverse, got, want)
+			}
+		}
+
+		// Tombstones survive a snapshot round trip
+		versions, err := store.Versions()
+		if err != nil {
+			t.Fatalf("Versions error: %v", err)
+		}
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(versions, nil, nil)); err != nil {
+			t.Fatalf("ReplaceAll error: %v", err)
+		}
+		if got, _ := store.Read("b", "999"); got != "" {
+			t.Errorf("expected b to stay deleted after ReplaceAll, got %q", got)
+		}
+	})
+}
+
+func TestExpiry(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "session", Value: "s1", ExpiresAt: "050"},
+			kvstore.KeyValue{Key: "renewed", Value: "r1", ExpiresAt: "030"},
+			kvstore.KeyValue{Key: "plain", Value: "p1"},
+		)
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "renewed", Value: "r2", ExpiresAt: "090"})
+
+		for ts, want := range map[string]string{"049": "s1", "050": "", "999": ""} {
+			if got, _ := store.Read("session", ts); got != want {
+				t.Errorf("Read session at %s: got %q, want %q", ts, got, want)
+			}
+		}
+		scan := func(ts string) []string {
+			var keys []string
+			store.Scan("", "", ts, false, func(v kvstore.Version) bool {
+				keys = append(keys, v.Key)
+				return true
+			})
+			return keys
+		}
+		if got, want := scan("060"), []string{"plain", "renewed"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Scan at 060: got %q, want %q", got, want)
+		}
+
+		expired, err := store.Expired("060", 10)
+		if err != nil {
+			t.Fatalf("Expired error: %v", err)
+		}
+		if len(expired) != 1 || expired[0].Key != "session" || expired[0].ExpiresAt != "050" {
+			t.Fatalf("expected only session to be expired at 060, got %+v", expired)
+		}
+		// A stale expiry of a key written again since is ignored
+		err = store.Expire([]kvstore.KeyValue{{Key: "session", ExpiresAt: "050"}, {Key: "renewed", ExpiresAt: "030"}})
+		if err != nil {
+			t.Fatalf("Expire error: %v", err)
+		}
+		if got, _ := store.Read("renewed", "060"); got != "r2" {
+			t.Errorf("expected renewed value to survive stale expiry, got %q", got)
+		}
+		if expired, _ := store.Expired("060", 10); len(expired) != 0 {
+			t.Errorf("expected nothing left to expire, got %+v", expired)
+		}
+		if got, _ := store.Read("session", "040"); got != "s1" {
+			t.Errorf("expected time-travel read before expiry to see s1, got %q", got)
+		}
+
+		// GC drops the expired version and its tombstone once past the threshold
+		if err := store.GC("100"); err != nil {
+			t.Fatalf("GC error: %v", err)
+		}
+		versions, _ := store.Versions()
+		var keys []string
+		for _, v := range versions {
+			keys = append(keys, v.Key+"@"+v.Timestamp)
+		}
+		if want := []string{"plain@010"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("versions after GC: got %q, want %q", keys, want)
+		}
+	})
+}
+
+func TestSameTimestamp(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		// Versions of a key at one timestamp are kept apart by transaction
+		// and the greatest transaction ID wins
+		mustCommit(t, store, "txb", "010", kvstore.KeyValue{Key: "k", Value: "b"})
+		mustCommit(t, store, "txa", "010", kvstore.KeyValue{Key: "k", Value: "a"})
+		if got, _ := store.Read("k", "010"); got != "b" {
+			t.Errorf("expected the version of txb at 010, got %q", got)
+		}
+
+		// An expiry tombstone does not replace a write pending at the expiry
+		mustCommit(t, store, "tx1", "020", kvstore.KeyValue{Key: "s", Value: "v1", ExpiresAt: "050"})
+		mustBegin(t, store, "tx2", "050")
+		if err := store.WriteWithTimestamp("s", "v2", "tx2", "050"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		if err := store.Expire([]kvstore.KeyValue{{Key: "s", ExpiresAt: "050"}}); err != nil {
+			t.Fatalf("Expire error: %v", err)
+		}
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		if got, _ := store.Read("s", "050"); got != "v2" {
+			t.Errorf("expected v2 to survive the expiry at its timestamp, got %q", got)
+		}
+
+		versions, err := store.Versions()
+		if err != nil {
+			t.Fatalf("Versions error: %v", err)
+		}
+		var got []string
+		for _, v := range versions {
+			got = append(got, v.Key+"@"+v.Timestamp+"/"+v.TxID)
+		}
+		want := []string{"k@010/txa", "k@010/txb", "s@020/tx1", "s@050/expire-050", "s@050/tx2"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("versions: got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010", kvstore.KeyValue{Key: "k", Value: "v1"}, kvstore.KeyValue{Key: "k\x00", Value: "other"})
+		if err := store.WriteWithTimestamp("k", "pending", "tx2", "015"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		mustBegin(t, store, "tx3", "020")
+		if err := store.DeleteWithTimestamp("k", "tx3", "020"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+		if err := store.Commit("tx3"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		mustCommit(t, store, "tx4", "030", kvstore.KeyValue{Key: "k", Value: "v3", ExpiresAt: "090"}, kvstore.KeyValue{Key: "kk", Value: "other"})
+
+		history := func(from, to string, limit int) []kvstore.Version {
+			t.Helper()
+			var got []kvstore.Version
+			err := store.History("k", from, to, func(v kvstore.Version) bool {
+				got = append(got, v)
+				return len(got) != limit
+			})
+			if err != nil {
+				t.Fatalf("History error: %v", err)
+			}
+			return got
+		}
+
+		want := []kvstore.Version{
+			{Key: "k", Value: "v3", Timestamp: "030", TxID: "tx4", Committed: true, ExpiresAt: "090", CommitSeq: 3},
+			{Key: "k", Timestamp: "020", TxID: "tx3", Committed: true, Tombstone: true, CommitSeq: 2},
+			{Key: "k", Value: "v1", Timestamp: "010", TxID: "tx1", Committed: true, CommitSeq: 1},
+		}
+		if got := history("", "", 0); !reflect.DeepEqual(got, want) {
+			t.Errorf("full history:\ngot  %+v\nwant %+v", got, want)
+		}
+		if got := history("015", "029", 0); !reflect.DeepEqual(got, want[1:2]) {
+			t.Errorf("history in [015, 029]: got %+v", got)
+		}
+		if got := history("020", "030", 0); !reflect.DeepEqual(got, want[:2]) {
+			t.Errorf("history in [020, 030]: got %+v", got)
+		}
+		if got := history("", "", 2); !reflect.DeepEqual(got, want[:2]) {
+			t.Errorf("history stopped after 2: got %+v", got)
+		}
+		if got := history("", "009", 0); len(got) != 0 {
+			t.Errorf("expected no history before 010, got %+v", got)
+		}
+	})
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		cas := func(key, value, ts string, cond kvstore.Condition) kvstore.CASResult {
+			t.Helper()
+			r, err := store.CompareAndSwap(kvstore.KeyValue{Key: key, Value: value}, cond, "cas"+ts, ts)
+			if err != nil {
+				t.Fatalf("CompareAndSwap at %s error: %v", ts, err)
+			}
+			return r
+		}
+		notExists := kvstore.Condition{Kind: kvstore.NotExists}
+
+		if r := cas("k", "v1", "010", notExists); !r.Succeeded || r.Current.Timestamp != "010" {
+			t.Fatalf("expected create to succeed, got %+v", r)
+		}
+		r := cas("k", "x", "020", kvstore.Condition{Kind: kvstore.ValueEquals, Value: "v0"})
+		if r.Succeeded || !r.Exists || r.Current.Value != "v1" || r.Current.Timestamp != "010" {
+			t.Errorf("expected value mismatch to report v1 at 010, got %+v", r)
+		}
+		if r := cas("k", "v2", "030", kvstore.Condition{Kind: kvstore.ValueEquals, Value: "v1"}); !r.Succeeded {
+			t.Errorf("expected value match to succeed, got %+v", r)
+		}
+		if r := cas("k", "x", "040", kvstore.Condition{Kind: kvstore.VersionEquals, Version: "010"}); r.Succeeded {
+			t.Errorf("expected stale version to fail, got %+v", r)
+		}
+		if r := cas("k", "v3", "050", kvstore.Condition{Kind: kvstore.VersionEquals, Version: "030"}); !r.Succeeded {
+			t.Errorf("expected version match to succeed, got %+v", r)
+		}
+		if got, _ := store.Read("k", "999"); got != "v3" {
+			t.Errorf("expected v3, got %q", got)
+		}
+
+		// Uncommitted writes are not seen by the condition
+		if err := store.WriteWithTimestamp("p", "pending", "tx1", "060"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		if r := cas("p", "v1", "070", notExists); !r.Succeeded {
+			t.Errorf("expected pending write to be ignored, got %+v", r)
+		}
+
+		// Deleted and expired keys count as absent
+		mustBegin(t, store, "tx2", "080")
+		if err := store.DeleteWithTimestamp("k", "tx2", "080"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		if r := cas("k", "v4", "090", notExists); !r.Succeeded {
+			t.Errorf("expected create after delete to succeed, got %+v", r)
+		}
+		mustCommit(t, store, "tx3", "100", kvstore.KeyValue{Key: "e", Value: "v", ExpiresAt: "110"})
+		if r := cas("e", "x", "120", kvstore.Condition{Kind: kvstore.ValueEquals, Value: "v"}); r.Succeeded || r.Exists {
+			t.Errorf("expected expired key to be absent, got %+v", r)
+		}
+		if r := cas("e", "v2", "130", notExists); !r.Succeeded {
+			t.Errorf("expected create after expiry to succeed, got %+v", r)
+		}
+
+		// A CAS proposed at an older timestamp than the newest committed
+		// version must not be checked against the state at its timestamp
+		if r := cas("n", "first", "201", notExists); !r.Succeeded {
+			t.Fatalf("expected create at 201 to succeed, got %+v", r)
+		}
+		for _, ts := range []string{"200", "201"} {
+			_, err := store.CompareAndSwap(kvstore.KeyValue{Key: "n", Value: "second"}, notExists, "cas-old"+ts, ts)
+			var tooOld *kvstore.WriteTooOldError
+			if !errors.Is(err, kvstore.ErrWriteTooOld) || !errors.As(err, &tooOld) || tooOld.Newer != "201" {
+				t.Errorf("expected ErrWriteTooOld for CAS at %s, got %v", ts, err)
+			}
+		}
+		if got, _ := store.Read("n", "999"); got != "first" {
+			t.Errorf("expected the first create to stand, got %q", got)
+		}
+	})
+}
+
+func TestTxn(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "balance", Value: "100"},
+			kvstore.KeyValue{Key: "old", Value: "x"},
+		)
+		compares := []kvstore.Comparison{
+			{Key: "balance", Condition: kvstore.Condition{Kind: kvstore.ValueEquals, Value: "100"}},
+			{Key: "lock", Condition: kvstore.Condition{Kind: kvstore.NotExists}},
+		}
+		success := []kvstore.TxnOp{
+			{Kind: kvstore.TxnPut, Key: "balance", Value: "90"},
+			{Kind: kvstore.TxnDelete, Key: "old"},
+			{Kind: kvstore.TxnGet, Key: "balance"},
+			{Kind: kvstore.TxnGet, Key: "old"},
+		}
+		failure := []kvstore.TxnOp{{Kind: kvstore.TxnGet, Key: "balance"}}
+
+		r, err := store.Txn(compares, success, failure, "tx2", "020")
+		if err != nil {
+			t.Fatalf("Txn error: %v", err)
+		}
+		if !r.Succeeded || len(r.Results) != 4 {
+			t.Fatalf("expected success branch with 4 results, got %+v", r)
+		}
+		if got := r.Results[2]; !got.Exists || got.Current.Value != "90" || got.Current.Timestamp != "020" {
+			t.Errorf("expected get to see the put in the same branch, got %+v", got)
+		}
+		if r.Results[3].Exists {
+			t.Errorf("expected get to see the delete in the same branch, got %+v", r.Results[3])
+		}
+		if got, _ := store.Read("balance", "999"); got != "90" {
+			t.Errorf("expected committed balance 90, got %q", got)
+		}
+		if got, _ := store.Read("old", "999"); got != "" {
+			t.Errorf("expected old to be deleted, got %q", got)
+		}
+		if got, _ := store.Read("balance", "015"); got != "100" {
+			t.Errorf("expected balance 100 before the txn, got %q", got)
+		}
+
+		// The same comparisons now fail, so only the failure branch runs
+		r, err = store.Txn(compares, success, failure, "tx3", "030")
+		if err != nil {
+			t.Fatalf("Txn error: %v", err)
+		}
+		if r.Succeeded || len(r.Results) != 1 || r.Results[0].Current.Value != "90" {
+			t.Errorf("expected failure branch reading 90, got %+v", r)
+		}
+		if got, _ := store.Read("old", "999"); got != "" {
+			t.Errorf("expected failure branch not to write, got %q", got)
+		}
+
+		twice := []kvstore.TxnOp{{Kind: kvstore.TxnPut, Key: "k"}, {Kind: kvstore.TxnDelete, Key: "k"}}
+		if _, err := store.Txn(nil, twice, nil, "tx4", "040"); err == nil {
+			t.Error("expected a key written twice to be rejected")
+		}
+
+		// A Txn at an older timestamp than a version of a key it compares,
+		// reads or writes is refused as a whole
+		mustCommit(t, store, "tx5", "100", kvstore.KeyValue{Key: "late", Value: "v"})
+		for name, txn := range map[string]struct {
+			compares []kvstore.Comparison
+			ops      []kvstore.TxnOp
+		}{
+			"compare": {compares: []kvstore.Comparison{{Key: "late", Condition: kvstore.Condition{Kind: kvstore.NotExists}}}},
+			"get":     {ops: []kvstore.TxnOp{{Kind: kvstore.TxnGet, Key: "late"}}},
+			"put":     {ops: []kvstore.TxnOp{{Kind: kvstore.TxnPut, Key: "late", Value: "older"}}},
+		} {
+			ops := append([]kvstore.TxnOp{{Kind: kvstore.TxnPut, Key: "other", Value: name}}, txn.ops...)
+			if _, err := store.Txn(txn.compares, ops, nil, "tx-"+name, "090"); !errors.Is(err, kvstore.ErrWriteTooOld) {
+				t.Errorf("%s: expected ErrWriteTooOld, got %v", name, err)
+			}
+		}
+		if got, _ := store.Read("other", "999"); got != "" {
+			t.Errorf("expected refused txns not to write, got %q", got)
+		}
+	})
+}
+
+func TestGC(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010",
+			kvstore.KeyValue{Key: "k", Value: "v1"},
+			kvstore.KeyValue{Key: "d", Value: "d1"},
+			kvstore.KeyValue{Key: "e", Value: "e1"},
+		)
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "k", Value: "v2"})
+		mustBegin(t, store, "tx3", "020")
+		if err := store.DeleteWithTimestamp("d", "tx3", "020"); err != nil {
+			t.Fatalf("Delete error: %v", err)
+		}
+		store.Commit("tx3")
+		mustCommit(t, store, "tx4", "040", kvstore.KeyValue{Key: "k", Value: "v4"})
+		store.WriteWithTimestamp("p", "abandoned", "tx5", "015")
+		mustBegin(t, store, "tx6", "050")
+		store.WriteWithTimestamp("q", "open", "tx6", "050")
+
+		if err := store.GC("030"); err != nil {
+			t.Fatalf("GC error: %v", err)
+		}
+		versions, err := store.Versions()
+		if err != nil {
+			t.Fatalf("Versions error: %v", err)
+		}
+		var got []string
+		for _, v := range versions {
+			got = append(got, v.Key+"@"+v.Timestamp)
+		}
+		if want := []string{"e@010", "k@020", "k@040", "q@050"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("versions after GC: got %q, want %q", got, want)
+		}
+
+		for ts, want := range map[string]string{"030": "v2", "999": "v4"} {
+			if got, err := store.Read("k", ts); err != nil || got != want {
+				t.Errorf("Read k at %s: got %q, %v, want %q", ts, got, err, want)
+			}
+		}
+		if _, err := store.Read("k", "025"); !errors.Is(err, kvstore.ErrBelowGCThreshold) {
+			t.Errorf("expected ErrBelowGCThreshold reading below threshold, got %v", err)
+		}
+		if err := store.Scan("", "", "025", false, func(kvstore.Version) bool { return true }); !errors.Is(err, kvstore.ErrBelowGCThreshold) {
+			t.Errorf("expected ErrBelowGCThreshold scanning below threshold, got %v", err)
+		}
+
+		// An older threshold is a no-op
+		if err := store.GC("020"); err != nil || store.GCThreshold() != "030" {
+			t.Errorf("expected GC threshold to stay 030, got %q, %v", store.GCThreshold(), err)
+		}
+		if err := store.Commit("tx6"); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+		if got, _ := store.Read("q", "999"); got != "open" {
+			t.Errorf("expected write newer than threshold to survive GC, got %q", got)
+		}
+
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(nil, nil, nil)); err != nil || store.GCThreshold() != "" {
+			t.Errorf("expected ReplaceAll to clear GC threshold, got %q, %v", store.GCThreshold(), err)
+		}
+		meta := map[string]string{kvstore.GCThresholdMeta: "040"}
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(nil, nil, meta)); err != nil || store.GCThreshold() != "040" {
+			t.Errorf("expected ReplaceAll to restore GC threshold 040, got %q, %v", store.GCThreshold(), err)
+		}
+	})
+}
+
+func TestReadDuringGC(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		// Each round reads the older of two versions while GC collects it:
+		// the read either sees it or fails, but never misses it
+		for round := 0; round < 50; round++ {
+			ts := func(offset int) string { return fmt.Sprintf("%06d", round*10+offset) }
+			key := fmt.Sprintf("k%02d", round)
+			mustCommit(t, store, "old-"+key, ts(1), kvstore.KeyValue{Key: key, Value: "old"})
+			mustCommit(t, store, "new-"+key, ts(5), kvstore.KeyValue{Key: key, Value: "new"})
+
+			done := make(chan error, 1)
+			go func() { done <- store.GC(ts(7)) }()
+			for reading := true; reading; {
+				select {
+				case err := <-done:
+					if err != nil {
+						t.Fatalf("GC error: %v", err)
+					}
+					reading = false
+				default:
+				}
+				got, err := store.Read(key, ts(3))
+				if err == nil && got != "old" {
+					t.Fatalf("round %d: read %q at %s during GC, want old or ErrBelowGCThreshold", round, got, ts(3))
+				}
+				if err != nil && !errors.Is(err, kvstore.ErrBelowGCThreshold) {
+					t.Fatalf("Read error: %v", err)
+				}
+				var scanned []string
+				err = store.Scan(key, key+"\x00", ts(3), false, func(v kvstore.Version) bool {
+					scanned = append(scanned, v.Value)
+					return true
+				})
+				if err == nil && !reflect.DeepEqual(scanned, []string{"old"}) {
+					t.Fatalf("round %d: scanned %q at %s during GC, want old or ErrBelowGCThreshold", round, scanned, ts(3))
+				}
+				if err != nil && !errors.Is(err, kvstore.ErrBelowGCThreshold) {
+					t.Fatalf("Scan error: %v", err)
+				}
+			}
+		}
+	})
+}
+
+func TestGCThresholdPersists(t *testing.T) {
+	for _, engine := range []string{kvstore.EngineSQLite, kvstore.EngineBolt} {
+		t.Run(engine, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kv.db")
+			store, err := kvstore.Open(engine, path)
+			if err != nil {
+				t.Fatalf("Open error: %v", err)
+			}
+			if err := store.GC("030"); err != nil {
+				t.Fatalf("GC error: %v", err)
+			}
+			store.Close()
+
+			store, err = kvstore.Open(engine, path)
+			if err != nil {
+				t.Fatalf("reopen error: %v", err)
+			}
+			if got := store.GCThreshold(); got != "030" {
+				t.Errorf("expected GC threshold 030 after reopen, got %q", got)
+			}
+
+			// A threshold restored from a snapshot is stored with the versions
+			if err := store.ReplaceAll(kvstore.NewSnapshotSource(nil, nil, map[string]string{kvstore.GCThresholdMeta: "050"})); err != nil {
+				t.Fatalf("ReplaceAll error: %v", err)
+			}
+			store.Close()
+			store, err = kvstore.Open(engine, path)
+			if err != nil {
+				t.Fatalf("reopen error: %v", err)
+			}
+			defer store.Close()
+			if got := store.GCThreshold(); got != "050" {
+				t.Errorf("expected restored GC threshold 050 after reopen, got %q", got)
+			}
+		})
+	}
+}
+
+func TestNodeAddressPersists(t *testing.T) {
+	for _, engine := range []string{kvstore.EngineSQLite, kvstore.EngineBolt} {
+		t.Run(engine, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kv.db")
+			store, err := kvstore.Open(engine, path)
+			if err != nil {
+				t.Fatalf("Open error: %v", err)
+			}
+			if _, ok := store.NodeAddress("node4"); ok {
+				t.Errorf("expected no address before registration")
+			}
+			if err := store.RegisterNode("node4", "node4:50051"); err != nil {
+				t.Fatalf("RegisterNode error: %v", err)
+			}
+			if err := store.RegisterNode("node4", "node4:50054"); err != nil {
+				t.Fatalf("RegisterNode error: %v", err)
+			}
+			store.Close()
+
+			store, err = kvstore.Open(engine, path)
+			if err != nil {
+				t.Fatalf("reopen error: %v", err)
+			}
+			defer store.Close()
+			if addr, ok := store.NodeAddress("node4"); !ok || addr != "node4:50054" {
+				t.Errorf("expected node4:50054 after reopen, got %q, %v", addr, ok)
+			}
+		})
+	}
+}
+
+// failingSource yields one batch of versions and then fails
+type failingSource struct {
+	sent bool
+}
+
+func (s *failingSource) NextVersions() ([]kvstore.Version, error) {
+	if s.sent {
+		return nil, errors.New("truncated snapshot")
+	}
+	s.sent = true
+	return []kvstore.Version{{Key: "new", Value: "y", Timestamp: "010", TxID: "tx1", Committed: true}}, nil
+}
+
+func (s *failingSource) NextTxns() ([]kvstore.TxnRecord, error) { return nil, nil }
+
+func (s *failingSource) Meta() (map[string]string, error) { return nil, nil }
+
+func TestReplaceAllFailureKeepsStore(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "001", kvstore.KeyValue{Key: "old", Value: "x"})
+		if err := store.ReplaceAll(&failingSource{}); err == nil {
+			t.Fatal("expected ReplaceAll to fail")
+		}
+		if got, _ := store.Read("old", "999"); got != "x" {
+			t.Errorf("expected old to survive a failed ReplaceAll, got %q", got)
+		}
+		if got, _ := store.Read("new", "999"); got != "" {
+			t.Errorf("expected the partial snapshot to be rolled back, got %q", got)
+		}
+	})
+}
+
+func TestReplaceAll(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "001", kvstore.KeyValue{Key: "old", Value: "x"})
+		want := []kvstore.Version{
+			{Key: "k", Value: "v1", Timestamp: "010", TxID: "tx1", Committed: true, ExpiresAt: "500"},
+			{Key: "k", Value: "v2", Timestamp: "020", TxID: "tx2"},
+		}
+		txns := []kvstore.TxnRecord{{TxID: "tx2", StartTimestamp: "020"}}
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(want, txns, nil)); err != nil {
+			t.Fatalf("ReplaceAll error: %v", err)
+		}
+		got, err := store.Versions()
+		if err != nil {
+			t.Fatalf("Versions error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Versions after ReplaceAll: got %+v, want %+v", got, want)
+		}
+		// Pending transactions restored from a snapshot can still commit
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("Commit error: %v", err)
+		}
+		if got, _ := store.Read("k", "999"); got != "v2" {
+			t.Errorf("expected v2, got %q", got)
+		}
+	})
+}
+
+func TestOpenUnknownEngine(t *testing.T) {
+	if _, err := kvstore.Open("rocks", filepath.Join(t.TempDir(), "kv.db")); err == nil {
+		t.Fatal("expected error for unknown engine")
+	}
+}
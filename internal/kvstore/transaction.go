@@ -0,0 +1,301 @@
+// internal/kvstore/transaction.go
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrConflict is returned, wrapped in a *ConflictError, when a transaction
+// cannot commit because another transaction committed a write to one of its
+// keys after it began. The transaction is aborted; it may be retried.
+var ErrConflict = errors.New("write-write conflict")
+
+// ConflictError reports the transaction that was aborted and the key that
+// was written by a later commit
+type ConflictError struct {
+	TxID string
+	Key  string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: transaction %s aborted, %q was written after it began", ErrConflict, e.TxID, e.Key)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// ErrSerialization is returned, wrapped in a *SerializationError, when a
+// SERIALIZABLE transaction cannot commit because another transaction
+// committed a write to something it read after it began. The transaction is
+// aborted; it may be retried.
+var ErrSerialization = errors.New("serialization failure")
+
+// SerializationError reports the transaction that was aborted and the key,
+// read by it, that was written by a later commit
+type SerializationError struct {
+	TxID string
+	Key  string
+}
+
+func (e *SerializationError) Error() string {
+	return fmt.Sprintf("%v: transaction %s aborted, %q it read was written after it began", ErrSerialization, e.TxID, e.Key)
+}
+
+func (e *SerializationError) Unwrap() error {
+	return ErrSerialization
+}
+
+// ErrTxnExists is returned by Begin for a transaction ID that already has
+// a record
+var ErrTxnExists = errors.New("transaction already exists")
+
+// ErrTxnNotFound reports a transaction ID without a record: it was never
+// begun, or its record was garbage collected
+var ErrTxnNotFound = errors.New("transaction not found")
+
+// ErrTxnCommitted and ErrTxnAborted are returned, wrapped in a
+// *TxnStateError, for a write, commit or abort of a transaction that has
+// already finished
+var (
+	ErrTxnCommitted = errors.New("transaction already committed")
+	ErrTxnAborted   = errors.New("transaction already aborted")
+)
+
+// TxnStateError reports an operation refused because of the status of a
+// transaction
+type TxnStateError struct {
+	TxID   string
+	Op     string // "write to", "commit" or "abort"
+	Status TxnStatus
+}
+
+func (e *TxnStateError) Error() string {
+	return fmt.Sprintf("cannot %s transaction %s: %v", e.Op, e.TxID, e.Unwrap())
+}
+
+func (e *TxnStateError) Unwrap() error {
+	if e.Status == TxnAborted {
+		return ErrTxnAborted
+	}
+	return ErrTxnCommitted
+}
+
+// TxnStatus is the state of a transaction in its record
+type TxnStatus int
+
+const (
+	// TxnPending transactions may still write, commit or abort
+	TxnPending TxnStatus = iota
+	TxnCommitted
+	TxnAborted
+)
+
+func (s TxnStatus) String() string {
+	switch s {
+	case TxnPending:
+		return "pending"
+	case TxnCommitted:
+		return "committed"
+	case TxnAborted:
+		return "aborted"
+	default:
+		return fmt.Sprintf("TxnStatus(%d)", int(s))
+	}
+}
+
+// Isolation is the isolation level of a transaction
+type Isolation int
+
+const (
+	// SnapshotIsolation reads the snapshot at the start timestamp and
+	// refuses to commit writes to keys written by a later commit
+	SnapshotIsolation Isolation = iota
+	// Serializable additionally refuses to commit if anything the
+	// transaction read was written by a later commit, ruling out write skew
+	Serializable
+)
+
+// KeyRange is the range of keys [Start, End) read by a transaction. An empty
+// End is unbounded.
+type KeyRange struct {
+	Start string
+	End   string
+}
+
+// PointRange is the range holding only key
+func PointRange(key string) KeyRange {
+	return KeyRange{Start: key, End: key + "\x00"}
+}
+
+// TxnRecord is the replicated record of a transaction begun with Begin. It
+// is kept once the transaction finished, with its outcome, until GC collects
+// it along with the versions older than its start.
+type TxnRecord struct {
+	TxID string
+	// StartTimestamp is the snapshot the transaction reads at
+	StartTimestamp string
+	// StartSeq is the commit sequence of the last commit before the
+	// transaction began
+	StartSeq  uint64
+	Isolation Isolation
+	// Owner is the ID of the node that began the transaction, as leader
+	Owner  string
+	Status TxnStatus
+	// CommitTimestamp is the HLC time of the commit of a committed
+	// transaction
+	CommitTimestamp string
+}
+
+// Begin records the start of transaction txID by node owner, pending and
+// reading the snapshot at startTimestamp. Its writes can only commit if no
+// other transaction commits a write to the same keys in the meantime, nor,
+// under Serializable, to what it read; see CommitReads. Begin fails with
+// ErrTxnExists if txID already has a record, which it leaves as it is.
+func (s *Store) Begin(txID, startTimestamp string, isolation Isolation, owner string) error {
+	if _, ok, err := s.engine.GetTxn(txID); err != nil {
+		return err
+	} else if ok {
+		return fmt.Errorf("%w: %s", ErrTxnExists, txID)
+	}
+	s.watchMu.Lock()
+	startSeq := s.commitSeq
+	s.watchMu.Unlock()
+	return s.engine.PutTxn(TxnRecord{
+		TxID:           txID,
+		StartTimestamp: startTimestamp,
+		StartSeq:       startSeq,
+		Isolation:      isolation,
+		Owner:          owner,
+	})
+}
+
+// Transaction returns the record of a transaction begun with Begin, until
+// GC collects it
+func (s *Store) Transaction(txID string) (TxnRecord, bool, error) {
+	return s.engine.GetTxn(txID)
+}
+
+// PendingTransaction returns the record of txID if it is pending. It fails
+// with ErrTxnNotFound if there is no record, or with a *TxnStateError naming
+// op if the transaction already finished.
+func (s *Store) PendingTransaction(txID, op string) (TxnRecord, error) {
+	rec, ok, err := s.engine.GetTxn(txID)
+	if err != nil {
+		return TxnRecord{}, err
+	}
+	if !ok {
+		return TxnRecord{}, fmt.Errorf("%w: %q", ErrTxnNotFound, txID)
+	}
+	if rec.Status != TxnPending {
+		return TxnRecord{}, &TxnStateError{TxID: txID, Op: op, Status: rec.Status}
+	}
+	return rec, nil
+}
+
+// checkWritable fails with a *TxnStateError if txID has a record and has
+// already finished. Writes of transactions without a record, which predate
+// records, are allowed.
+func (s *Store) checkWritable(txID string) error {
+	rec, ok, err := s.engine.GetTxn(txID)
+	if err != nil {
+		return err
+	}
+	if ok && rec.Status != TxnPending {
+		return &TxnStateError{TxID: txID, Op: "write to", Status: rec.Status}
+	}
+	return nil
+}
+
+// checkConflicts returns a *ConflictError if a key written by the
+// transaction of rec has a committed version newer than its start timestamp,
+// or one committed after it began. The changes since the start are read in a
+// single pass, and the newer versions of every written key in one batch.
+func (s *Store) checkConflicts(rec TxnRecord) error {
+	writes, err := s.ownWrites(rec.TxID, "", "")
+	if err != nil || len(writes) == 0 {
+		return err
+	}
+	keys := make([]string, len(writes))
+	written := make(map[string]bool, len(writes))
+	for i, w := range writes {
+		keys[i] = w.Key
+		written[w.Key] = true
+	}
+
+	var conflict string
+	err = s.engine.Changes(keys[0], keys[len(keys)-1]+"\x00", rec.StartSeq+1, func(v Version) bool {
+		if written[v.Key] && !expiredBefore(rec, v) {
+			conflict = v.Key
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if conflict == "" {
+		err = s.engine.Newer(keys, rec.StartTimestamp, func(v Version) bool {
+			conflict = v.Key
+			return false
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if conflict != "" {
+		return &ConflictError{TxID: rec.TxID, Key: conflict}
+	}
+	return nil
+}
+
+// checkReads returns a *SerializationError if a key in reads has a
+// committed version newer than the start timestamp of rec, or one committed
+// after the transaction began
+func (s *Store) checkReads(rec TxnRecord, reads []KeyRange) error {
+	for _, r := range reads {
+		key, err := s.writtenSince(rec, r)
+		if err != nil {
+			return err
+		}
+		if key != "" {
+			return &SerializationError{TxID: rec.TxID, Key: key}
+		}
+	}
+	return nil
+}
+
+// writtenSince returns a key of r committed after the transaction of rec
+// began, or "" if there is none. A version newer than the start timestamp
+// counts even if it committed first, an expiry the snapshot already saw does
+// not.
+func (s *Store) writtenSince(rec TxnRecord, r KeyRange) (string, error) {
+	var written string
+	err := s.engine.Changes(r.Start, r.End, rec.StartSeq+1, func(v Version) bool {
+		if expiredBefore(rec, v) {
+			return true
+		}
+		written = v.Key
+		return false
+	})
+	if err != nil || written != "" {
+		return written, err
+	}
+	err = s.engine.Scan(r.Start, r.End, latestTimestamp, false, func(v Version) bool {
+		if v.Timestamp > rec.StartTimestamp {
+			written = v.Key
+			return false
+		}
+		return true
+	})
+	return written, err
+}
+
+// expiredBefore reports whether v is a tombstone written by Expire for an
+// expiry at or before the start of the transaction of rec. Its snapshot
+// already saw the key as expired, so the tombstone changes nothing it read.
+func expiredBefore(rec TxnRecord, v Version) bool {
+	return v.Tombstone && strings.HasPrefix(v.TxID, expiryTxPrefix) && v.Timestamp <= rec.StartTimestamp
+}
@@ -0,0 +1,385 @@
+package kvstore_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+)
+
+// mustBegin records the start of txID at ts under snapshot isolation
+func mustBegin(t *testing.T, store *kvstore.Store, txID, ts string) {
+	t.Helper()
+	mustBeginAt(t, store, txID, ts, kvstore.SnapshotIsolation)
+}
+
+// mustBeginAt records the start of txID at ts under isolation
+func mustBeginAt(t *testing.T, store *kvstore.Store, txID, ts string, isolation kvstore.Isolation) {
+	t.Helper()
+	if err := store.Begin(txID, ts, isolation, "node1"); err != nil {
+		t.Fatalf("Begin(%s) error: %v", txID, err)
+	}
+}
+
+// mustWrite writes key as an uncommitted version of txID at ts
+func mustWrite(t *testing.T, store *kvstore.Store, txID, ts, key, value string) {
+	t.Helper()
+	if err := store.WriteWithTimestamp(key, value, txID, ts); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+}
+
+// expectStatus checks that the record of txID has status
+func expectStatus(t *testing.T, store *kvstore.Store, txID string, status kvstore.TxnStatus) {
+	t.Helper()
+	rec, ok, err := store.Transaction(txID)
+	if err != nil || !ok {
+		t.Fatalf("Transaction(%s) = %+v, %v, %v", txID, rec, ok, err)
+	}
+	if rec.Status != status {
+		t.Errorf("expected %s to be %v, got %v", txID, status, rec.Status)
+	}
+}
+
+// expectConflict checks that committing txID fails with a conflict on key
+// and aborts the transaction
+func expectConflict(t *testing.T, store *kvstore.Store, txID, key string) {
+	t.Helper()
+	err := store.Commit(txID)
+	var conflict *kvstore.ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, kvstore.ErrConflict) {
+		t.Fatalf("expected conflict committing %s, got %v", txID, err)
+	}
+	if conflict.TxID != txID || conflict.Key != key {
+		t.Errorf("expected conflict of %s on %q, got %+v", txID, key, conflict)
+	}
+	versions, err := store.Versions()
+	if err != nil {
+		t.Fatalf("Versions error: %v", err)
+	}
+	for _, v := range versions {
+		if v.TxID == txID {
+			t.Errorf("expected the writes of %s to be aborted, found %+v", txID, v)
+		}
+	}
+	expectStatus(t, store, txID, kvstore.TxnAborted)
+}
+
+func TestCommitConflict(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "005", kvstore.KeyValue{Key: "k", Value: "v0"})
+
+		// tx2 commits k after tx1 began, so tx1 loses
+		mustBegin(t, store, "tx1", "010")
+		mustBegin(t, store, "tx2", "015")
+		mustWrite(t, store, "tx2", "020", "k", "v2")
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("Commit(tx2) error: %v", err)
+		}
+		mustWrite(t, store, "tx1", "030", "k", "v1")
+		mustWrite(t, store, "tx1", "030", "other", "v1")
+		expectConflict(t, store, "tx1", "k")
+		if got, _ := store.Read("k", "999"); got != "v2" {
+			t.Errorf("expected v2 to survive, got %q", got)
+		}
+		if got, _ := store.Read("other", "999"); got != "" {
+			t.Errorf("expected the other write of tx1 to be aborted, got %q", got)
+		}
+
+		// tx4 wrote before tx3 began but commits after, which also conflicts
+		mustBegin(t, store, "tx4", "035")
+		mustWrite(t, store, "tx4", "036", "k", "v4")
+		mustBegin(t, store, "tx3", "040")
+		if err := store.Commit("tx4"); err != nil {
+			t.Fatalf("Commit(tx4) error: %v", err)
+		}
+		mustWrite(t, store, "tx3", "050", "k", "v3")
+		expectConflict(t, store, "tx3", "k")
+
+		// A version newer than the start timestamp conflicts even if it
+		// committed first
+		mustCommit(t, store, "tx5", "100", kvstore.KeyValue{Key: "k", Value: "v5"})
+		mustBegin(t, store, "tx6", "060")
+		mustWrite(t, store, "tx6", "110", "k", "v6")
+		expectConflict(t, store, "tx6", "k")
+	})
+}
+
+func TestCommitConflictManyKeys(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		// Only the last of many written keys has a newer version, which
+		// committed before tx1 began
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "k1199", Value: "v2"})
+		mustBegin(t, store, "tx1", "010")
+		pairs := make([]kvstore.KeyValue, 1200)
+		for i := range pairs {
+			pairs[i] = kvstore.KeyValue{Key: fmt.Sprintf("k%04d", i), Value: "v1"}
+		}
+		if err := store.WriteBatchWithTimestamp(pairs, "tx1", "030"); err != nil {
+			t.Fatalf("WriteBatchWithTimestamp error: %v", err)
+		}
+		expectConflict(t, store, "tx1", "k1199")
+	})
+}
+
+func TestCommitWithoutConflict(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "005", kvstore.KeyValue{Key: "a", Value: "a0"}, kvstore.KeyValue{Key: "b", Value: "b0"})
+
+		// Concurrent transactions writing different keys both commit
+		mustBegin(t, store, "tx1", "010")
+		mustBegin(t, store, "tx2", "011")
+		mustWrite(t, store, "tx1", "020", "a", "a1")
+		mustWrite(t, store, "tx2", "021", "b", "b2")
+		for _, txID := range []string{"tx2", "tx1"} {
+			if err := store.Commit(txID); err != nil {
+				t.Fatalf("Commit(%s) error: %v", txID, err)
+			}
+			expectStatus(t, store, txID, kvstore.TxnCommitted)
+		}
+
+		// A transaction that begins after a commit does not conflict with it
+		mustBegin(t, store, "tx3", "030")
+		mustWrite(t, store, "tx3", "040", "a", "a3")
+		if err := store.Commit("tx3"); err != nil {
+			t.Fatalf("Commit(tx3) error: %v", err)
+		}
+		for key, want := range map[string]string{"a": "a3", "b": "b2"} {
+			if got, _ := store.Read(key, "999"); got != want {
+				t.Errorf("Read(%q) = %q, want %q", key, got, want)
+			}
+		}
+	})
+}
+
+func TestCommitAfterExpiry(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "020",
+			kvstore.KeyValue{Key: "s", Value: "v0", ExpiresAt: "050"},
+			kvstore.KeyValue{Key: "e", Value: "v0", ExpiresAt: "090"})
+
+		// s expired before tx1 and tx2 began, so the tombstone making it
+		// permanent changes nothing they saw
+		mustBegin(t, store, "tx1", "060")
+		mustBeginAt(t, store, "tx2", "060", kvstore.Serializable)
+		if err := store.Expire([]kvstore.KeyValue{{Key: "s", ExpiresAt: "050"}}); err != nil {
+			t.Fatalf("Expire error: %v", err)
+		}
+		mustWrite(t, store, "tx2", "070", "other", "v2")
+		if err := store.CommitReads("tx2", "", []kvstore.KeyRange{kvstore.PointRange("s")}); err != nil {
+			t.Fatalf("CommitReads(tx2) error: %v", err)
+		}
+		mustWrite(t, store, "tx1", "070", "s", "v1")
+		if err := store.Commit("tx1"); err != nil {
+			t.Fatalf("Commit(tx1) error: %v", err)
+		}
+
+		// e expired after tx3 began, which is a write it did not see
+		mustBegin(t, store, "tx3", "080")
+		if err := store.Expire([]kvstore.KeyValue{{Key: "e", ExpiresAt: "090"}}); err != nil {
+			t.Fatalf("Expire error: %v", err)
+		}
+		mustWrite(t, store, "tx3", "100", "e", "v3")
+		expectConflict(t, store, "tx3", "e")
+	})
+}
+
+func TestAbortTransaction(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustBegin(t, store, "tx1", "010")
+		rec, ok, err := store.Transaction("tx1")
+		if err != nil || !ok || rec.StartTimestamp != "010" {
+			t.Fatalf("Transaction(tx1) = %+v, %v, %v", rec, ok, err)
+		}
+		mustWrite(t, store, "tx1", "020", "k", "v1")
+		if err := store.Abort("tx1"); err != nil {
+			t.Fatalf("Abort error: %v", err)
+		}
+		expectStatus(t, store, "tx1", kvstore.TxnAborted)
+		if _, exists, _ := store.GetInTxn("k", "tx1", "999"); exists {
+			t.Error("expected the write to be discarded by Abort")
+		}
+	})
+}
+
+func TestTransactionRecordsCollected(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustBegin(t, store, "old", "010")
+		mustBegin(t, store, "new", "030")
+		if err := store.GC("020"); err != nil {
+			t.Fatalf("GC error: %v", err)
+		}
+		if _, ok, _ := store.Transaction("old"); ok {
+			t.Error("expected the record of an abandoned transaction to be collected")
+		}
+		if _, ok, _ := store.Transaction("new"); !ok {
+			t.Error("expected the record of a recent transaction to be kept")
+		}
+	})
+}
+
+// expectSerializationFailure checks that committing txID after reading reads
+// fails with a serialization failure on key and aborts the transaction
+func expectSerializationFailure(t *testing.T, store *kvstore.Store, txID string, reads []kvstore.KeyRange, key string) {
+	t.Helper()
+	err := store.CommitReads(txID, "100", reads)
+	var failure *kvstore.SerializationError
+	if !errors.As(err, &failure) || !errors.Is(err, kvstore.ErrSerialization) {
+		t.Fatalf("expected serialization failure committing %s, got %v", txID, err)
+	}
+	if failure.TxID != txID || failure.Key != key {
+		t.Errorf("expected failure of %s on %q, got %+v", txID, key, failure)
+	}
+	expectStatus(t, store, txID, kvstore.TxnAborted)
+}
+
+func TestSerializableWriteSkew(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "005", kvstore.KeyValue{Key: "x", Value: "1"}, kvstore.KeyValue{Key: "y", Value: "1"})
+		reads := []kvstore.KeyRange{kvstore.PointRange("x"), kvstore.PointRange("y")}
+
+		// Under snapshot isolation, both transactions read x and y and
+		// write the other key, and both commit
+		mustBegin(t, store, "si1", "010")
+		mustBegin(t, store, "si2", "011")
+		mustWrite(t, store, "si1", "020", "x", "0")
+		mustWrite(t, store, "si2", "021", "y", "0")
+		for _, txID := range []string{"si1", "si2"} {
+			if err := store.CommitReads(txID, "030", reads); err != nil {
+				t.Fatalf("CommitReads(%s) error: %v", txID, err)
+			}
+		}
+
+		// Under Serializable, the second one to commit fails
+		mustBeginAt(t, store, "sr1", "030", kvstore.Serializable)
+		mustBeginAt(t, store, "sr2", "031", kvstore.Serializable)
+		rec, ok, err := store.Transaction("sr1")
+		if err != nil || !ok || rec.Isolation != kvstore.Serializable {
+			t.Fatalf("Transaction(sr1) = %+v, %v, %v", rec, ok, err)
+		}
+		mustWrite(t, store, "sr1", "040", "x", "2")
+		mustWrite(t, store, "sr2", "041", "y", "2")
+		if err := store.CommitReads("sr1", "050", reads); err != nil {
+			t.Fatalf("CommitReads(sr1) error: %v", err)
+		}
+		expectSerializationFailure(t, store, "sr2", reads, "x")
+		if got, _ := store.Read("y", "999"); got != "0" {
+			t.Errorf("expected the write of sr2 to be aborted, got y = %q", got)
+		}
+	})
+}
+
+func TestSerializableRangeRead(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx0", "005", kvstore.KeyValue{Key: "acct/a", Value: "1"})
+		reads := []kvstore.KeyRange{{Start: "acct/", End: "acct0"}}
+
+		// A key inserted into a scanned range fails the scan
+		mustBeginAt(t, store, "tx1", "010", kvstore.Serializable)
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "acct/b", Value: "1"})
+		mustWrite(t, store, "tx1", "030", "total", "1")
+		expectSerializationFailure(t, store, "tx1", reads, "acct/b")
+
+		// Writes outside what was read do not
+		mustBeginAt(t, store, "tx3", "040", kvstore.Serializable)
+		mustCommit(t, store, "tx4", "050", kvstore.KeyValue{Key: "other", Value: "1"})
+		mustWrite(t, store, "tx3", "060", "total", "2")
+		if err := store.CommitReads("tx3", "070", reads); err != nil {
+			t.Fatalf("CommitReads(tx3) error: %v", err)
+		}
+		if got, _ := store.Read("total", "999"); got != "2" {
+			t.Errorf("expected total = 2, got %q", got)
+		}
+	})
+}
+
+// expectState checks that err refuses op on txID because its status is
+// status
+func expectState(t *testing.T, err error, txID, op string, status kvstore.TxnStatus) {
+	t.Helper()
+	var state *kvstore.TxnStateError
+	if !errors.As(err, &state) {
+		t.Fatalf("expected a state error for %s %s, got %v", op, txID, err)
+	}
+	if state.TxID != txID || state.Op != op || state.Status != status {
+		t.Errorf("expected %s %s refused as %v, got %+v", op, txID, status, state)
+	}
+	want := kvstore.ErrTxnCommitted
+	if status == kvstore.TxnAborted {
+		want = kvstore.ErrTxnAborted
+	}
+	if !errors.Is(err, want) {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestTransactionLifecycle(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustBegin(t, store, "tx1", "010")
+		rec, ok, err := store.Transaction("tx1")
+		if err != nil || !ok {
+			t.Fatalf("Transaction(tx1) = %+v, %v, %v", rec, ok, err)
+		}
+		want := kvstore.TxnRecord{TxID: "tx1", StartTimestamp: "010", Owner: "node1", Status: kvstore.TxnPending}
+		if rec != want {
+			t.Errorf("Transaction(tx1) = %+v, want %+v", rec, want)
+		}
+		if err := store.Begin("tx1", "020", kvstore.SnapshotIsolation, "node2"); !errors.Is(err, kvstore.ErrTxnExists) {
+			t.Errorf("expected ErrTxnExists beginning tx1 again, got %v", err)
+		}
+
+		// A committed transaction keeps its record and refuses anything more
+		mustWrite(t, store, "tx1", "020", "k", "v1")
+		if err := store.CommitReads("tx1", "030", nil); err != nil {
+			t.Fatalf("CommitReads(tx1) error: %v", err)
+		}
+		rec, _, _ = store.Transaction("tx1")
+		if rec.Status != kvstore.TxnCommitted || rec.CommitTimestamp != "030" || rec.Owner != "node1" {
+			t.Errorf("expected tx1 committed at 030, got %+v", rec)
+		}
+		expectState(t, store.WriteWithTimestamp("k", "v2", "tx1", "040"), "tx1", "write to", kvstore.TxnCommitted)
+		expectState(t, store.WriteBatchWithTimestamp([]kvstore.KeyValue{{Key: "k", Value: "v2"}}, "tx1", "040"), "tx1", "write to", kvstore.TxnCommitted)
+		expectState(t, store.DeleteWithTimestamp("k", "tx1", "040"), "tx1", "write to", kvstore.TxnCommitted)
+		expectState(t, store.Commit("tx1"), "tx1", "commit", kvstore.TxnCommitted)
+		expectState(t, store.Abort("tx1"), "tx1", "abort", kvstore.TxnCommitted)
+		if got, _ := store.Read("k", "999"); got != "v1" {
+			t.Errorf("expected k = v1, got %q", got)
+		}
+
+		// So does an aborted one
+		mustBegin(t, store, "tx2", "050")
+		mustWrite(t, store, "tx2", "060", "k", "v3")
+		if err := store.Abort("tx2"); err != nil {
+			t.Fatalf("Abort(tx2) error: %v", err)
+		}
+		expectStatus(t, store, "tx2", kvstore.TxnAborted)
+		expectState(t, store.WriteWithTimestamp("k", "v4", "tx2", "070"), "tx2", "write to", kvstore.TxnAborted)
+		expectState(t, store.Commit("tx2"), "tx2", "commit", kvstore.TxnAborted)
+		expectState(t, store.Abort("tx2"), "tx2", "abort", kvstore.TxnAborted)
+		if got, _ := store.Read("k", "999"); got != "v1" {
+			t.Errorf("expected k = v1, got %q", got)
+		}
+
+		if _, err := store.PendingTransaction("tx3", "commit"); !errors.Is(err, kvstore.ErrTxnNotFound) {
+			t.Errorf("expected ErrTxnNotFound for an unknown transaction, got %v", err)
+		}
+
+		// Writes without a record can neither commit nor abort
+		mustWrite(t, store, "tx4", "075", "k", "v5")
+		for op, err := range map[string]error{"Commit": store.Commit("tx4"), "Abort": store.Abort("tx4")} {
+			if !errors.Is(err, kvstore.ErrTxnNotFound) {
+				t.Errorf("expected ErrTxnNotFound from %s without a record, got %v", op, err)
+			}
+		}
+		if got, _ := store.Read("k", "999"); got != "v1" {
+			t.Errorf("expected k = v1, got %q", got)
+		}
+		_, err = store.PendingTransaction("tx2", "commit")
+		expectState(t, err, "tx2", "commit", kvstore.TxnAborted)
+		mustBegin(t, store, "tx3", "080")
+		if rec, err := store.PendingTransaction("tx3", "commit"); err != nil || rec.TxID != "tx3" {
+			t.Errorf("PendingTransaction(tx3) = %+v, %v", rec, err)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// internal/kvstore/txn.go
+package kvstore
+
+import "fmt"
+
+// TxnOpKind selects what a TxnOp does
+type TxnOpKind int
+
+const (
+	// TxnPut writes Value, expiring at ExpiresAt if set
+	TxnPut TxnOpKind = iota + 1
+	// TxnDelete writes a tombstone
+	TxnDelete
+	// TxnGet reads the key, seeing writes of earlier ops in the same branch
+	TxnGet
+)
+
+// TxnOp is one operation of a Txn branch
+type TxnOp struct {
+	Kind      TxnOpKind
+	Key       string
+	Value     string
+	ExpiresAt string
+}
+
+// Comparison is a Condition on one key
+type Comparison struct {
+	Key string
+	Condition
+}
+
+// TxnResult reports which branch of a Txn ran and the outcome of its ops
+type TxnResult struct {
+	Succeeded bool // every comparison held and the success branch ran
+	Results   []TxnOpResult
+}
+
+// TxnOpResult is the state of an op's key once the op ran: the version read
+// by a TxnGet or written by a TxnPut. Exists is false after a TxnDelete or
+// for a TxnGet of a key with no live version.
+type TxnOpResult struct {
+	Current Version
+	Exists  bool
+}
+
+// ValidateTxn checks that the ops of both branches are well-formed. A key may
+// be written at most once per branch, since all writes share one timestamp.
+func ValidateTxn(success, failure []TxnOp) error {
+	for _, ops := range [][]TxnOp{success, failure} {
+		written := make(map[string]bool)
+		for _, op := range ops {
+			switch op.Kind {
+			case TxnPut, TxnDelete:
+				if written[op.Key] {
+					return fmt.Errorf("key %q is written more than once", op.Key)
+				}
+				written[op.Key] = true
+			case TxnGet:
+			default:
+				return fmt.Errorf("unknown txn op kind %d", op.Kind)
+			}
+		}
+	}
+	return nil
+}
+
+// Txn evaluates compares against the newest committed versions, then runs
+// the success ops if all of them hold and the failure ops otherwise. The
+// writes of the branch are committed together as transaction txID at
+// timestamp, in a single engine transaction. If a key compared, read or
+// written already has a committed version at or after timestamp, Txn fails
+// with a *WriteTooOldError and writes nothing. Like CompareAndSwap, Txn is
+// atomic as long as calls are serialized, as they are by the Raft FSM.
+func (s *Store) Txn(compares []Comparison, success, failure []TxnOp, txID, timestamp string) (TxnResult, error) {
+	if err := ValidateTxn(success, failure); err != nil {
+		return TxnResult{}, err
+	}
+	if err := s.checkReadTimestamp(timestamp); err != nil {
+		return TxnResult{}, err
+	}
+
+	result := TxnResult{Succeeded: true}
+	for _, c := range compares {
+		current, exists, err := s.latest(c.Key, timestamp)
+		if err != nil {
+			return TxnResult{}, err
+		}
+		ok, err := c.holds(current, exists)
+		if err != nil {
+			return TxnResult{}, err
+		}
+		if !ok {
+			result.Succeeded = false
+			break
+		}
+	}
+	ops := success
+	if !result.Succeeded {
+		ops = failure
+	}
+
+	written := make(map[string]TxnOpResult)
+	var versions []Version
+	for _, op := range ops {
+		if _, ok := written[op.Key]; !ok {
+			current, exists, err := s.latest(op.Key, timestamp)
+			if err != nil {
+				return TxnResult{}, err
+			}
+			if op.Kind == TxnGet {
+				result.Results = append(result.Results, TxnOpResult{Current: current, Exists: exists})
+				continue
+			}
+		}
+		switch op.Kind {
+		case TxnPut:
+			v := Version{Key: op.Key, Value: op.Value, Timestamp: timestamp, TxID: txID, Committed: true, ExpiresAt: op.ExpiresAt}
+			written[op.Key] = TxnOpResult{Current: v, Exists: true}
+			versions = append(versions, v)
+		case TxnDelete:
+			written[op.Key] = TxnOpResult{}
+			versions = append(versions, Version{Key: op.Key, Timestamp: timestamp, TxID: txID, Committed: true, Tombstone: true})
+		}
+		result.Results = append(result.Results, written[op.Key])
+	}
+
+	if len(versions) > 0 {
+		if err := s.putCommitted(versions); err != nil {
+			return TxnResult{}, err
+		}
+	}
+	return result, nil
+}
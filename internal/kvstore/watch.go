@@ -0,0 +1,281 @@
+// internal/kvstore/watch.go
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrWatchLagging closes a watcher that fell too far behind the commits
+	ErrWatchLagging = errors.New("watcher fell too far behind")
+	// ErrWatchReset closes every watcher when the store is replaced by a
+	// snapshot, since the changes in between are not known
+	ErrWatchReset = errors.New("store was restored from a snapshot")
+	// ErrWatchClosed is returned by Next once the watcher is closed
+	ErrWatchClosed = errors.New("watcher closed")
+	// ErrWatchCompacted is returned by Watch and WatchSince when versions
+	// committed at or after the starting point may already have been garbage
+	// collected
+	ErrWatchCompacted = errors.New("changes since the requested start were garbage collected")
+)
+
+// watchBuffer is how many events a watcher may have pending before it is
+// closed with ErrWatchLagging. Commits never wait for watchers.
+const watchBuffer = 1024
+
+// WatchEvent is a committed version of a watched key, or a progress marker
+type WatchEvent struct {
+	Version Version
+	// Progress is set, instead of Version, on the marker queued by
+	// RequestProgress
+	Progress bool
+	// Sequence is the commit sequence of Version or, on a progress marker,
+	// one up to which every commit has been delivered
+	Sequence uint64
+}
+
+// Watcher delivers the versions committed to the keys in a range, in commit
+// order, after replaying those committed since a starting commit sequence.
+type Watcher struct {
+	store      *Store
+	start, end string
+
+	catchUp  []Version
+	caughtUp uint64 // commits up to this sequence are in catchUp or were not asked for
+
+	events chan WatchEvent
+	done   chan struct{} // closed once the watcher is removed from the store
+	err    error         // why, set before done is closed
+}
+
+// Watch starts watching the keys in [start, end); an empty end means no upper
+// bound. If from is not 0, the versions committed at or after commit sequence
+// from are replayed first, in commit order. That fails with ErrWatchCompacted
+// if garbage collection removed any of them.
+func (s *Store) Watch(start, end string, from uint64) (*Watcher, error) {
+	if from != 0 {
+		if err := s.checkWatchSeq(from); err != nil {
+			return nil, err
+		}
+	}
+	w := s.newWatcher(start, end)
+	if from == 0 {
+		return w, nil
+	}
+	if err := w.replay(from); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// WatchSince is Watch starting at timestamp rather than at a commit
+// sequence. It replays, in commit order, the versions committed at or after
+// the first commit sequence of a version of the keys whose timestamp is at or
+// after timestamp. Versions committed before commit sequences existed,
+// which have none, are replayed before them, oldest first. It fails with
+// ErrWatchCompacted if timestamp is older than the GC threshold.
+func (s *Store) WatchSince(start, end, timestamp string) (*Watcher, error) {
+	if threshold := s.GCThreshold(); timestamp < threshold {
+		return nil, fmt.Errorf("%w: %s is older than %s", ErrWatchCompacted, timestamp, threshold)
+	}
+	w := s.newWatcher(start, end)
+	var (
+		from   uint64
+		legacy []Version
+	)
+	err := s.engine.Since(start, end, timestamp, func(v Version) bool {
+		if v.CommitSeq == 0 {
+			legacy = append(legacy, v)
+		} else if from == 0 || v.CommitSeq < from {
+			from = v.CommitSeq
+		}
+		return true
+	})
+	if err == nil && from != 0 {
+		if err = s.checkWatchSeq(from); err == nil {
+			err = w.replay(from)
+		}
+	}
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	sort.SliceStable(legacy, func(i, j int) bool { return legacy[i].Timestamp < legacy[j].Timestamp })
+	w.catchUp = append(legacy, w.catchUp...)
+	return w, nil
+}
+
+// checkWatchSeq fails with ErrWatchCompacted if versions committed at or
+// after from may have been garbage collected
+func (s *Store) checkWatchSeq(from uint64) error {
+	s.mu.RLock()
+	gcSeq := s.gcSeq
+	s.mu.RUnlock()
+	if from <= gcSeq {
+		return fmt.Errorf("%w: %d is not after %d", ErrWatchCompacted, from, gcSeq)
+	}
+	return nil
+}
+
+// newWatcher registers a watcher of [start, end). It is registered before
+// its catch-up is read so no commit falls in between. Commits that land in
+// both are at or below the last sequence of the catch-up, which is read after
+// they were, and are skipped by Next.
+func (s *Store) newWatcher(start, end string) *Watcher {
+	w := &Watcher{
+		store:  s,
+		start:  start,
+		end:    end,
+		events: make(chan WatchEvent, watchBuffer),
+		done:   make(chan struct{}),
+	}
+	s.watchMu.Lock()
+	if s.watchers == nil {
+		s.watchers = make(map[*Watcher]struct{})
+	}
+	s.watchers[w] = struct{}{}
+	s.watchMu.Unlock()
+	return w
+}
+
+// replay reads the versions committed at or after sequence from into the
+// catch-up of w
+func (w *Watcher) replay(from uint64) error {
+	w.caughtUp = from - 1
+	return w.store.engine.Changes(w.start, w.end, from, func(v Version) bool {
+		w.catchUp = append(w.catchUp, v)
+		w.caughtUp = v.CommitSeq
+		return true
+	})
+}
+
+// Next returns the next event, waiting for one if necessary
+func (w *Watcher) Next(ctx context.Context) (WatchEvent, error) {
+	if len(w.catchUp) > 0 {
+		v := w.catchUp[0]
+		w.catchUp = w.catchUp[1:]
+		return WatchEvent{Version: v, Sequence: v.CommitSeq}, nil
+	}
+	for {
+		select {
+		case ev := <-w.events:
+			if ev.Progress {
+				ev.Sequence = max(ev.Sequence, w.caughtUp)
+			} else if ev.Sequence <= w.caughtUp {
+				continue
+			}
+			return ev, nil
+		case <-w.done:
+			return WatchEvent{}, w.err
+		case <-ctx.Done():
+			return WatchEvent{}, ctx.Err()
+		}
+	}
+}
+
+// RequestProgress queues a progress marker behind the commits delivered so
+// far, carrying the sequence of the last of them. It is skipped if the
+// watcher has no room for it.
+func (w *Watcher) RequestProgress() {
+	w.store.watchMu.Lock()
+	defer w.store.watchMu.Unlock()
+	if _, ok := w.store.watchers[w]; !ok {
+		return
+	}
+	select {
+	case w.events <- WatchEvent{Progress: true, Sequence: w.store.commitSeq}:
+	default:
+	}
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() {
+	w.store.watchMu.Lock()
+	defer w.store.watchMu.Unlock()
+	w.store.removeWatcher(w, ErrWatchClosed)
+}
+
+// CloseWatchers closes every watcher with err, for example when the node
+// shuts down. Watchers started later are not affected.
+func (s *Store) CloseWatchers(err error) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for w := range s.watchers {
+		s.removeWatcher(w, err)
+	}
+}
+
+// removeWatcher must be called with watchMu held
+func (s *Store) removeWatcher(w *Watcher, err error) {
+	if _, ok := s.watchers[w]; !ok {
+		return
+	}
+	delete(s.watchers, w)
+	w.err = err
+	close(w.done)
+}
+
+// commit commits the transaction of rec at the next commit sequence, storing
+// rec with its writes, and passes the versions it made visible to the
+// watchers of their keys
+func (s *Store) commit(rec TxnRecord) error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	seq := s.nextSeq()
+	versions, err := s.engine.Commit(rec, seq)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		s.commitSeq = seq
+		s.notify(versions)
+	}
+	return nil
+}
+
+// putCommitted stores versions as committed at the next commit sequence, in
+// one engine transaction, and passes them to the watchers of their keys
+func (s *Store) putCommitted(versions []Version) error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	seq := s.nextSeq()
+	if err := s.engine.PutCommitted(versions, seq); err != nil {
+		return err
+	}
+	s.commitSeq = seq
+	for i := range versions {
+		versions[i].Committed, versions[i].CommitSeq = true, seq
+	}
+	s.notify(versions)
+	return nil
+}
+
+// nextSeq returns the commit sequence of the next commit, see Advance. It
+// must be called with watchMu held.
+func (s *Store) nextSeq() uint64 {
+	if s.index != 0 {
+		return s.index
+	}
+	return s.commitSeq + 1
+}
+
+// notify passes newly committed versions to the watchers of their keys. It
+// must be called with watchMu held.
+func (s *Store) notify(versions []Version) {
+	for _, v := range versions {
+		for w := range s.watchers {
+			if v.Key < w.start || (w.end != "" && v.Key >= w.end) {
+				continue
+			}
+			select {
+			case w.events <- WatchEvent{Version: v, Sequence: v.CommitSeq}:
+			default:
+				s.removeWatcher(w, ErrWatchLagging)
+			}
+		}
+	}
+}
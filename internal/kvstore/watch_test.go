@@ -0,0 +1,235 @@
+package kvstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+)
+
+// next returns the next event of w, failing the test if none arrives
+func next(t *testing.T, w *kvstore.Watcher) kvstore.WatchEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ev, err := w.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	return ev
+}
+
+// change summarizes a version event as key=value@timestamp#sequence, or
+// key deleted@timestamp#sequence
+func change(ev kvstore.WatchEvent) string {
+	v := ev.Version
+	if v.Tombstone {
+		return fmt.Sprintf("%s deleted@%s#%d", v.Key, v.Timestamp, ev.Sequence)
+	}
+	return fmt.Sprintf("%s=%s@%s#%d", v.Key, v.Value, v.Timestamp, ev.Sequence)
+}
+
+func TestWatch(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "010", kvstore.KeyValue{Key: "a1", Value: "old"})
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "a2", Value: "v"}, kvstore.KeyValue{Key: "b", Value: "v"})
+
+		// Commit sequences 1 and 2; the watch replays from the second
+		w, err := store.Watch("a", "b", 2)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+		defer w.Close()
+
+		// A transaction that writes early and commits late is delivered in
+		// commit order; keys outside the range are skipped
+		mustBegin(t, store, "tx3", "030")
+		if err := store.WriteWithTimestamp("a3", "late", "tx3", "030"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		mustCommit(t, store, "tx4", "040", kvstore.KeyValue{Key: "a1", Value: "new"}, kvstore.KeyValue{Key: "c", Value: "v"})
+		if err := store.Commit("tx3"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		mustBegin(t, store, "tx5", "050")
+		if err := store.DeleteWithTimestamp("a2", "tx5", "050"); err != nil {
+			t.Fatalf("delete error: %v", err)
+		}
+		if err := store.Commit("tx5"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		if _, err := store.CompareAndSwap(kvstore.KeyValue{Key: "a4", Value: "cas"}, kvstore.Condition{Kind: kvstore.NotExists}, "tx6", "060"); err != nil {
+			t.Fatalf("CompareAndSwap error: %v", err)
+		}
+		w.RequestProgress()
+
+		for _, want := range []string{"a2=v@020#2", "a1=new@040#3", "a3=late@030#4", "a2 deleted@050#5", "a4=cas@060#6"} {
+			if got := change(next(t, w)); got != want {
+				t.Errorf("got change %s, want %s", got, want)
+			}
+		}
+		if ev := next(t, w); !ev.Progress || ev.Sequence != 6 {
+			t.Errorf("expected progress at 6, got %+v", ev)
+		}
+	})
+}
+
+func TestWatchResume(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		mustCommit(t, store, "tx1", "040", kvstore.KeyValue{Key: "k", Value: "v1", ExpiresAt: "060"})
+		w, err := store.Watch("", "", 0)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+		defer w.Close()
+
+		// Both commits below carry timestamps older than the last change
+		// delivered, which a resume by timestamp would skip
+		mustBegin(t, store, "tx2", "030")
+		if err := store.WriteWithTimestamp("late", "v", "tx2", "030"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		mustCommit(t, store, "tx3", "050", kvstore.KeyValue{Key: "j", Value: "v"})
+		last := next(t, w)
+		if got := change(last); got != "j=v@050#2" {
+			t.Fatalf("got change %s, want j=v@050#2", got)
+		}
+		w.Close()
+
+		if err := store.Commit("tx2"); err != nil {
+			t.Fatalf("commit error: %v", err)
+		}
+		if err := store.Expire([]kvstore.KeyValue{{Key: "k", ExpiresAt: "060"}}); err != nil {
+			t.Fatalf("Expire error: %v", err)
+		}
+
+		resumed, err := store.Watch("", "", last.Sequence+1)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+		defer resumed.Close()
+		for _, want := range []string{"late=v@030#3", "k deleted@060#4"} {
+			if got := change(next(t, resumed)); got != want {
+				t.Errorf("got change %s, want %s", got, want)
+			}
+		}
+
+		// Live commits follow the catch-up
+		mustCommit(t, store, "tx4", "070", kvstore.KeyValue{Key: "j", Value: "v2"})
+		resumed.RequestProgress()
+		if got := change(next(t, resumed)); got != "j=v2@070#5" {
+			t.Errorf("got change %s, want j=v2@070#5", got)
+		}
+		if ev := next(t, resumed); !ev.Progress || ev.Sequence != 5 {
+			t.Errorf("expected progress at 5, got %+v", ev)
+		}
+	})
+}
+
+func TestWatchSince(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		// Versions committed before commit sequences existed have none
+		legacy := []kvstore.Version{
+			{Key: "a", Value: "old", Timestamp: "010", TxID: "tx0", Committed: true},
+			{Key: "b", Value: "old", Timestamp: "030", TxID: "tx0", Committed: true},
+		}
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(legacy, nil, nil)); err != nil {
+			t.Fatalf("ReplaceAll error: %v", err)
+		}
+		mustCommit(t, store, "tx1", "050", kvstore.KeyValue{Key: "c", Value: "v1"})
+		mustCommit(t, store, "tx2", "020", kvstore.KeyValue{Key: "d", Value: "v2"})
+		mustCommit(t, store, "tx3", "060", kvstore.KeyValue{Key: "e", Value: "v3"})
+
+		// The replay starts at c, the first commit at or after 025, and
+		// includes d, committed later at an older timestamp
+		w, err := store.WatchSince("", "", "025")
+		if err != nil {
+			t.Fatalf("WatchSince error: %v", err)
+		}
+		defer w.Close()
+		for _, want := range []string{"b=old@030#0", "c=v1@050#1", "d=v2@020#2", "e=v3@060#3"} {
+			if got := change(next(t, w)); got != want {
+				t.Errorf("got change %s, want %s", got, want)
+			}
+		}
+		mustCommit(t, store, "tx4", "070", kvstore.KeyValue{Key: "f", Value: "v4"})
+		if got := change(next(t, w)); got != "f=v4@070#4" {
+			t.Errorf("got change %s, want f=v4@070#4", got)
+		}
+
+		// Nothing at or after the start is only followed live
+		later, err := store.WatchSince("", "", "080")
+		if err != nil {
+			t.Fatalf("WatchSince error: %v", err)
+		}
+		defer later.Close()
+		mustCommit(t, store, "tx5", "090", kvstore.KeyValue{Key: "g", Value: "v5"})
+		if got := change(next(t, later)); got != "g=v5@090#5" {
+			t.Errorf("got change %s, want g=v5@090#5", got)
+		}
+
+		if err := store.GC("040"); err != nil {
+			t.Fatalf("GC error: %v", err)
+		}
+		if _, err := store.WatchSince("", "", "030"); !errors.Is(err, kvstore.ErrWatchCompacted) {
+			t.Errorf("expected ErrWatchCompacted, got %v", err)
+		}
+	})
+}
+
+func TestWatchClosed(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, store *kvstore.Store) {
+		lagging, err := store.Watch("", "", 0)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+		reset, err := store.Watch("x", "y", 0)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+
+		// One commit with more versions than a watcher buffers
+		var pairs []kvstore.KeyValue
+		for i := 0; i < 2000; i++ {
+			pairs = append(pairs, kvstore.KeyValue{Key: fmt.Sprintf("k%04d", i), Value: "v"})
+		}
+		mustCommit(t, store, "tx1", "010", pairs...)
+		ctx := context.Background()
+		for {
+			if _, err = lagging.Next(ctx); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, kvstore.ErrWatchLagging) {
+			t.Errorf("expected ErrWatchLagging, got %v", err)
+		}
+
+		if err := store.ReplaceAll(kvstore.NewSnapshotSource(nil, nil, nil)); err != nil {
+			t.Fatalf("ReplaceAll error: %v", err)
+		}
+		if _, err := reset.Next(ctx); !errors.Is(err, kvstore.ErrWatchReset) {
+			t.Errorf("expected ErrWatchReset, got %v", err)
+		}
+
+		// GC removes k@010, committed at sequence 1
+		mustCommit(t, store, "tx2", "010", kvstore.KeyValue{Key: "k", Value: "v1"})
+		mustCommit(t, store, "tx3", "020", kvstore.KeyValue{Key: "k", Value: "v2"})
+		if err := store.GC("100"); err != nil {
+			t.Fatalf("GC error: %v", err)
+		}
+		if _, err := store.Watch("", "", 1); !errors.Is(err, kvstore.ErrWatchCompacted) {
+			t.Errorf("expected ErrWatchCompacted, got %v", err)
+		}
+		w, err := store.Watch("", "", 2)
+		if err != nil {
+			t.Fatalf("Watch error: %v", err)
+		}
+		defer w.Close()
+		if got := change(next(t, w)); got != "k=v2@020#2" {
+			t.Errorf("got change %s, want k=v2@020#2", got)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+// internal/raftstore/codec.go
+package raftstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// LogFormatVersion is the version of the protobuf log entry written by EncodeCommand.
+const LogFormatVersion = 1
+
+// logEntryMagic prefixes protobuf log entries. A gob stream starts with its
+// message length, whose first byte is either below 0x80 or at least 0xF8, so
+// this byte can never begin a legacy gob-encoded entry.
+const logEntryMagic byte = 0xA1
+
+var opToProto = map[Op]amberpb.LogOp{
+	OpWrite:      amberpb.LogOp_LOG_OP_WRITE,
+	OpWriteBatch: amberpb.LogOp_LOG_OP_WRITE_BATCH,
+	OpCommit:     amberpb.LogOp_LOG_OP_COMMIT,
+	OpAbort:      amberpb.LogOp_LOG_OP_ABORT,
+	OpDelete:     amberpb.LogOp_LOG_OP_DELETE,
+	OpGC:         amberpb.LogOp_LOG_OP_GC,
+	OpExpire:     amberpb.LogOp_LOG_OP_EXPIRE,
+	OpCAS:        amberpb.LogOp_LOG_OP_CAS,
+	OpTxn:        amberpb.LogOp_LOG_OP_TXN,
+
+	OpRegisterNode: amberpb.LogOp_LOG_OP_REGISTER_NODE,
+	OpBegin:        amberpb.LogOp_LOG_OP_BEGIN,
+}
+
+var conditionToProto = map[kvstore.ConditionKind]amberpb.LogEntry_Condition_Kind{
+	kvstore.ValueEquals:   amberpb.LogEntry_Condition_VALUE_EQUALS,
+	kvstore.VersionEquals: amberpb.LogEntry_Condition_VERSION_EQUALS,
+	kvstore.NotExists:     amberpb.LogEntry_Condition_NOT_EXISTS,
+	kvstore.Exists:        amberpb.LogEntry_Condition_EXISTS,
+}
+
+var txnOpToProto = map[kvstore.TxnOpKind]amberpb.LogEntry_TxnOp_Kind{
+	kvstore.TxnPut:    amberpb.LogEntry_TxnOp_PUT,
+	kvstore.TxnDelete: amberpb.LogEntry_TxnOp_DELETE,
+	kvstore.TxnGet:    amberpb.LogEntry_TxnOp_GET,
+}
+
+var isolationToProto = map[kvstore.Isolation]amberpb.LogEntry_Isolation{
+	kvstore.SnapshotIsolation: amberpb.LogEntry_SNAPSHOT_ISOLATION,
+	kvstore.Serializable:      amberpb.LogEntry_SERIALIZABLE,
+}
+
+var opFromProto = func() map[amberpb.LogOp]Op {
+	m := make(map[amberpb.LogOp]Op, len(opToProto))
+	for op, pb := range opToProto {
+		m[pb] = op
+	}
+	return m
+}()
+
+// EncodeCommand serializes cmd as a versioned protobuf log entry
+func EncodeCommand(cmd Command) ([]byte, error) {
+	op, ok := opToProto[cmd.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown command operation: %s", cmd.Op)
+	}
+	entry := &amberpb.LogEntry{
+		FormatVersion: LogFormatVersion,
+		Op:            op,
+		Key:           []byte(cmd.Key),
+		Value:         []byte(cmd.Value),
+		TxId:          cmd.TxID,
+		Timestamp:     cmd.Timestamp,
+		ExpiresAt:     cmd.ExpiresAt,
+	}
+	isolation, ok := isolationToProto[cmd.Isolation]
+	if !ok {
+		return nil, fmt.Errorf("unknown isolation %d", cmd.Isolation)
+	}
+	entry.Isolation = isolation
+	if cmd.Condition != nil {
+		c, err := encodeCondition(*cmd.Condition)
+		if err != nil {
+			return nil, err
+		}
+		entry.Condition = c
+	}
+	for _, cmp := range cmd.Compares {
+		c, err := encodeCondition(cmp.Condition)
+		if err != nil {
+			return nil, err
+		}
+		entry.Compares = append(entry.Compares, &amberpb.LogEntry_Compare{Key: []byte(cmp.Key), Condition: c})
+	}
+	var err error
+	if entry.Success, err = encodeTxnOps(cmd.Success); err != nil {
+		return nil, err
+	}
+	if entry.Failure, err = encodeTxnOps(cmd.Failure); err != nil {
+		return nil, err
+	}
+	for _, kv := range cmd.Batch {
+		entry.Batch = append(entry.Batch, &amberpb.LogEntry_Pair{Key: []byte(kv.Key), Value: []byte(kv.Value), ExpiresAt: kv.ExpiresAt})
+	}
+	for _, r := range cmd.ReadSet {
+		entry.ReadSet = append(entry.ReadSet, &amberpb.LogEntry_KeyRange{Start: []byte(r.Start), End: []byte(r.End)})
+	}
+	data, err := proto.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{logEntryMagic}, data...), nil
+}
+
+// DecodeCommand parses a log entry written by EncodeCommand, or a legacy
+// gob-encoded Command written before the protobuf format existed
+func DecodeCommand(data []byte) (Command, error) {
+	var cmd Command
+	if len(data) == 0 || data[0] != logEntryMagic {
+		err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cmd)
+		return cmd, err
+	}
+
+	var entry amberpb.LogEntry
+	if err := proto.Unmarshal(data[1:], &entry); err != nil {
+		return cmd, err
+	}
+	if entry.FormatVersion > LogFormatVersion {
+		return cmd, fmt.Errorf("unsupported log format version %d (max %d)", entry.FormatVersion, LogFormatVersion)
+	}
+	op, ok := opFromProto[entry.Op]
+	if !ok {
+		return cmd, fmt.Errorf("unknown log operation: %v", entry.Op)
+	}
+	cmd = Command{
+		Op:        op,
+		Key:       string(entry.Key),
+		Value:     string(entry.Value),
+		TxID:      entry.TxId,
+		Timestamp: entry.Timestamp,
+		ExpiresAt: entry.ExpiresAt,
+	}
+	if entry.Condition != nil {
+		c, err := decodeCondition(entry.Condition)
+		if err != nil {
+			return cmd, err
+		}
+		cmd.Condition = &c
+	}
+	for _, cmp := range entry.Compares {
+		c, err := decodeCondition(cmp.Condition)
+		if err != nil {
+			return cmd, err
+		}
+		cmd.Compares = append(cmd.Compares, kvstore.Comparison{Key: string(cmp.Key), Condition: c})
+	}
+	var err error
+	if cmd.Success, err = decodeTxnOps(entry.Success); err != nil {
+		return cmd, err
+	}
+	if cmd.Failure, err = decodeTxnOps(entry.Failure); err != nil {
+		return cmd, err
+	}
+	for _, p := range entry.Batch {
+		cmd.Batch = append(cmd.Batch, kvstore.KeyValue{Key: string(p.Key), Value: string(p.Value), ExpiresAt: p.ExpiresAt})
+	}
+	for _, r := range entry.ReadSet {
+		cmd.ReadSet = append(cmd.ReadSet, kvstore.KeyRange{Start: string(r.Start), End: string(r.End)})
+	}
+	isolation, ok := decodeIsolation(entry.Isolation)
+	if !ok {
+		return cmd, fmt.Errorf("unknown isolation: %v", entry.Isolation)
+	}
+	cmd.Isolation = isolation
+	return cmd, nil
+}
+
+func decodeIsolation(i amberpb.LogEntry_Isolation) (kvstore.Isolation, bool) {
+	for isolation, pb := range isolationToProto {
+		if pb == i {
+			return isolation, true
+		}
+	}
+	return 0, false
+}
+
+func encodeCondition(c kvstore.Condition) (*amberpb.LogEntry_Condition, error) {
+	kind, ok := conditionToProto[c.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown condition kind %d", c.Kind)
+	}
+	return &amberpb.LogEntry_Condition{Kind: kind, Value: []byte(c.Value), Version: c.Version}, nil
+}
+
+func decodeCondition(c *amberpb.LogEntry_Condition) (kvstore.Condition, error) {
+	for kind, pb := range conditionToProto {
+		if pb == c.GetKind() {
+			return kvstore.Condition{Kind: kind, Value: string(c.Value), Version: c.Version}, nil
+		}
+	}
+	return kvstore.Condition{}, fmt.Errorf("unknown condition kind: %v", c.GetKind())
+}
+
+func encodeTxnOps(ops []kvstore.TxnOp) ([]*amberpb.LogEntry_TxnOp, error) {
+	var out []*amberpb.LogEntry_TxnOp
+	for _, op := range ops {
+		kind, ok := txnOpToProto[op.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown txn op kind %d", op.Kind)
+		}
+		out = append(out, &amberpb.LogEntry_TxnOp{Kind: kind, Key: []byte(op.Key), Value: []byte(op.Value), ExpiresAt: op.ExpiresAt})
+	}
+	return out, nil
+}
+
+func decodeTxnOps(ops []*amberpb.LogEntry_TxnOp) ([]kvstore.TxnOp, error) {
+	var out []kvstore.TxnOp
+	for _, op := range ops {
+		var kind kvstore.TxnOpKind
+		for k, pb := range txnOpToProto {
+			if pb == op.Kind {
+				kind = k
+			}
+		}
+		if kind == 0 {
+			return nil, fmt.Errorf("unknown txn op kind: %v", op.Kind)
+		}
+		out = append(out, kvstore.TxnOp{Kind: kind, Key: string(op.Key), Value: string(op.Value), ExpiresAt: op.ExpiresAt})
+	}
+	return out, nil
+}
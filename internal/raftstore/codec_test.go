@@ -0,0 +1,100 @@
+package raftstore_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"github.com/hashicorp/raft"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	cmds := []raftstore.Command{
+		{Op: raftstore.OpWrite, Key: "k", Value: "v", TxID: "tx1", Timestamp: "001"},
+		{Op: raftstore.OpWriteBatch, TxID: "tx1", Timestamp: "002", Batch: []kvstore.KeyValue{{Key: "a", Value: "1"}}},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+		{Op: raftstore.OpAbort, TxID: "tx2"},
+		{Op: raftstore.OpDelete, Key: "k", TxID: "tx3", Timestamp: "003"},
+		{Op: raftstore.OpGC, Timestamp: "004"},
+		{Op: raftstore.OpWrite, Key: "\xff\x00", Value: "\xc3\x28", TxID: "tx4", Timestamp: "005"},
+		{Op: raftstore.OpWrite, Key: "s", Value: "v", TxID: "tx5", Timestamp: "006", ExpiresAt: "009"},
+		{Op: raftstore.OpExpire, Batch: []kvstore.KeyValue{{Key: "s", ExpiresAt: "009"}}},
+		{Op: raftstore.OpCAS, Key: "k", Value: "v2", TxID: "tx6", Timestamp: "007",
+			Condition: &kvstore.Condition{Kind: kvstore.ValueEquals, Value: "v"}},
+		{Op: raftstore.OpCAS, Key: "n", TxID: "tx7", Timestamp: "008", Condition: &kvstore.Condition{Kind: kvstore.NotExists}},
+		{Op: raftstore.OpTxn, TxID: "tx8", Timestamp: "009",
+			Compares: []kvstore.Comparison{
+				{Key: "k", Condition: kvstore.Condition{Kind: kvstore.VersionEquals, Version: "007"}},
+				{Key: "n", Condition: kvstore.Condition{Kind: kvstore.Exists}},
+			},
+			Success: []kvstore.TxnOp{{Kind: kvstore.TxnPut, Key: "k", Value: "v3", ExpiresAt: "020"}, {Kind: kvstore.TxnDelete, Key: "n"}},
+			Failure: []kvstore.TxnOp{{Kind: kvstore.TxnGet, Key: "k"}}},
+		{Op: raftstore.OpRegisterNode, Key: "node4", Value: "node4:50054"},
+		{Op: raftstore.OpBegin, TxID: "tx9", Timestamp: "010"},
+		{Op: raftstore.OpBegin, Key: "node2", TxID: "tx10", Timestamp: "011", Isolation: kvstore.Serializable},
+		{Op: raftstore.OpCommit, TxID: "tx10", Timestamp: "012", ReadSet: []kvstore.KeyRange{kvstore.PointRange("k"), {Start: "a/", End: ""}}},
+	}
+	for _, cmd := range cmds {
+		data, err := raftstore.EncodeCommand(cmd)
+		if err != nil {
+			t.Fatalf("EncodeCommand(%s) error: %v", cmd.Op, err)
+		}
+		got, err := raftstore.DecodeCommand(data)
+		if err != nil {
+			t.Fatalf("DecodeCommand(%s) error: %v", cmd.Op, err)
+		}
+		if !reflect.DeepEqual(got, cmd) {
+			t.Errorf("round trip mismatch: got %+v, want %+v", got, cmd)
+		}
+	}
+}
+
+func TestEncodeUnknownOp(t *testing.T) {
+	if _, err := raftstore.EncodeCommand(raftstore.Command{Op: "BOGUS"}); err == nil {
+		t.Fatal("expected error for unknown op")
+	}
+}
+
+func TestApplyLegacyGobEntry(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+
+	// Entries written before the protobuf format were gob-encoded Commands.
+	// They carry no BEGIN, so the transaction is begun in the current format.
+	if resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "001"}); resp != nil {
+		t.Fatalf("BEGIN error: %v", resp)
+	}
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpWrite, Key: "k", Value: "old", TxID: "tx1", Timestamp: "001"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(cmd); err != nil {
+			t.Fatalf("gob encode error: %v", err)
+		}
+		if resp := fsm.Apply(&raft.Log{Data: buf.Bytes()}); resp != nil {
+			t.Fatalf("Apply legacy %s error: %v", cmd.Op, resp)
+		}
+	}
+	if val, _ := store.Read("k", "999"); val != "old" {
+		t.Errorf("expected old, got %q", val)
+	}
+}
+
+func TestDecodeFutureVersion(t *testing.T) {
+	data, err := proto.Marshal(&amberpb.LogEntry{
+		FormatVersion: raftstore.LogFormatVersion + 1,
+		Op:            amberpb.LogOp_LOG_OP_COMMIT,
+	})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if _, err := raftstore.DecodeCommand(append([]byte{0xA1}, data...)); err == nil {
+		t.Fatal("expected error for newer log format version")
+	}
+}
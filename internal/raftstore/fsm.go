@@ -2,67 +2,311 @@
 package raftstore
 
 import (
-	"bytes"
+	"bufio"
 	"encoding/gob"
 	"fmt"
 	"io"
 
+	"github.com/dishankoza/amberdb/internal/hlc"
 	"github.com/dishankoza/amberdb/internal/kvstore"
 	"github.com/hashicorp/raft"
 )
 
 type FSM struct {
 	store *kvstore.Store
+	clock *hlc.Clock
 }
 
 func NewFSM(store *kvstore.Store) *FSM {
-	return &FSM{store: store}
+	return &FSM{store: store, clock: hlc.NewClock()}
 }
 
+// Clock returns the HLC of the node. The FSM moves it past the timestamp of
+// every entry it applies and every version it restores, so that a node
+// elected after a leader with a faster clock never reads or begins behind
+// the data it replicated.
+func (f *FSM) Clock() *hlc.Clock {
+	return f.clock
+}
+
+// observe moves clock past ts. Timestamps that are not HLC timestamps, such
+// as the ones clients set themselves, are ignored.
+func observe(clock *hlc.Clock, ts string) {
+	if ts != "" {
+		clock.Update(ts)
+	}
+}
+
+// Op identifies the operation of a Command
+type Op string
+
+const (
+	OpWrite      Op = "WRITE"
+	OpWriteBatch Op = "WRITE_BATCH"
+	OpCommit     Op = "COMMIT"
+	OpAbort      Op = "ABORT"
+	OpDelete     Op = "DELETE"
+	OpGC         Op = "GC"     // Timestamp is the new GC threshold
+	OpExpire     Op = "EXPIRE" // Batch holds keys with the expiry to make permanent
+	OpCAS        Op = "CAS"    // responds with a kvstore.CASResult
+	OpTxn        Op = "TXN"    // responds with a kvstore.TxnResult
+
+	OpRegisterNode Op = "REGISTER_NODE" // Value is the gRPC address of node Key
+	OpBegin        Op = "BEGIN"         // Timestamp is the start timestamp of TxID, begun at Isolation by node Key
+)
+
 // Command represents a Raft log entry
 type Command struct {
-	Op        string // "WRITE", "COMMIT", or "ABORT"
+	Op        Op
 	Key       string
 	Value     string
 	TxID      string
-	Timestamp string // HLC or system timestamp for versioning
+	Timestamp string             // HLC or system timestamp for versioning
+	Batch     []kvstore.KeyValue // pairs written by WRITE_BATCH
+	ExpiresAt string             // HLC timestamp at which a WRITE or CAS expires, if set
+	Condition *kvstore.Condition // precondition of a CAS
+	Compares  []kvstore.Comparison
+	Success   []kvstore.TxnOp    // TXN ops run if every comparison holds
+	Failure   []kvstore.TxnOp    // TXN ops run otherwise
+	ReadSet   []kvstore.KeyRange // ranges read by a SERIALIZABLE transaction, on COMMIT
+	Isolation kvstore.Isolation  // of the transaction started by BEGIN
 }
 
 func (f *FSM) Apply(log *raft.Log) interface{} {
-	var cmd Command
-	decoder := gob.NewDecoder(bytes.NewReader(log.Data))
-	if err := decoder.Decode(&cmd); err != nil {
+	cmd, err := DecodeCommand(log.Data)
+	if err != nil {
 		return fmt.Errorf("failed to decode command: %w", err)
 	}
+	// The entry's index becomes the commit sequence of whatever it commits
+	f.store.Advance(log.Index)
+	observe(f.clock, cmd.Timestamp)
 	// Dispatch based on operation
 	switch cmd.Op {
-	case "WRITE":
+	case OpWrite:
 		// Use timestamp-aware write
-		return f.store.WriteWithTimestamp(cmd.Key, cmd.Value, cmd.TxID, cmd.Timestamp)
-	case "COMMIT":
-		return f.store.Commit(cmd.TxID)
-	case "ABORT":
+		kv := kvstore.KeyValue{Key: cmd.Key, Value: cmd.Value, ExpiresAt: cmd.ExpiresAt}
+		return f.store.WriteBatchWithTimestamp([]kvstore.KeyValue{kv}, cmd.TxID, cmd.Timestamp)
+	case OpWriteBatch:
+		return f.store.WriteBatchWithTimestamp(cmd.Batch, cmd.TxID, cmd.Timestamp)
+	case OpDelete:
+		return f.store.DeleteWithTimestamp(cmd.Key, cmd.TxID, cmd.Timestamp)
+	case OpCAS:
+		if cmd.Condition == nil {
+			return fmt.Errorf("CAS without condition")
+		}
+		kv := kvstore.KeyValue{Key: cmd.Key, Value: cmd.Value, ExpiresAt: cmd.ExpiresAt}
+		result, err := f.store.CompareAndSwap(kv, *cmd.Condition, cmd.TxID, cmd.Timestamp)
+		if err != nil {
+			return err
+		}
+		return result
+	case OpTxn:
+		result, err := f.store.Txn(cmd.Compares, cmd.Success, cmd.Failure, cmd.TxID, cmd.Timestamp)
+		if err != nil {
+			return err
+		}
+		return result
+	case OpGC:
+		return f.store.GC(cmd.Timestamp)
+	case OpExpire:
+		return f.store.Expire(cmd.Batch)
+	case OpBegin:
+		return f.store.Begin(cmd.TxID, cmd.Timestamp, cmd.Isolation, cmd.Key)
+	case OpCommit:
+		return f.store.CommitReads(cmd.TxID, cmd.Timestamp, cmd.ReadSet)
+	case OpAbort:
 		return f.store.Abort(cmd.TxID)
+	case OpRegisterNode:
+		return f.store.RegisterNode(cmd.Key, cmd.Value)
 	default:
 		return fmt.Errorf("unknown command operation: %s", cmd.Op)
 	}
 }
 
+// snapshotMagic starts snapshots written as a header followed by batches of
+// versions. Like logEntryMagic, it can never begin a gob stream, which is
+// how older snapshots were written.
+const snapshotMagic byte = 0xA5
+
+// snapshotBatch is the number of versions encoded per batch
+const snapshotBatch = 1024
+
+// snapshotHeader precedes the batches of versions of a snapshot
+type snapshotHeader struct {
+	Meta map[string]string // engine metadata such as the GC threshold
+}
+
+// Snapshot captures every version in the store, including uncommitted writes
+// of in-flight transactions, the transaction records, so that a restored node
+// can still commit or abort them, and the GC threshold. It only opens a point-in-time view of
+// the engine; the versions are read from it by Persist, off the FSM goroutine.
 func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
-	// Not implemented for now
-	return &noopSnapshot{}, nil
+	view, err := f.store.Snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store snapshot: %w", err)
+	}
+	return &fsmSnapshot{view: view}, nil
 }
 
+// Restore replaces the local store with the contents of a snapshot. The
+// versions are installed a batch at a time, as Persist wrote them.
+// Snapshots written before Persist existed are empty: the data lived only in
+// the engine file, so the local store is left as it is.
 func (f *FSM) Restore(snapshot io.ReadCloser) error {
-	// No-op for now
-	return nil
+	defer snapshot.Close()
+
+	r := bufio.NewReader(snapshot)
+	magic, err := r.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if magic[0] == snapshotMagic {
+		r.ReadByte()
+		dec := gob.NewDecoder(r)
+		var header snapshotHeader
+		if err := dec.Decode(&header); err != nil {
+			return fmt.Errorf("failed to decode snapshot header: %w", err)
+		}
+		// The versions and the GC threshold they were collected at are
+		// restored together, so a failure cannot leave one without the other
+		return f.store.ReplaceAll(&snapshotDecoder{dec: dec, meta: header.Meta, clock: f.clock})
+	}
+
+	// Snapshots written before batching hold every version in one value
+	dec := gob.NewDecoder(r)
+	var versions []kvstore.Version
+	if err := dec.Decode(&versions); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	// Snapshots taken before GC existed end after the versions
+	var gcThreshold string
+	if err := dec.Decode(&gcThreshold); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode snapshot GC threshold: %w", err)
+	}
+	for _, v := range versions {
+		observe(f.clock, v.Timestamp)
+	}
+	var meta map[string]string
+	if gcThreshold != "" {
+		meta = map[string]string{kvstore.GCThresholdMeta: gcThreshold}
+	}
+	return f.store.ReplaceAll(kvstore.NewSnapshotSource(versions, nil, meta))
+}
+
+// snapshotDecoder reads the batches written by Persist after the header
+type snapshotDecoder struct {
+	dec   *gob.Decoder
+	meta  map[string]string
+	clock *hlc.Clock // moved past the timestamp of every version read
+	ended bool       // the stream ended before the transaction records
 }
 
-// noopSnapshot implements raft.FSMSnapshot but does nothing
-type noopSnapshot struct{}
+func (d *snapshotDecoder) NextVersions() ([]kvstore.Version, error) {
+	var batch []kvstore.Version
+	if err := d.dec.Decode(&batch); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	for _, v := range batch {
+		observe(d.clock, v.Timestamp)
+	}
+	return batch, nil
+}
 
-func (n *noopSnapshot) Persist(sink raft.SnapshotSink) error {
+func (d *snapshotDecoder) NextTxns() ([]kvstore.TxnRecord, error) {
+	if d.ended {
+		return nil, nil
+	}
+	var batch []kvstore.TxnRecord
+	err := d.dec.Decode(&batch)
+	if err == io.EOF {
+		// Snapshots taken before transaction records existed end here
+		d.ended = true
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot transactions: %w", err)
+	}
+	return batch, nil
+}
+
+func (d *snapshotDecoder) Meta() (map[string]string, error) {
+	return d.meta, nil
+}
+
+// fsmSnapshot implements raft.FSMSnapshot over a point-in-time view of the store
+type fsmSnapshot struct {
+	view kvstore.EngineSnapshot
+}
+
+// Persist writes snapshotMagic, the header, the versions and then the
+// transaction records, each in batches of snapshotBatch ending with an empty
+// batch.
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := s.persist(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
 	return sink.Close()
 }
 
-func (n *noopSnapshot) Release() {}
+func (s *fsmSnapshot) persist(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if err := bw.WriteByte(snapshotMagic); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	enc := gob.NewEncoder(bw)
+	if err := enc.Encode(snapshotHeader{Meta: s.view.Meta()}); err != nil {
+		return fmt.Errorf("failed to encode snapshot header: %w", err)
+	}
+	batch := make([]kvstore.Version, 0, snapshotBatch)
+	err := s.view.Versions(func(v kvstore.Version) error {
+		batch = append(batch, v)
+		if len(batch) < snapshotBatch {
+			return nil
+		}
+		err := enc.Encode(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := enc.Encode(batch); err != nil {
+			return fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+	}
+	if err := enc.Encode([]kvstore.Version{}); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	txns := make([]kvstore.TxnRecord, 0, snapshotBatch)
+	err = s.view.Txns(func(rec kvstore.TxnRecord) error {
+		txns = append(txns, rec)
+		if len(txns) < snapshotBatch {
+			return nil
+		}
+		err := enc.Encode(txns)
+		txns = txns[:0]
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot transactions: %w", err)
+	}
+	if len(txns) > 0 {
+		if err := enc.Encode(txns); err != nil {
+			return fmt.Errorf("failed to encode snapshot transactions: %w", err)
+		}
+	}
+	if err := enc.Encode([]kvstore.TxnRecord{}); err != nil {
+		return fmt.Errorf("failed to encode snapshot transactions: %w", err)
+	}
+	return bw.Flush()
+}
+
+func (s *fsmSnapshot) Release() {
+	s.view.Close()
+}
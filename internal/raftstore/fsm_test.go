@@ -0,0 +1,700 @@
+package raftstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	"github.com/hashicorp/raft"
+)
+
+// memorySink is an in-memory raft.SnapshotSink
+type memorySink struct {
+	bytes.Buffer
+	cancelled bool
+}
+
+func (s *memorySink) ID() string    { return "test" }
+func (s *memorySink) Close() error  { return nil }
+func (s *memorySink) Cancel() error { s.cancelled = true; return nil }
+
+func newTestStore(t *testing.T, name string) *kvstore.Store {
+	t.Helper()
+	store, err := kvstore.NewStore(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("NewStore error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// apply encodes cmd as a log entry and applies it to fsm
+func apply(t *testing.T, fsm *raftstore.FSM, cmd raftstore.Command) interface{} {
+	t.Helper()
+	data, err := raftstore.EncodeCommand(cmd)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	return fsm.Apply(&raft.Log{Data: data})
+}
+
+func TestApplyWriteBatch(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+	if resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "001"}); resp != nil {
+		t.Fatalf("BEGIN error: %v", resp)
+	}
+	resp := apply(t, fsm, raftstore.Command{
+		Op:        raftstore.OpWriteBatch,
+		TxID:      "tx1",
+		Timestamp: "001",
+		Batch: []kvstore.KeyValue{
+			{Key: "a", Value: "1"},
+			{Key: "b", Value: "2"},
+			{Key: "a", Value: "3"},
+		},
+	})
+	if resp != nil {
+		t.Fatalf("WRITE_BATCH error: %v", resp)
+	}
+	if val, _ := store.Read("a", "999"); val != "" {
+		t.Errorf("expected uncommitted batch to be invisible, got %q", val)
+	}
+	if resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpCommit, TxID: "tx1"}); resp != nil {
+		t.Fatalf("COMMIT error: %v", resp)
+	}
+	if val, _ := store.Read("a", "999"); val != "3" {
+		t.Errorf("expected last value of duplicate key, got %q", val)
+	}
+	if val, _ := store.Read("b", "999"); val != "2" {
+		t.Errorf("expected 2, got %q", val)
+	}
+}
+
+func TestApplyDelete(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "001"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "001"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+		{Op: raftstore.OpBegin, TxID: "tx2", Timestamp: "002"},
+		{Op: raftstore.OpDelete, Key: "k", TxID: "tx2", Timestamp: "002"},
+		{Op: raftstore.OpCommit, TxID: "tx2"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+	if val, _ := store.Read("k", "999"); val != "" {
+		t.Errorf("expected deleted key to read empty, got %q", val)
+	}
+	if val, _ := store.Read("k", "001"); val != "v1" {
+		t.Errorf("expected v1 before the delete, got %q", val)
+	}
+}
+
+func TestApplyExpire(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "s", Value: "v1", TxID: "tx1", Timestamp: "010", ExpiresAt: "050"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+	if val, _ := store.Read("s", "050"); val != "" {
+		t.Errorf("expected key to read as deleted at its expiry, got %q", val)
+	}
+
+	expired, err := store.Expired("060", 10)
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("expected one expired key, got %+v, %v", expired, err)
+	}
+	cmd := raftstore.Command{Op: raftstore.OpExpire, Batch: []kvstore.KeyValue{{Key: "s", ExpiresAt: "050"}}}
+	if resp := apply(t, fsm, cmd); resp != nil {
+		t.Fatalf("EXPIRE error: %v", resp)
+	}
+	if expired, _ := store.Expired("060", 10); len(expired) != 0 {
+		t.Errorf("expected EXPIRE to leave nothing to expire, got %+v", expired)
+	}
+	if val, _ := store.Read("s", "040"); val != "v1" {
+		t.Errorf("expected v1 before expiry, got %q", val)
+	}
+}
+
+func TestApplyCompareAndSwap(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+	cas := func(value, ts string, cond kvstore.Condition) kvstore.CASResult {
+		t.Helper()
+		resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpCAS, Key: "k", Value: value, TxID: "tx" + ts, Timestamp: ts, Condition: &cond})
+		result, ok := resp.(kvstore.CASResult)
+		if !ok {
+			t.Fatalf("CAS at %s: unexpected response %v", ts, resp)
+		}
+		return result
+	}
+
+	if r := cas("v1", "010", kvstore.Condition{Kind: kvstore.NotExists}); !r.Succeeded {
+		t.Fatalf("expected create to succeed, got %+v", r)
+	}
+	r := cas("v2", "020", kvstore.Condition{Kind: kvstore.NotExists})
+	if r.Succeeded || !r.Exists || r.Current.Value != "v1" || r.Current.Timestamp != "010" {
+		t.Fatalf("expected second create to fail with v1, got %+v", r)
+	}
+	if r := cas("v2", "030", kvstore.Condition{Kind: kvstore.VersionEquals, Version: "010"}); !r.Succeeded {
+		t.Fatalf("expected version match to succeed, got %+v", r)
+	}
+	if val, _ := store.Read("k", "999"); val != "v2" {
+		t.Errorf("expected CAS to commit v2, got %q", val)
+	}
+	if resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpCAS, Key: "k", TxID: "tx4", Timestamp: "040"}); resp == nil {
+		t.Error("expected CAS without condition to fail")
+	}
+
+	// Two creates proposed out of timestamp order: the later entry must not
+	// succeed by looking at the key as of its older timestamp
+	notExists := kvstore.Condition{Kind: kvstore.NotExists}
+	first := raftstore.Command{Op: raftstore.OpCAS, Key: "n", Value: "first", TxID: "tx5", Timestamp: "101", Condition: &notExists}
+	if r, ok := apply(t, fsm, first).(kvstore.CASResult); !ok || !r.Succeeded {
+		t.Fatalf("expected create at 101 to succeed, got %+v", r)
+	}
+	second := raftstore.Command{Op: raftstore.OpCAS, Key: "n", Value: "second", TxID: "tx6", Timestamp: "100", Condition: &notExists}
+	if err, ok := apply(t, fsm, second).(error); !ok || !errors.Is(err, kvstore.ErrWriteTooOld) {
+		t.Errorf("expected create at 100 to fail with ErrWriteTooOld, got %v", err)
+	}
+	if val, _ := store.Read("n", "999"); val != "first" {
+		t.Errorf("expected the create at 101 to stand, got %q", val)
+	}
+}
+
+func TestApplyTxn(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	fsm := raftstore.NewFSM(store)
+	txn := func(ts string, compares ...kvstore.Comparison) kvstore.TxnResult {
+		t.Helper()
+		resp := apply(t, fsm, raftstore.Command{
+			Op:        raftstore.OpTxn,
+			TxID:      "tx" + ts,
+			Timestamp: ts,
+			Compares:  compares,
+			Success:   []kvstore.TxnOp{{Kind: kvstore.TxnPut, Key: "a", Value: ts}, {Kind: kvstore.TxnPut, Key: "b", Value: ts}},
+			Failure:   []kvstore.TxnOp{{Kind: kvstore.TxnGet, Key: "a"}},
+		})
+		result, ok := resp.(kvstore.TxnResult)
+		if !ok {
+			t.Fatalf("TXN at %s: unexpected response %v", ts, resp)
+		}
+		return result
+	}
+
+	absent := kvstore.Comparison{Key: "a", Condition: kvstore.Condition{Kind: kvstore.NotExists}}
+	if r := txn("010", absent); !r.Succeeded || len(r.Results) != 2 {
+		t.Fatalf("expected success branch, got %+v", r)
+	}
+	r := txn("020", absent)
+	if r.Succeeded || len(r.Results) != 1 || r.Results[0].Current.Value != "010" {
+		t.Fatalf("expected failure branch to read a, got %+v", r)
+	}
+	if val, _ := store.Read("b", "999"); val != "010" {
+		t.Errorf("expected b from the first txn, got %q", val)
+	}
+}
+
+func TestClockFollowsReplicatedWrites(t *testing.T) {
+	// The old leader's clock runs an hour ahead of its followers'
+	ahead, err := hlc.Add(hlc.FromTime(time.Now()), time.Hour)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	leader := raftstore.NewFSM(newTestStore(t, "leader.db"))
+	followerStore := newTestStore(t, "follower.db")
+	follower := raftstore.NewFSM(followerStore)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: ahead},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v", TxID: "tx1", Timestamp: ahead},
+		{Op: raftstore.OpCommit, TxID: "tx1", Timestamp: ahead},
+	} {
+		for _, fsm := range []*raftstore.FSM{leader, follower} {
+			if resp := apply(t, fsm, cmd); resp != nil {
+				t.Fatalf("%s error: %v", cmd.Op, resp)
+			}
+		}
+	}
+
+	// Once the follower leads, reads at its clock see the write and new
+	// transactions start after it
+	now := follower.Clock().Now()
+	if now <= ahead {
+		t.Fatalf("expected the follower clock past %s, got %s", ahead, now)
+	}
+	if val, _ := followerStore.Read("k", now); val != "v" {
+		t.Errorf("expected v at the follower clock, got %q", val)
+	}
+
+	// So does a node that catches up from a snapshot
+	snap, err := follower.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	snap.Release()
+	restored := raftstore.NewFSM(newTestStore(t, "restored.db"))
+	if err := restored.Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if now := restored.Clock().Now(); now <= ahead {
+		t.Errorf("expected the restored clock past %s, got %s", ahead, now)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	// One committed version, one pending write of an open transaction
+	if err := src.Begin("tx1", "001", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := src.WriteWithTimestamp("k1", "v1", "tx1", "001"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := src.Commit("tx1"); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if err := src.Begin("tx2", "002", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := src.WriteWithTimestamp("k1", "v2", "tx2", "002"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	snap, err := raftstore.NewFSM(src).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	snap.Release()
+
+	// Restore over a store that already holds unrelated data
+	dst := newTestStore(t, "dst.db")
+	if err := dst.Begin("tx0", "000", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := dst.WriteWithTimestamp("stale", "x", "tx0", "000"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := dst.Commit("tx0"); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+
+	if val, _ := dst.Read("stale", "999"); val != "" {
+		t.Errorf("expected stale key to be gone, got %q", val)
+	}
+	if val, _ := dst.Read("k1", "999"); val != "v1" {
+		t.Errorf("expected v1 before pending commit, got %q", val)
+	}
+	// The pending transaction survives the snapshot and can still commit
+	if err := dst.Commit("tx2"); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if val, _ := dst.Read("k1", "999"); val != "v2" {
+		t.Errorf("expected v2 after commit, got %q", val)
+	}
+}
+
+func TestSnapshotIsPointInTime(t *testing.T) {
+	for _, engine := range []string{kvstore.EngineSQLite, kvstore.EngineBolt} {
+		t.Run(engine, func(t *testing.T) {
+			src, err := kvstore.Open(engine, filepath.Join(t.TempDir(), "src.db"))
+			if err != nil {
+				t.Fatalf("Open error: %v", err)
+			}
+			defer src.Close()
+			fsm := raftstore.NewFSM(src)
+			for _, cmd := range []raftstore.Command{
+				{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+				{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "010"},
+				{Op: raftstore.OpCommit, TxID: "tx1"},
+			} {
+				if resp := apply(t, fsm, cmd); resp != nil {
+					t.Fatalf("%s error: %v", cmd.Op, resp)
+				}
+			}
+
+			snap, err := fsm.Snapshot()
+			if err != nil {
+				t.Fatalf("Snapshot error: %v", err)
+			}
+			// The FSM keeps applying while the snapshot is persisted
+			for _, cmd := range []raftstore.Command{
+				{Op: raftstore.OpBegin, TxID: "tx2", Timestamp: "020"},
+				{Op: raftstore.OpWrite, Key: "k", Value: "v2", TxID: "tx2", Timestamp: "020"},
+				{Op: raftstore.OpCommit, TxID: "tx2"},
+				{Op: raftstore.OpGC, Timestamp: "030"},
+			} {
+				if resp := apply(t, fsm, cmd); resp != nil {
+					t.Fatalf("%s error: %v", cmd.Op, resp)
+				}
+			}
+			sink := &memorySink{}
+			if err := snap.Persist(sink); err != nil {
+				t.Fatalf("Persist error: %v", err)
+			}
+			snap.Release()
+
+			dst := newTestStore(t, "dst.db")
+			if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+				t.Fatalf("Restore error: %v", err)
+			}
+			if val, _ := dst.Read("k", "999"); val != "v1" || dst.GCThreshold() != "" {
+				t.Errorf("expected the state at the snapshot, got %q, GC threshold %q", val, dst.GCThreshold())
+			}
+		})
+	}
+}
+
+func TestApplyGCAndSnapshot(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	fsm := raftstore.NewFSM(src)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+		{Op: raftstore.OpBegin, TxID: "tx2", Timestamp: "020"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v2", TxID: "tx2", Timestamp: "020"},
+		{Op: raftstore.OpCommit, TxID: "tx2"},
+		{Op: raftstore.OpGC, Timestamp: "030"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+	if versions, _ := src.Versions(); len(versions) != 1 {
+		t.Errorf("expected GC to leave 1 version, got %+v", versions)
+	}
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if got := dst.GCThreshold(); got != "030" {
+		t.Errorf("expected restored GC threshold 030, got %q", got)
+	}
+	if _, err := dst.Read("k", "015"); !errors.Is(err, kvstore.ErrBelowGCThreshold) {
+		t.Errorf("expected restored store to refuse old reads, got %v", err)
+	}
+}
+
+func TestCommitSequenceIsRaftIndex(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	fsm := raftstore.NewFSM(src)
+	for i, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		data, err := raftstore.EncodeCommand(cmd)
+		if err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+		if resp := fsm.Apply(&raft.Log{Index: uint64(5 + i), Data: data}); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	snap.Release()
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+
+	// The restored replica replays the change at the leader's sequence and
+	// numbers its own commits after it
+	w, err := dst.Watch("", "", 7)
+	if err != nil {
+		t.Fatalf("Watch error: %v", err)
+	}
+	defer w.Close()
+	if err := dst.Begin("tx2", "020", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := dst.WriteWithTimestamp("k", "v2", "tx2", "020"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := dst.Commit("tx2"); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	for _, want := range []string{"v1@7", "v2@8"} {
+		ev, err := w.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if got := fmt.Sprintf("%s@%d", ev.Version.Value, ev.Sequence); got != want {
+			t.Errorf("got change %s, want %s", got, want)
+		}
+	}
+}
+
+func TestApplyRegisterNode(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	fsm := raftstore.NewFSM(src)
+	if resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpRegisterNode, Key: "node4", Value: "node4:50054"}); resp != nil {
+		t.Fatalf("REGISTER_NODE error: %v", resp)
+	}
+	if addr, ok := src.NodeAddress("node4"); !ok || addr != "node4:50054" {
+		t.Fatalf("expected node4:50054, got %q, %v", addr, ok)
+	}
+
+	// The directory travels with snapshots so new members can forward too
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if addr, ok := dst.NodeAddress("node4"); !ok || addr != "node4:50054" {
+		t.Errorf("expected restored node4:50054, got %q, %v", addr, ok)
+	}
+}
+
+func TestApplyBeginAndSnapshot(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	fsm := raftstore.NewFSM(src)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "020"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	dst := newTestStore(t, "dst.db")
+	dstFSM := raftstore.NewFSM(dst)
+	if err := dstFSM.Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if rec, ok, err := dst.Transaction("tx1"); err != nil || !ok || rec.StartTimestamp != "010" {
+		t.Fatalf("expected restored record of tx1, got %+v, %v, %v", rec, ok, err)
+	}
+
+	// The restored record still guards the commit against conflicts
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx2", Timestamp: "030"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v2", TxID: "tx2", Timestamp: "030"},
+		{Op: raftstore.OpCommit, TxID: "tx2"},
+	} {
+		if resp := apply(t, dstFSM, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+	resp := apply(t, dstFSM, raftstore.Command{Op: raftstore.OpCommit, TxID: "tx1"})
+	if err, _ := resp.(error); !errors.Is(err, kvstore.ErrConflict) {
+		t.Errorf("expected conflict committing tx1, got %v", resp)
+	}
+}
+
+func TestApplySerializableCommit(t *testing.T) {
+	store := newTestStore(t, "fsm.db")
+	fsm := raftstore.NewFSM(store)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010", Isolation: kvstore.Serializable},
+		{Op: raftstore.OpBegin, TxID: "tx2", Timestamp: "020"},
+		{Op: raftstore.OpWrite, Key: "x", Value: "1", TxID: "tx2", Timestamp: "020"},
+		{Op: raftstore.OpCommit, TxID: "tx2"},
+		{Op: raftstore.OpWrite, Key: "y", Value: "1", TxID: "tx1", Timestamp: "030"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+
+	// tx1 read x, which tx2 wrote after tx1 began
+	resp := apply(t, fsm, raftstore.Command{Op: raftstore.OpCommit, TxID: "tx1", ReadSet: []kvstore.KeyRange{kvstore.PointRange("x")}})
+	if err, _ := resp.(error); !errors.Is(err, kvstore.ErrSerialization) {
+		t.Fatalf("expected serialization failure committing tx1, got %v", resp)
+	}
+	if v, _ := store.Read("y", "999"); v != "" {
+		t.Errorf("expected the write of tx1 to be aborted, got %q", v)
+	}
+}
+
+func TestApplyTransactionLifecycle(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	fsm := raftstore.NewFSM(src)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, Key: "node1", TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v1", TxID: "tx1", Timestamp: "020"},
+		{Op: raftstore.OpCommit, TxID: "tx1", Timestamp: "030"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+
+	// Entries applied again, or proposed after the commit, leave the
+	// committed transaction as it is
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, Key: "node2", TxID: "tx1", Timestamp: "040"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v2", TxID: "tx1", Timestamp: "050"},
+		{Op: raftstore.OpCommit, TxID: "tx1", Timestamp: "060"},
+		{Op: raftstore.OpAbort, TxID: "tx1"},
+	} {
+		if err, _ := apply(t, fsm, cmd).(error); err == nil {
+			t.Errorf("expected %s of a committed transaction to fail", cmd.Op)
+		}
+	}
+	if v, _ := src.Read("k", "999"); v != "v1" {
+		t.Errorf("expected k = v1, got %q", v)
+	}
+
+	// The record survives a snapshot with its outcome
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	rec, ok, err := dst.Transaction("tx1")
+	if err != nil || !ok {
+		t.Fatalf("Transaction(tx1) = %+v, %v, %v", rec, ok, err)
+	}
+	if rec.Owner != "node1" || rec.Status != kvstore.TxnCommitted || rec.StartTimestamp != "010" || rec.CommitTimestamp != "030" {
+		t.Errorf("expected tx1 of node1 committed at 030, got %+v", rec)
+	}
+}
+
+func TestRestoreSnapshotWithoutGCThreshold(t *testing.T) {
+	// Snapshots written before GC existed hold only the versions
+	var buf bytes.Buffer
+	versions := []kvstore.Version{{Key: "k", Value: "v", Timestamp: "010", TxID: "tx1", Committed: true}}
+	if err := gob.NewEncoder(&buf).Encode(versions); err != nil {
+		t.Fatalf("gob encode error: %v", err)
+	}
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&buf)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if val, _ := dst.Read("k", "999"); val != "v" || dst.GCThreshold() != "" {
+		t.Errorf("unexpected restore of legacy snapshot: %q, threshold %q", val, dst.GCThreshold())
+	}
+}
+
+func TestRestoreEmptySnapshot(t *testing.T) {
+	dst := newTestStore(t, "dst.db")
+	fsm := raftstore.NewFSM(dst)
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v", TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		if resp := apply(t, fsm, cmd); resp != nil {
+			t.Fatalf("%s error: %v", cmd.Op, resp)
+		}
+	}
+	if err := fsm.Restore(io.NopCloser(&bytes.Buffer{})); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if val, _ := dst.Read("k", "999"); val != "v" {
+		t.Errorf("expected an empty snapshot to keep the local store, got %q", val)
+	}
+}
+
+func TestSnapshotEmptyStore(t *testing.T) {
+	snap, err := raftstore.NewFSM(newTestStore(t, "src.db")).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	if err := raftstore.NewFSM(newTestStore(t, "dst.db")).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+}
+
+func TestSnapshotManyVersions(t *testing.T) {
+	src := newTestStore(t, "src.db")
+	var batch []kvstore.KeyValue
+	for i := 0; i < 2500; i++ {
+		batch = append(batch, kvstore.KeyValue{Key: fmt.Sprintf("k%04d", i), Value: "v"})
+	}
+	if err := src.WriteBatchWithTimestamp(batch, "tx1", "010"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	snap, err := raftstore.NewFSM(src).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	sink := &memorySink{}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("Persist error: %v", err)
+	}
+	snap.Release()
+
+	dst := newTestStore(t, "dst.db")
+	if err := raftstore.NewFSM(dst).Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if versions, _ := dst.Versions(); len(versions) != len(batch) {
+		t.Errorf("expected %d restored versions, got %d", len(batch), len(versions))
+	}
+}
@@ -0,0 +1,85 @@
+// internal/raftstore/gc.go
+package raftstore
+
+import (
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/kvstore"
+)
+
+// expireBatchSize caps the keys expired by one EXPIRE command
+const expireBatchSize = 1000
+
+// runGC periodically proposes a GC command while this node leads, so every
+// replica collects the same versions at the same point of the log. Versions
+// superseded more than retention ago are removed and reads older than that
+// are refused.
+func (s *Store) runGC(retention, interval time.Duration) {
+	defer s.loops.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		}
+		if !s.IsLeader() {
+			continue
+		}
+		threshold := hlc.FromTime(time.Now().Add(-retention))
+		s.applyBackground("GC", Command{Op: OpGC, Timestamp: threshold})
+	}
+}
+
+// runExpiry periodically replicates the expiration of keys whose TTL has
+// passed while this node leads. Reads already treat expired keys as deleted;
+// the EXPIRE command turns each expiry into a tombstone at the same point of
+// every replica's log, so that GC can reclaim it.
+func (s *Store) runExpiry(store *kvstore.Store, interval time.Duration) {
+	defer s.loops.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		}
+		if !s.IsLeader() {
+			continue
+		}
+		expired, err := store.Expired(hlc.FromTime(time.Now()), expireBatchSize)
+		if err != nil {
+			s.logger.Error("failed to find expired keys", "error", err)
+			continue
+		}
+		if len(expired) == 0 {
+			continue
+		}
+		cmd := Command{Op: OpExpire}
+		for _, v := range expired {
+			cmd.Batch = append(cmd.Batch, kvstore.KeyValue{Key: v.Key, ExpiresAt: v.ExpiresAt})
+		}
+		s.applyBackground("EXPIRE", cmd)
+	}
+}
+
+// applyBackground replicates a command proposed by a background loop and
+// logs, rather than returns, any failure
+func (s *Store) applyBackground(name string, cmd Command) {
+	data, err := EncodeCommand(cmd)
+	if err != nil {
+		s.logger.Error("failed to encode command", "op", name, "error", err)
+		return
+	}
+	future := s.raft.Apply(data, s.applyTimeout)
+	if err := future.Error(); err != nil {
+		s.logger.Warn("apply failed", "op", name, "error", err)
+		return
+	}
+	if err, ok := future.Response().(error); ok && err != nil {
+		s.logger.Error("command failed", "op", name, "error", err)
+	}
+}
@@ -2,9 +2,12 @@
 package raftstore
 
 import (
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -13,7 +16,40 @@ import (
 )
 
 type Store struct {
-	raft *raft.Raft
+	raft   *raft.Raft
+	id     raft.ServerID
+	logger hclog.Logger
+
+	transport   *raft.NetworkTransport
+	logStore    *raftboltdb.BoltStore
+	stableStore *raftboltdb.BoltStore
+
+	applyTimeout      time.Duration
+	membershipTimeout time.Duration
+
+	// grpcAddr is the client address this node registers when elected
+	grpcAddr string
+
+	// draining is set while the node hands off leadership for maintenance
+	draining atomic.Bool
+
+	// barrierTerm is the last term in which this node, as leader, applied a
+	// barrier. Until then its commit index may trail the previous leader's.
+	barrierTerm atomic.Uint64
+
+	// leaseTimeout is how long after a quorum confirmed leadership no other
+	// leader can have been elected, see VerifyLease
+	leaseTimeout time.Duration
+	leaseMu      sync.Mutex
+	lease        lease
+
+	// observer feeds leader changes to watchLeadership until Shutdown
+	observer *raft.Observer
+
+	// shutdownCh is closed by Shutdown to stop background loops, which
+	// Shutdown waits for on loops
+	shutdownCh chan struct{}
+	loops      sync.WaitGroup
 }
 
 func (s *Store) IsLeader() bool {
@@ -24,12 +60,343 @@ func (s *Store) Apply(data []byte, timeout time.Duration) raft.ApplyFuture {
 	return s.raft.Apply(data, timeout)
 }
 
-// NewRaftNode creates and starts a Raft node.
-// NewRaftNode(dataDir, nodeID, bindAddr string, peers []raft.Server, fsm raft.FSM)
-func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.Server, fsm raft.FSM) (*Store, error) {
+// ID returns the ID of this node.
+func (s *Store) ID() string {
+	return string(s.id)
+}
+
+// ApplyTimeout is the configured time a client write may wait to be committed.
+func (s *Store) ApplyTimeout() time.Duration {
+	return s.applyTimeout
+}
+
+// lease is the period in which this node is known to be the only leader
+type lease struct {
+	term    uint64
+	expires time.Time
+}
+
+// VerifyRead makes sure a read served from the local store observes every
+// write committed before the call (read-index). It confirms leadership with a
+// quorum and waits until the commit index seen at the start has been applied.
+// Must be called on the leader.
+func (s *Store) VerifyRead(timeout time.Duration) error {
+	term := s.raft.CurrentTerm()
+	if err := s.barrierOnce(term, timeout); err != nil {
+		return err
+	}
+
+	readIndex := s.raft.CommitIndex()
+	if err := s.verifyLeader(term); err != nil {
+		return err
+	}
+	if s.raft.AppliedIndex() >= readIndex {
+		return nil
+	}
+	// A barrier completes once every entry before it has been applied
+	return s.raft.Barrier(timeout).Error()
+}
+
+// VerifyLease makes sure a read served from the local store observes every
+// write committed before the call, as long as clocks run at about the same
+// rate. Within LeaderLeaseTimeout of a quorum confirming its leadership, no
+// other node can have been elected, since followers that recently heard from
+// a leader refuse to vote, so the leader serves the read without a round
+// trip. Once the lease runs out it is renewed as VerifyRead confirms
+// leadership. Must be called on the leader.
+func (s *Store) VerifyLease(timeout time.Duration) error {
+	term := s.raft.CurrentTerm()
+	if err := s.barrierOnce(term, timeout); err != nil {
+		return err
+	}
+	if s.HasLease() {
+		return nil
+	}
+	return s.verifyLeader(term)
+}
+
+// HasLease reports whether this node holds an unexpired leader lease
+func (s *Store) HasLease() bool {
+	s.leaseMu.Lock()
+	l := s.lease
+	s.leaseMu.Unlock()
+	return s.IsLeader() && l.term == s.raft.CurrentTerm() && time.Now().Before(l.expires)
+}
+
+// barrierOnce applies a barrier the first time it is called in term. A new
+// leader only learns the true commit index once an entry from its own term
+// commits, and may not have applied the entries of the previous leader yet.
+func (s *Store) barrierOnce(term uint64, timeout time.Duration) error {
+	if s.barrierTerm.Load() == term {
+		return nil
+	}
+	if err := s.raft.Barrier(timeout).Error(); err != nil {
+		return err
+	}
+	s.barrierTerm.Store(term)
+	return nil
+}
+
+// verifyLeader confirms leadership with a quorum and renews the lease from
+// the time the confirmation was requested
+func (s *Store) verifyLeader(term uint64) error {
+	start := time.Now()
+	if err := s.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	if expires := start.Add(s.leaseTimeout); term > s.lease.term || expires.After(s.lease.expires) {
+		s.lease = lease{term: term, expires: expires}
+	}
+	return nil
+}
+
+// AddVoter adds a server to the cluster as a voting member, or promotes an
+// existing non-voter. Must be called on the leader.
+func (s *Store) AddVoter(id, address string) error {
+	return s.raft.AddVoter(raft.ServerID(id), raft.ServerAddress(address), 0, s.membershipTimeout).Error()
+}
+
+// AddNonvoter adds a server that receives the replicated log but does not
+// take part in elections or count toward quorum. Must be called on the leader.
+func (s *Store) AddNonvoter(id, address string) error {
+	return s.raft.AddNonvoter(raft.ServerID(id), raft.ServerAddress(address), 0, s.membershipTimeout).Error()
+}
+
+// RemoveServer removes a server from the cluster. Must be called on the leader.
+func (s *Store) RemoveServer(id string) error {
+	return s.raft.RemoveServer(raft.ServerID(id), 0, s.membershipTimeout).Error()
+}
+
+// DemoteVoter turns a voting member into a non-voter. Must be called on the leader.
+func (s *Store) DemoteVoter(id string) error {
+	return s.raft.DemoteVoter(raft.ServerID(id), 0, s.membershipTimeout).Error()
+}
+
+// GetConfiguration returns the latest cluster membership known to this node.
+func (s *Store) GetConfiguration() ([]raft.Server, error) {
+	future := s.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	return future.Configuration().Servers, nil
+}
+
+// TransferLeadership hands leadership to the voter with the given ID, or to
+// the most up-to-date voter if id is empty. Must be called on the leader.
+func (s *Store) TransferLeadership(id string) error {
+	if id == "" {
+		return s.raft.LeadershipTransfer().Error()
+	}
+	servers, err := s.GetConfiguration()
+	if err != nil {
+		return err
+	}
+	for _, srv := range servers {
+		if string(srv.ID) == id {
+			return s.raft.LeadershipTransferToServer(srv.ID, srv.Address).Error()
+		}
+	}
+	return fmt.Errorf("unknown server %q", id)
+}
+
+// Drain puts the node into maintenance mode: it gives up leadership and keeps
+// handing it off if re-elected. New transactions should be refused while
+// IsDraining reports true. If the transfer fails the node stays draining.
+func (s *Store) Drain() error {
+	s.draining.Store(true)
+	if !s.IsLeader() {
+		return nil
+	}
+	if err := s.raft.LeadershipTransfer().Error(); err != nil {
+		return fmt.Errorf("leadership transfer failed: %w", err)
+	}
+	return nil
+}
+
+// Undrain takes the node out of maintenance mode.
+func (s *Store) Undrain() {
+	s.draining.Store(false)
+}
+
+// IsDraining reports whether the node is in maintenance mode.
+func (s *Store) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// watchLeadership transfers leadership away whenever a draining node is
+// elected, and otherwise registers the node's gRPC address so that followers
+// can forward to it
+func (s *Store) watchLeadership(observations <-chan raft.Observation) {
+	defer s.loops.Done()
+	for {
+		var o raft.Observation
+		select {
+		case <-s.shutdownCh:
+			return
+		case o = <-observations:
+		}
+		leader, ok := o.Data.(raft.LeaderObservation)
+		if !ok || leader.LeaderID != s.id {
+			continue
+		}
+		if !s.IsDraining() {
+			if s.grpcAddr != "" {
+				go s.applyBackground("REGISTER_NODE", Command{Op: OpRegisterNode, Key: string(s.id), Value: s.grpcAddr})
+			}
+			continue
+		}
+		s.logger.Info("draining node elected leader, transferring leadership")
+		if err := s.raft.LeadershipTransfer().Error(); err != nil {
+			s.logger.Error("leadership transfer failed", "error", err)
+		}
+	}
+}
+
+// RegisterNode replicates grpcAddr as the gRPC address of the node with the
+// given ID. Must be called on the leader.
+func (s *Store) RegisterNode(id, grpcAddr string) error {
+	data, err := EncodeCommand(Command{Op: OpRegisterNode, Key: id, Value: grpcAddr})
+	if err != nil {
+		return err
+	}
+	future := s.raft.Apply(data, s.applyTimeout)
+	if err := future.Error(); err != nil {
+		return err
+	}
+	if err, ok := future.Response().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// LeaderAddr returns the raft address of the current leader, or "" if there is none.
+func (s *Store) LeaderAddr() string {
+	addr, _ := s.raft.LeaderWithID()
+	return string(addr)
+}
+
+// LeaderID returns the ID of the current leader, or "" if there is none.
+func (s *Store) LeaderID() string {
+	_, id := s.raft.LeaderWithID()
+	return string(id)
+}
+
+// Options tunes a Raft node. Zero values fall back to the defaults of
+// raft.DefaultConfig() and of this package.
+type Options struct {
+	ApplyTimeout       time.Duration // how long a client write waits to be committed
+	MembershipTimeout  time.Duration // how long a membership change waits to be committed
+	TransportTimeout   time.Duration
+	TransportMaxPool   int
+	SnapshotRetain     int
+	HeartbeatTimeout   time.Duration
+	ElectionTimeout    time.Duration
+	LeaderLeaseTimeout time.Duration
+	CommitTimeout      time.Duration
+	SnapshotThreshold  uint64
+	SnapshotInterval   time.Duration
+	TrailingLogs       uint64
+	// GCRetention is how long superseded versions stay readable. Zero
+	// disables garbage collection.
+	GCRetention time.Duration
+	GCInterval  time.Duration // how often the leader proposes a GC
+	// ExpiryInterval is how often the leader expires keys past their TTL
+	ExpiryInterval time.Duration
+	// GRPCAddress is the client address of this node. The node registers it
+	// whenever it is elected, so that followers can forward writes to it.
+	GRPCAddress string
+}
+
+// DefaultOptions returns the options NewRaftNode uses when none are set.
+func DefaultOptions() Options {
+	def := raft.DefaultConfig()
+	return Options{
+		ApplyTimeout:       5 * time.Second,
+		MembershipTimeout:  raftTimeout(),
+		TransportTimeout:   raftTimeout(),
+		TransportMaxPool:   3,
+		SnapshotRetain:     2,
+		HeartbeatTimeout:   def.HeartbeatTimeout,
+		ElectionTimeout:    def.ElectionTimeout,
+		LeaderLeaseTimeout: def.LeaderLeaseTimeout,
+		CommitTimeout:      def.CommitTimeout,
+		SnapshotThreshold:  def.SnapshotThreshold,
+		SnapshotInterval:   def.SnapshotInterval,
+		TrailingLogs:       def.TrailingLogs,
+		GCRetention:        24 * time.Hour,
+		GCInterval:         time.Minute,
+		ExpiryInterval:     5 * time.Second,
+	}
+}
+
+// withDefaults fills zero fields from DefaultOptions
+func (o Options) withDefaults() Options {
+	def := DefaultOptions()
+	if o.ApplyTimeout == 0 {
+		o.ApplyTimeout = def.ApplyTimeout
+	}
+	if o.MembershipTimeout == 0 {
+		o.MembershipTimeout = def.MembershipTimeout
+	}
+	if o.TransportTimeout == 0 {
+		o.TransportTimeout = def.TransportTimeout
+	}
+	if o.TransportMaxPool == 0 {
+		o.TransportMaxPool = def.TransportMaxPool
+	}
+	if o.SnapshotRetain == 0 {
+		o.SnapshotRetain = def.SnapshotRetain
+	}
+	if o.HeartbeatTimeout == 0 {
+		o.HeartbeatTimeout = def.HeartbeatTimeout
+	}
+	if o.ElectionTimeout == 0 {
+		o.ElectionTimeout = def.ElectionTimeout
+	}
+	if o.LeaderLeaseTimeout == 0 {
+		o.LeaderLeaseTimeout = def.LeaderLeaseTimeout
+	}
+	if o.CommitTimeout == 0 {
+		o.CommitTimeout = def.CommitTimeout
+	}
+	if o.SnapshotThreshold == 0 {
+		o.SnapshotThreshold = def.SnapshotThreshold
+	}
+	if o.SnapshotInterval == 0 {
+		o.SnapshotInterval = def.SnapshotInterval
+	}
+	if o.TrailingLogs == 0 {
+		o.TrailingLogs = def.TrailingLogs
+	}
+	if o.GCInterval == 0 {
+		o.GCInterval = def.GCInterval
+	}
+	if o.ExpiryInterval == 0 {
+		o.ExpiryInterval = def.ExpiryInterval
+	}
+	return o
+}
+
+// NewRaftNode creates and starts a Raft node, with the GC and expiry loops
+// that run on the store of fsm while the node leads.
+func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.Server, fsm *FSM, opts Options) (*Store, error) {
+	opts = opts.withDefaults()
+
 	// Create raft config
 	raftConfig := raft.DefaultConfig()
 	raftConfig.LocalID = raft.ServerID(nodeID)
+	raftConfig.HeartbeatTimeout = opts.HeartbeatTimeout
+	raftConfig.ElectionTimeout = opts.ElectionTimeout
+	raftConfig.LeaderLeaseTimeout = opts.LeaderLeaseTimeout
+	raftConfig.CommitTimeout = opts.CommitTimeout
+	raftConfig.SnapshotThreshold = opts.SnapshotThreshold
+	raftConfig.SnapshotInterval = opts.SnapshotInterval
+	raftConfig.TrailingLogs = opts.TrailingLogs
+	if err := raft.ValidateConfig(raftConfig); err != nil {
+		return nil, fmt.Errorf("invalid raft config: %w", err)
+	}
 
 	// Create a proper logger for Raft
 	logger := hclog.New(&hclog.LoggerOptions{
@@ -46,11 +413,11 @@ func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.S
 
 	// Create transport: use bindAddr for listening and advertiseTCP for advertising
 	transport, err := raft.NewTCPTransportWithLogger(
-		bindAddr,      // Address to bind to for listening
-		advertiseTCP,  // Address to advertise to other nodes
-		3,             // Max pool size
-		raftTimeout(), // Timeout
-		logger,        // Logger
+		bindAddr,              // Address to bind to for listening
+		advertiseTCP,          // Address to advertise to other nodes
+		opts.TransportMaxPool, // Max pool size
+		opts.TransportTimeout, // Timeout
+		logger,                // Logger
 	)
 	if err != nil {
 		return nil, err
@@ -59,7 +426,7 @@ func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.S
 	// Create a snapshot store with the appropriate logger
 	snapshots, err := raft.NewFileSnapshotStoreWithLogger(
 		dataDir,
-		2,
+		opts.SnapshotRetain,
 		logger,
 	)
 	if err != nil {
@@ -98,7 +465,34 @@ func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.S
 		return nil, err
 	}
 
-	store := &Store{raft: r}
+	store := &Store{
+		raft:              r,
+		applyTimeout:      opts.ApplyTimeout,
+		membershipTimeout: opts.MembershipTimeout,
+		grpcAddr:          opts.GRPCAddress,
+		leaseTimeout:      opts.LeaderLeaseTimeout,
+		id:                raftConfig.LocalID,
+		logger:            logger,
+		transport:         transport,
+		logStore:          logStore,
+		stableStore:       stableStore,
+		shutdownCh:        make(chan struct{}),
+	}
+
+	observations := make(chan raft.Observation, 1)
+	store.observer = raft.NewObserver(observations, false, func(o *raft.Observation) bool {
+		_, ok := o.Data.(raft.LeaderObservation)
+		return ok
+	})
+	r.RegisterObserver(store.observer)
+	store.loops.Add(1)
+	go store.watchLeadership(observations)
+	if opts.GCRetention > 0 {
+		store.loops.Add(1)
+		go store.runGC(opts.GCRetention, opts.GCInterval)
+	}
+	store.loops.Add(1)
+	go store.runExpiry(fsm.store, opts.ExpiryInterval)
 
 	// Bootstrap the cluster if necessary
 	hasState, err := raft.HasExistingState(logStore, stableStore, snapshots)
@@ -114,6 +508,32 @@ func NewRaftNode(dataDir, nodeID, advertiseAddr, bindAddr string, peers []raft.S
 	return store, nil
 }
 
+// Shutdown stops the Raft node and closes its transport and BoltDB stores.
+// A leader first hands leadership to another voter so the cluster does not
+// have to wait for an election timeout. Background loops have exited when it
+// returns.
+func (s *Store) Shutdown() error {
+	close(s.shutdownCh)
+	s.raft.DeregisterObserver(s.observer)
+	s.loops.Wait()
+	if s.IsLeader() {
+		s.logger.Info("stepping down before shutdown")
+		if err := s.raft.LeadershipTransfer().Error(); err != nil {
+			s.logger.Warn("leadership transfer before shutdown failed", "error", err)
+		}
+	}
+	if err := s.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	if err := s.transport.Close(); err != nil {
+		return err
+	}
+	if err := s.logStore.Close(); err != nil {
+		return err
+	}
+	return s.stableStore.Close()
+}
+
 func raftTimeout() time.Duration {
 	return 10 * time.Second
 }
@@ -0,0 +1,282 @@
+package raftstore_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	"github.com/hashicorp/raft"
+)
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// startSingleNode bootstraps a one-node cluster on a free local port and
+// waits for it to lead
+func startSingleNode(t *testing.T, opts raftstore.Options) (*raftstore.Store, *kvstore.Store) {
+	t.Helper()
+	store := newTestStore(t, "kv.db")
+	return startNode(t, t.TempDir(), freeAddr(t), store, opts), store
+}
+
+// startNode starts node n1 at addr, with its Raft state in dataDir, and
+// waits for it to lead
+func startNode(t *testing.T, dataDir, addr string, store *kvstore.Store, opts raftstore.Options) *raftstore.Store {
+	t.Helper()
+	peers := []raft.Server{{ID: "n1", Address: raft.ServerAddress(addr), Suffrage: raft.Voter}}
+	node, err := raftstore.NewRaftNode(dataDir, "n1", addr, addr, peers, raftstore.NewFSM(store), opts)
+	if err != nil {
+		t.Fatalf("NewRaftNode error: %v", err)
+	}
+	t.Cleanup(func() { node.Shutdown() })
+	deadline := time.Now().Add(10 * time.Second)
+	for !node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("node did not become leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return node
+}
+
+// fastOptions makes elections and leadership transfers quick on loopback
+var fastOptions = raftstore.Options{
+	HeartbeatTimeout:   100 * time.Millisecond,
+	ElectionTimeout:    100 * time.Millisecond,
+	LeaderLeaseTimeout: 50 * time.Millisecond,
+	CommitTimeout:      5 * time.Millisecond,
+}
+
+// startCluster bootstraps a cluster of n voters, n1 to nN, on free local
+// ports and waits for one of them to lead
+func startCluster(t *testing.T, n int, opts raftstore.Options) []*raftstore.Store {
+	t.Helper()
+	var peers []raft.Server
+	for i := 1; i <= n; i++ {
+		peers = append(peers, raft.Server{ID: raft.ServerID(fmt.Sprintf("n%d", i)), Address: raft.ServerAddress(freeAddr(t)), Suffrage: raft.Voter})
+	}
+	nodes := make([]*raftstore.Store, n)
+	for i, p := range peers {
+		store := newTestStore(t, "kv.db")
+		addr := string(p.Address)
+		node, err := raftstore.NewRaftNode(t.TempDir(), string(p.ID), addr, addr, peers, raftstore.NewFSM(store), opts)
+		if err != nil {
+			t.Fatalf("NewRaftNode error: %v", err)
+		}
+		t.Cleanup(func() { node.Shutdown() })
+		nodes[i] = node
+	}
+	waitLeader(t, nodes, "")
+	return nodes
+}
+
+// waitLeader waits for a node whose ID is not "not" to lead, and returns it
+func waitLeader(t *testing.T, nodes []*raftstore.Store, not string) *raftstore.Store {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, node := range nodes {
+			if node.IsLeader() && node.ID() != not {
+				return node
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no leader other than %q was elected", not)
+	return nil
+}
+
+func TestDrainTransfersLeadership(t *testing.T) {
+	nodes := startCluster(t, 3, fastOptions)
+	drained := waitLeader(t, nodes, "")
+	if err := drained.Drain(); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	if !drained.IsDraining() {
+		t.Fatal("expected the node to be draining")
+	}
+	leader := waitLeader(t, nodes, drained.ID())
+
+	// Handing leadership back elects the drained node, which must give it
+	// up again
+	if err := leader.TransferLeadership(drained.ID()); err != nil {
+		t.Fatalf("TransferLeadership error: %v", err)
+	}
+	leader = waitLeader(t, nodes, drained.ID())
+	time.Sleep(5 * fastOptions.ElectionTimeout)
+	if drained.IsLeader() || !leader.IsLeader() {
+		t.Fatal("expected the drained node to give up leadership once re-elected")
+	}
+
+	drained.Undrain()
+	if drained.IsDraining() {
+		t.Fatal("expected Undrain to end the drain")
+	}
+	if err := leader.TransferLeadership(drained.ID()); err != nil {
+		t.Fatalf("TransferLeadership error: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !drained.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the undrained node to keep leadership")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDrainWithoutOtherVoters(t *testing.T) {
+	node, _ := startSingleNode(t, raftstore.Options{})
+	if err := node.Drain(); err == nil {
+		t.Fatal("expected Drain to fail with no voter to take over")
+	}
+	if !node.IsDraining() {
+		t.Error("expected the node to stay draining after a failed transfer")
+	}
+}
+
+func TestShutdownStopsBackgroundLoops(t *testing.T) {
+	store := newTestStore(t, "kv.db")
+	addr := freeAddr(t)
+	peers := []raft.Server{{ID: "n1", Address: raft.ServerAddress(addr), Suffrage: raft.Voter}}
+	opts := raftstore.Options{GCRetention: time.Hour, GCInterval: time.Millisecond, ExpiryInterval: time.Millisecond}
+	node, err := raftstore.NewRaftNode(t.TempDir(), "n1", addr, addr, peers, raftstore.NewFSM(store), opts)
+	if err != nil {
+		t.Fatalf("NewRaftNode error: %v", err)
+	}
+	if err := node.Shutdown(); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	buf := make([]byte, 1<<20)
+	stacks := string(buf[:runtime.Stack(buf, true)])
+	for _, loop := range []string{"watchLeadership", "runGC", "runExpiry"} {
+		if strings.Contains(stacks, "raftstore.(*Store)."+loop) {
+			t.Errorf("%s still running after Shutdown", loop)
+		}
+	}
+}
+
+func TestLeaderLease(t *testing.T) {
+	node, _ := startSingleNode(t, raftstore.Options{LeaderLeaseTimeout: 200 * time.Millisecond})
+	if node.HasLease() {
+		t.Fatal("expected no lease before leadership was confirmed")
+	}
+	if err := node.VerifyLease(time.Second); err != nil {
+		t.Fatalf("VerifyLease error: %v", err)
+	}
+	if !node.HasLease() {
+		t.Fatal("expected a lease once leadership was confirmed")
+	}
+	time.Sleep(300 * time.Millisecond)
+	if node.HasLease() {
+		t.Fatal("expected the lease to run out")
+	}
+	if err := node.VerifyRead(time.Second); err != nil {
+		t.Fatalf("VerifyRead error: %v", err)
+	}
+	if !node.HasLease() {
+		t.Error("expected VerifyRead to renew the lease")
+	}
+}
+
+func TestVerifyReadSeesCommittedWrites(t *testing.T) {
+	node, store := startSingleNode(t, raftstore.Options{})
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v", TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		data, err := raftstore.EncodeCommand(cmd)
+		if err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+		// Enqueue without waiting; VerifyRead must wait for them to be applied
+		node.Apply(data, time.Second)
+	}
+	if err := node.VerifyRead(5 * time.Second); err != nil {
+		t.Fatalf("VerifyRead error: %v", err)
+	}
+	if val, _ := store.Read("k", "999"); val != "v" {
+		t.Errorf("expected the committed write to be applied, got %q", val)
+	}
+}
+
+func TestNodeExpiresKeys(t *testing.T) {
+	node, store := startSingleNode(t, raftstore.Options{ExpiryInterval: 10 * time.Millisecond})
+	for _, cmd := range []raftstore.Command{
+		{Op: raftstore.OpBegin, TxID: "tx1", Timestamp: "010"},
+		{Op: raftstore.OpWrite, Key: "k", Value: "v", TxID: "tx1", Timestamp: "010", ExpiresAt: "050"},
+		{Op: raftstore.OpCommit, TxID: "tx1"},
+	} {
+		data, err := raftstore.EncodeCommand(cmd)
+		if err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+		if err := node.Apply(data, time.Second).Error(); err != nil {
+			t.Fatalf("apply error: %v", err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		expired, err := store.Expired(hlc.FromTime(time.Now()), 10)
+		if err != nil {
+			t.Fatalf("Expired error: %v", err)
+		}
+		if len(expired) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the leader to expire %v", expired)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartWithLegacyEmptySnapshot(t *testing.T) {
+	// Nodes that ran before snapshots were persisted wrote empty ones, and
+	// kept their data only in the engine file
+	dataDir := t.TempDir()
+	addr := freeAddr(t)
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, 1, io.Discard)
+	if err != nil {
+		t.Fatalf("NewFileSnapshotStore error: %v", err)
+	}
+	config := raft.Configuration{Servers: []raft.Server{{ID: "n1", Address: raft.ServerAddress(addr), Suffrage: raft.Voter}}}
+	_, trans := raft.NewInmemTransport(raft.ServerAddress(addr))
+	sink, err := snapshots.Create(raft.SnapshotVersionMax, 5, 1, config, 1, trans)
+	if err != nil {
+		t.Fatalf("snapshot Create error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("snapshot Close error: %v", err)
+	}
+
+	store := newTestStore(t, "kv.db")
+	if err := store.Begin("tx1", "010", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := store.WriteWithTimestamp("k", "v", "tx1", "010"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := store.Commit("tx1"); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	startNode(t, dataDir, addr, store, raftstore.Options{})
+	if val, _ := store.Read("k", "999"); val != "v" {
+		t.Errorf("expected the engine data to survive the empty snapshot, got %q", val)
+	}
+}
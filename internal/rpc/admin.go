@@ -0,0 +1,121 @@
+// internal/rpc/admin.go
+package rpc
+
+import (
+	"context"
+	"log"
+
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc"
+)
+
+type adminServer struct {
+	amberpb.UnimplementedAdminServiceServer
+	raftStore *raftstore.Store
+}
+
+func RegisterAdminService(grpcServer *grpc.Server, raftStore *raftstore.Store) {
+	amberpb.RegisterAdminServiceServer(grpcServer, &adminServer{raftStore: raftStore})
+}
+
+func (s *adminServer) AddVoter(ctx context.Context, req *amberpb.AddServerRequest) (*amberpb.Status, error) {
+	if req.Id == "" || req.Address == "" {
+		return &amberpb.Status{Success: false, Message: "id and address are required"}, nil
+	}
+	return s.leaderOp("AddVoter", func() error {
+		if err := s.raftStore.AddVoter(req.Id, req.Address); err != nil {
+			return err
+		}
+		return s.registerNode(req)
+	})
+}
+
+func (s *adminServer) AddNonvoter(ctx context.Context, req *amberpb.AddServerRequest) (*amberpb.Status, error) {
+	if req.Id == "" || req.Address == "" {
+		return &amberpb.Status{Success: false, Message: "id and address are required"}, nil
+	}
+	return s.leaderOp("AddNonvoter", func() error {
+		if err := s.raftStore.AddNonvoter(req.Id, req.Address); err != nil {
+			return err
+		}
+		return s.registerNode(req)
+	})
+}
+
+// registerNode replicates the gRPC address of an added server, if given
+func (s *adminServer) registerNode(req *amberpb.AddServerRequest) error {
+	if req.GrpcAddress == "" {
+		return nil
+	}
+	return s.raftStore.RegisterNode(req.Id, req.GrpcAddress)
+}
+
+func (s *adminServer) RemoveServer(ctx context.Context, req *amberpb.ServerID) (*amberpb.Status, error) {
+	if req.Id == "" {
+		return &amberpb.Status{Success: false, Message: "id is required"}, nil
+	}
+	return s.leaderOp("RemoveServer", func() error {
+		return s.raftStore.RemoveServer(req.Id)
+	})
+}
+
+func (s *adminServer) DemoteVoter(ctx context.Context, req *amberpb.ServerID) (*amberpb.Status, error) {
+	if req.Id == "" {
+		return &amberpb.Status{Success: false, Message: "id is required"}, nil
+	}
+	return s.leaderOp("DemoteVoter", func() error {
+		return s.raftStore.DemoteVoter(req.Id)
+	})
+}
+
+func (s *adminServer) GetConfiguration(ctx context.Context, _ *amberpb.Empty) (*amberpb.Configuration, error) {
+	servers, err := s.raftStore.GetConfiguration()
+	if err != nil {
+		log.Printf("GetConfiguration error: %v", err)
+		return nil, err
+	}
+	resp := &amberpb.Configuration{LeaderId: s.raftStore.LeaderID()}
+	for _, srv := range servers {
+		resp.Servers = append(resp.Servers, &amberpb.Server{
+			Id:       string(srv.ID),
+			Address:  string(srv.Address),
+			Suffrage: srv.Suffrage.String(),
+		})
+	}
+	return resp, nil
+}
+
+func (s *adminServer) TransferLeadership(ctx context.Context, req *amberpb.TransferLeadershipRequest) (*amberpb.Status, error) {
+	return s.leaderOp("TransferLeadership", func() error {
+		return s.raftStore.TransferLeadership(req.Id)
+	})
+}
+
+func (s *adminServer) Drain(ctx context.Context, _ *amberpb.Empty) (*amberpb.Status, error) {
+	if err := s.raftStore.Drain(); err != nil {
+		log.Printf("Drain error: %v", err)
+		return &amberpb.Status{Success: false, Message: err.Error()}, nil
+	}
+	log.Printf("Node draining: refusing new transactions")
+	return &amberpb.Status{Success: true, Message: "Draining"}, nil
+}
+
+func (s *adminServer) Undrain(ctx context.Context, _ *amberpb.Empty) (*amberpb.Status, error) {
+	s.raftStore.Undrain()
+	log.Printf("Node undrained: accepting new transactions")
+	return &amberpb.Status{Success: true, Message: "OK"}, nil
+}
+
+// leaderOp runs a leader-only operation and reports the outcome
+func (s *adminServer) leaderOp(name string, change func() error) (*amberpb.Status, error) {
+	if !s.raftStore.IsLeader() {
+		log.Printf("%s rejected: not the leader", name)
+		return &amberpb.Status{Success: false, Message: "not the leader"}, nil
+	}
+	if err := change(); err != nil {
+		log.Printf("%s error: %v", name, err)
+		return &amberpb.Status{Success: false, Message: err.Error()}, nil
+	}
+	return &amberpb.Status{Success: true, Message: "OK"}, nil
+}
@@ -0,0 +1,106 @@
+package rpc_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+)
+
+// suffrages returns the suffrage of each server in the configuration, by ID
+func suffrages(t *testing.T, admin amberpb.AdminServiceServer) map[string]string {
+	t.Helper()
+	conf, err := admin.GetConfiguration(context.Background(), &amberpb.Empty{})
+	if err != nil {
+		t.Fatalf("GetConfiguration error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, srv := range conf.Servers {
+		got[srv.Id] = srv.Suffrage
+	}
+	return got
+}
+
+// expectSuccess fails the test unless an admin call succeeded
+func expectSuccess(t *testing.T, name string, resp *amberpb.Status, err error) {
+	t.Helper()
+	if err != nil || !resp.Success {
+		t.Fatalf("%s: got %v, %v", name, resp, err)
+	}
+}
+
+func TestAdminMembership(t *testing.T) {
+	leader, store := startLeader(t)
+	admin := rpc.NewAdminServer(leader)
+	ctx := context.Background()
+	addr2, addr3 := freeAddr(t), freeAddr(t)
+	node2, _ := startNode(t, "n2", addr2, nil)
+	startNode(t, "n3", addr3, nil)
+
+	resp, err := admin.AddVoter(ctx, &amberpb.AddServerRequest{Id: "n2", Address: addr2, GrpcAddress: "n2:50051"})
+	expectSuccess(t, "AddVoter", resp, err)
+	resp, err = admin.AddNonvoter(ctx, &amberpb.AddServerRequest{Id: "n3", Address: addr3})
+	expectSuccess(t, "AddNonvoter", resp, err)
+	want := map[string]string{"n1": "Voter", "n2": "Voter", "n3": "Nonvoter"}
+	if got := suffrages(t, admin); !reflect.DeepEqual(got, want) {
+		t.Errorf("after adding servers: got %v, want %v", got, want)
+	}
+	if addr, ok := store.NodeAddress("n2"); !ok || addr != "n2:50051" {
+		t.Errorf("expected AddVoter to register the gRPC address of n2, got %q, %v", addr, ok)
+	}
+	if _, ok := store.NodeAddress("n3"); ok {
+		t.Error("expected no gRPC address for n3, none was given")
+	}
+
+	resp, err = admin.RemoveServer(ctx, &amberpb.ServerID{Id: "n3"})
+	expectSuccess(t, "RemoveServer", resp, err)
+	delete(want, "n3")
+	if got := suffrages(t, admin); !reflect.DeepEqual(got, want) {
+		t.Errorf("after removing n3: got %v, want %v", got, want)
+	}
+
+	resp, err = admin.TransferLeadership(ctx, &amberpb.TransferLeadershipRequest{Id: "n2"})
+	expectSuccess(t, "TransferLeadership", resp, err)
+	waitLeader(t, node2)
+
+	// Membership changes are refused by the old leader
+	resp, err = admin.RemoveServer(ctx, &amberpb.ServerID{Id: "n2"})
+	if err != nil || resp.Success || resp.Message != "not the leader" {
+		t.Errorf("RemoveServer on a follower: got %v, %v", resp, err)
+	}
+}
+
+func TestAdminTransferToUnknownServer(t *testing.T) {
+	leader, _ := startLeader(t)
+	resp, err := rpc.NewAdminServer(leader).TransferLeadership(context.Background(), &amberpb.TransferLeadershipRequest{Id: "n9"})
+	if err != nil || resp.Success {
+		t.Errorf("TransferLeadership to an unknown server: got %v, %v", resp, err)
+	}
+	if !leader.IsLeader() {
+		t.Error("expected the node to keep leadership")
+	}
+}
+
+func TestAdminRequiresIDAndAddress(t *testing.T) {
+	leader, _ := startLeader(t)
+	admin := rpc.NewAdminServer(leader)
+	ctx := context.Background()
+	for name, call := range map[string]func() (*amberpb.Status, error){
+		"AddVoter without address": func() (*amberpb.Status, error) {
+			return admin.AddVoter(ctx, &amberpb.AddServerRequest{Id: "n2"})
+		},
+		"AddNonvoter without id": func() (*amberpb.Status, error) {
+			return admin.AddNonvoter(ctx, &amberpb.AddServerRequest{Address: freeAddr(t)})
+		},
+		"RemoveServer without id": func() (*amberpb.Status, error) {
+			return admin.RemoveServer(ctx, &amberpb.ServerID{})
+		},
+	} {
+		resp, err := call()
+		if err != nil || resp.Success {
+			t.Errorf("%s: got %v, %v", name, resp, err)
+		}
+	}
+}
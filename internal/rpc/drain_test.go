@@ -0,0 +1,73 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainRefusesNewWork(t *testing.T) {
+	node, store := startLeader(t)
+	srv := rpc.NewServer(store, node, hlc.NewClock(), nil)
+	ctx := context.Background()
+	var txIDs []string
+	for i := 0; i < 2; i++ {
+		tx, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{})
+		if err != nil {
+			t.Fatalf("BeginTransaction error: %v", err)
+		}
+		if _, err := srv.Write(ctx, &amberpb.WriteRequest{Key: []byte("k"), Value: []byte(tx.Id), TxId: tx.Id}); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		txIDs = append(txIDs, tx.Id)
+	}
+
+	// With no other voter to take over, the node keeps leading while draining
+	node.Drain()
+	if !node.IsDraining() {
+		t.Fatal("expected the node to be draining")
+	}
+
+	for name, call := range map[string]func() error{
+		"BeginTransaction": func() error {
+			_, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{})
+			return err
+		},
+		"Write": func() error {
+			_, err := srv.Write(ctx, &amberpb.WriteRequest{Key: []byte("k"), Value: []byte("v"), TxId: txIDs[0]})
+			return err
+		},
+		"CompareAndSwap": func() error {
+			_, err := srv.CompareAndSwap(ctx, &amberpb.CompareAndSwapRequest{Key: []byte("k"), Value: []byte("v"), Condition: &amberpb.CompareAndSwapRequest_MustNotExist{MustNotExist: true}})
+			return err
+		},
+		"Txn": func() error {
+			_, err := srv.Txn(ctx, &amberpb.TxnRequest{})
+			return err
+		},
+	} {
+		if code := status.Code(call()); code != codes.Unavailable {
+			t.Errorf("%s while draining: got code %v, want %v", name, code, codes.Unavailable)
+		}
+	}
+
+	// Transactions begun before the drain can still finish
+	resp, err := srv.Commit(ctx, &amberpb.TxnID{Id: txIDs[0]})
+	if err != nil || !resp.Success {
+		t.Errorf("Commit while draining: got %v, %v", resp, err)
+	}
+	resp, err = srv.Abort(ctx, &amberpb.TxnID{Id: txIDs[1]})
+	if err != nil || !resp.Success {
+		t.Errorf("Abort while draining: got %v, %v", resp, err)
+	}
+
+	node.Undrain()
+	if _, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{}); err != nil {
+		t.Errorf("BeginTransaction after Undrain error: %v", err)
+	}
+}
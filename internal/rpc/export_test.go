@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+)
+
+// NewLocalServer returns the service without Raft, for the RPCs that are
+// served from the local replica
+func NewLocalServer(store *kvstore.Store) amberpb.AmberServiceServer {
+	return &server{store: store, clock: hlc.NewClock(), reads: newReadSets()}
+}
+
+// NewServer returns the service of a node running raftStore, as registered
+// by RegisterAmberService
+func NewServer(store *kvstore.Store, raftStore *raftstore.Store, clock *hlc.Clock, grpcAddrs map[string]string) amberpb.AmberServiceServer {
+	return &server{
+		store:     store,
+		raftStore: raftStore,
+		clock:     clock,
+		forwarder: newForwarder(raftStore, store, grpcAddrs),
+		reads:     newReadSets(),
+	}
+}
+
+// GRPCAddr looks up the gRPC address of the configured peer with raft
+// address raftAddr
+func GRPCAddr(grpcAddrs map[string]string, raftAddr string) (string, bool) {
+	return newForwarder(nil, nil, grpcAddrs).grpcAddr(raftAddr)
+}
+
+// NewAdminServer returns the admin service of a node running raftStore
+func NewAdminServer(raftStore *raftstore.Store) amberpb.AdminServiceServer {
+	return &adminServer{raftStore: raftStore}
+}
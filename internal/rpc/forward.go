@@ -0,0 +1,129 @@
+// internal/rpc/forward.go
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// forwardedKey marks a request that a follower already relayed to the leader,
+// so a node with a stale view of the leader does not bounce it around again.
+const forwardedKey = "x-amberdb-forwarded"
+
+// forwarder relays leader-only requests from a follower to the current leader
+type forwarder struct {
+	raftStore *raftstore.Store
+	nodes     *kvstore.Store    // replicated node ID -> gRPC address directory
+	grpcAddrs map[string]string // raft address -> gRPC address of configured peers
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newForwarder(raftStore *raftstore.Store, nodes *kvstore.Store, grpcAddrs map[string]string) *forwarder {
+	return &forwarder{
+		raftStore: raftStore,
+		nodes:     nodes,
+		grpcAddrs: grpcAddrs,
+		conns:     make(map[string]*grpc.ClientConn),
+	}
+}
+
+// leaderClient returns a client connected to the current leader's gRPC endpoint
+func (f *forwarder) leaderClient() (amberpb.AmberServiceClient, error) {
+	raftAddr := f.raftStore.LeaderAddr()
+	if raftAddr == "" {
+		return nil, fmt.Errorf("no leader elected")
+	}
+	addr, ok := f.nodes.NodeAddress(f.raftStore.LeaderID())
+	if !ok {
+		addr, ok = f.grpcAddr(raftAddr)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown gRPC address for leader %s", raftAddr)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	conn, ok := f.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			return nil, fmt.Errorf("dial leader %s: %w", addr, err)
+		}
+		f.conns[addr] = conn
+	}
+	return amberpb.NewAmberServiceClient(conn), nil
+}
+
+// grpcAddr looks up the gRPC address of a configured peer by raft address,
+// for leaders that have not registered one yet. Raft reports the
+// leader by the resolved address its transport advertises, so configured
+// hostnames are resolved before comparing.
+func (f *forwarder) grpcAddr(raftAddr string) (string, bool) {
+	if addr, ok := f.grpcAddrs[raftAddr]; ok {
+		return addr, true
+	}
+	for configured, addr := range f.grpcAddrs {
+		resolved, err := net.ResolveTCPAddr("tcp", configured)
+		if err == nil && resolved.String() == raftAddr {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+// errAlreadyForwarded is returned when a forwarded request reaches a follower.
+// RPCs fail with Unavailable on it, so that the client retries once the
+// nodes agree on a leader.
+var errAlreadyForwarded = errors.New("not the leader, and the request was already forwarded")
+
+// leaderContext returns a client for the leader and a context marking the
+// request as forwarded, unless it was already forwarded once
+func (f *forwarder) leaderContext(ctx context.Context) (context.Context, amberpb.AmberServiceClient, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(forwardedKey)) > 0 {
+		return nil, nil, errAlreadyForwarded
+	}
+	client, err := f.leaderClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, forwardedKey, "true"), client, nil
+}
+
+// forward sends a request that returns a Status to the leader
+func (f *forwarder) forward(ctx context.Context, name string, call func(context.Context, amberpb.AmberServiceClient) (*amberpb.Status, error)) (*amberpb.Status, error) {
+	ctx, client, err := f.leaderContext(ctx)
+	if errors.Is(err, errAlreadyForwarded) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		log.Printf("%s forward error: %v", name, err)
+		return &amberpb.Status{Success: false, Message: err.Error()}, nil
+	}
+	return call(ctx, client)
+}
+
+// Close closes all connections to leaders
+func (f *forwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for addr, conn := range f.conns {
+		conn.Close()
+		delete(f.conns, addr)
+	}
+	return nil
+}
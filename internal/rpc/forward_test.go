@@ -0,0 +1,134 @@
+package rpc_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/hlc"
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serveGRPC serves the service of the node on a free local port and returns
+// its address
+func serveGRPC(t *testing.T, srv amberpb.AmberServiceServer) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	amberpb.RegisterAmberServiceServer(grpcServer, srv)
+	go grpcServer.Serve(l)
+	t.Cleanup(grpcServer.Stop)
+	return l.Addr().String()
+}
+
+func TestForwardToLeader(t *testing.T) {
+	leader, leaderStore := startLeader(t)
+	leaderGRPC := serveGRPC(t, rpc.NewServer(leaderStore, leader, hlc.NewClock(), nil))
+	addr := freeAddr(t)
+	follower, followerStore := startNode(t, "n2", addr, nil)
+	if err := leader.AddNonvoter("n2", addr); err != nil {
+		t.Fatalf("AddNonvoter error: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for follower.LeaderAddr() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("follower did not learn the leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The leader registered no gRPC address, so the follower finds it among
+	// the configured peers by the raft address it advertises
+	srv := rpc.NewServer(followerStore, follower, hlc.NewClock(), map[string]string{leader.LeaderAddr(): leaderGRPC})
+	ctx := context.Background()
+	tx, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{})
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %v", err)
+	}
+	resp, err := srv.Write(ctx, &amberpb.WriteRequest{Key: []byte("k"), Value: []byte("v"), TxId: tx.Id})
+	expectSuccess(t, "Write", resp, err)
+	resp, err = srv.Commit(ctx, &amberpb.TxnID{Id: tx.Id})
+	expectSuccess(t, "Commit", resp, err)
+	if val, _ := leaderStore.Read("k", hlc.NewClock().Now()); val != "v" {
+		t.Errorf("expected the forwarded write on the leader, got %q", val)
+	}
+}
+
+func TestForwardOnlyOnce(t *testing.T) {
+	// A node that is not the leader refuses a request another node forwarded
+	follower, store := startNode(t, "n2", freeAddr(t), nil)
+	srv := rpc.NewServer(store, follower, hlc.NewClock(), nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-amberdb-forwarded", "true"))
+
+	for name, call := range map[string]func() error{
+		"BeginTransaction": func() error {
+			_, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{})
+			return err
+		},
+		"Write": func() error {
+			_, err := srv.Write(ctx, &amberpb.WriteRequest{Key: []byte("k"), Value: []byte("v"), TxId: "tx1"})
+			return err
+		},
+		"Commit": func() error {
+			_, err := srv.Commit(ctx, &amberpb.TxnID{Id: "tx1"})
+			return err
+		},
+		"CompareAndSwap": func() error {
+			_, err := srv.CompareAndSwap(ctx, &amberpb.CompareAndSwapRequest{Key: []byte("k"), Value: []byte("v")})
+			return err
+		},
+		"Txn": func() error {
+			_, err := srv.Txn(ctx, &amberpb.TxnRequest{})
+			return err
+		},
+	} {
+		if code := status.Code(call()); code != codes.Unavailable {
+			t.Errorf("%s forwarded twice: got code %v, want %v", name, code, codes.Unavailable)
+		}
+	}
+}
+
+func TestForwardWithoutLeader(t *testing.T) {
+	follower, store := startNode(t, "n2", freeAddr(t), nil)
+	srv := rpc.NewServer(store, follower, hlc.NewClock(), nil)
+	ctx := context.Background()
+
+	if _, err := srv.BeginTransaction(ctx, &amberpb.BeginTransactionRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("BeginTransaction without a leader: got %v, want code %v", err, codes.Unavailable)
+	}
+	resp, err := srv.Write(ctx, &amberpb.WriteRequest{Key: []byte("k"), Value: []byte("v"), TxId: "tx1"})
+	if err != nil || resp.Success || !strings.Contains(resp.Message, "no leader") {
+		t.Errorf("Write without a leader: got %v, %v", resp, err)
+	}
+}
+
+func TestGRPCAddr(t *testing.T) {
+	grpcAddrs := map[string]string{
+		"127.0.0.1:9001": "127.0.0.1:50051",
+		"localhost:9002": "localhost:50052",
+	}
+	for _, tt := range []struct {
+		raftAddr string
+		want     string
+		ok       bool
+	}{
+		{"127.0.0.1:9001", "127.0.0.1:50051", true},
+		{"127.0.0.1:9002", "localhost:50052", true}, // raft reports resolved addresses
+		{"127.0.0.1:9003", "", false},
+	} {
+		got, ok := rpc.GRPCAddr(grpcAddrs, tt.raftAddr)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("GRPCAddr(%q): got %q, %v, want %q, %v", tt.raftAddr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// internal/rpc/history.go
+package rpc
+
+import (
+	"context"
+	"log"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) GetHistory(ctx context.Context, req *amberpb.GetHistoryRequest) (*amberpb.GetHistoryResponse, error) {
+	forward, err := s.checkConsistency("GetHistory", req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+	if forward {
+		ctx, client, err := s.forwarder.leaderContext(ctx)
+		if err != nil {
+			log.Printf("GetHistory forward error: %v", err)
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return client.GetHistory(ctx, req)
+	}
+
+	// Versions come newest first, ordered by timestamp and then transaction
+	// ID, so a page resumes below the last version returned
+	to, lastTs, lastTx := req.EndTimestamp, "", ""
+	if req.ContinuationToken != "" {
+		lastTs, lastTx, err = decodeToken(req.ContinuationToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if to == "" || lastTs < to {
+			to = lastTs
+		}
+	}
+
+	resp := &amberpb.GetHistoryResponse{}
+	err = s.store.History(string(req.Key), req.StartTimestamp, to, func(v kvstore.Version) bool {
+		if v.Timestamp == lastTs && v.TxID >= lastTx {
+			return true
+		}
+		if req.Limit > 0 && len(resp.Versions) == int(req.Limit) {
+			prev := resp.Versions[len(resp.Versions)-1]
+			// Several transactions may write a key at one timestamp, so the
+			// token records the transaction ID of the last version too
+			resp.ContinuationToken = encodeToken(prev.Timestamp, prev.TxId)
+			return false
+		}
+		resp.Versions = append(resp.Versions, &amberpb.KeyVersion{
+			Value:     []byte(v.Value),
+			Timestamp: v.Timestamp,
+			TxId:      v.TxID,
+			Tombstone: v.Tombstone,
+			ExpiresAt: v.ExpiresAt,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, readError("GetHistory", err)
+	}
+	return resp, nil
+}
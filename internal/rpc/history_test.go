@@ -0,0 +1,79 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetHistoryPages(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "k", "v1")
+	// Three transactions writing the key at one timestamp
+	mustCommit(t, store, "txA", "020", "k", "a")
+	mustCommit(t, store, "txC", "020", "k", "c")
+	mustCommit(t, store, "txB", "020", "k", "b")
+	mustCommit(t, store, "tx5", "030", "k", "v5")
+	srv := rpc.NewLocalServer(store)
+
+	tests := []struct {
+		name  string
+		end   string
+		limit uint32
+		want  []string
+	}{
+		{"one per page", "", 1, []string{"v5@030", "c@020", "b@020", "a@020", "v1@010"}},
+		{"pages split a timestamp", "", 2, []string{"v5@030", "c@020", "b@020", "a@020", "v1@010"}},
+		{"bounded", "020", 2, []string{"c@020", "b@020", "a@020", "v1@010"}},
+		{"no limit", "", 0, []string{"v5@030", "c@020", "b@020", "a@020", "v1@010"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &amberpb.GetHistoryRequest{Key: []byte("k"), EndTimestamp: tt.end, Limit: tt.limit, Consistency: amberpb.ReadConsistency_STALE}
+			var got []string
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatalf("too many pages, got %v so far", got)
+				}
+				resp, err := srv.GetHistory(context.Background(), req)
+				if err != nil {
+					t.Fatalf("GetHistory error: %v", err)
+				}
+				if tt.limit > 0 && len(resp.Versions) > int(tt.limit) {
+					t.Errorf("page of %d versions exceeds limit %d", len(resp.Versions), tt.limit)
+				}
+				for _, v := range resp.Versions {
+					got = append(got, fmt.Sprintf("%s@%s", v.Value, v.Timestamp))
+				}
+				if resp.ContinuationToken == "" {
+					break
+				}
+				req.ContinuationToken = resp.ContinuationToken
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHistoryMalformedToken(t *testing.T) {
+	srv := rpc.NewLocalServer(newTestStore(t))
+	for _, token := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("020")),
+		base64.RawURLEncoding.EncodeToString([]byte(":txA")),
+	} {
+		req := &amberpb.GetHistoryRequest{Key: []byte("k"), ContinuationToken: token, Consistency: amberpb.ReadConsistency_STALE}
+		if _, err := srv.GetHistory(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("token %q: expected InvalidArgument, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package rpc_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	"github.com/hashicorp/raft"
+)
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// startNode starts Raft node id at addr, bootstrapping peers unless empty
+func startNode(t *testing.T, id, addr string, peers []raft.Server) (*raftstore.Store, *kvstore.Store) {
+	t.Helper()
+	store := newTestStore(t)
+	opts := raftstore.Options{
+		HeartbeatTimeout:   100 * time.Millisecond,
+		ElectionTimeout:    100 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+		CommitTimeout:      5 * time.Millisecond,
+	}
+	node, err := raftstore.NewRaftNode(t.TempDir(), id, addr, addr, peers, raftstore.NewFSM(store), opts)
+	if err != nil {
+		t.Fatalf("NewRaftNode error: %v", err)
+	}
+	t.Cleanup(func() { node.Shutdown() })
+	return node, store
+}
+
+// startLeader bootstraps a one-node cluster of n1 on a free local port and
+// waits for it to lead
+func startLeader(t *testing.T) (*raftstore.Store, *kvstore.Store) {
+	t.Helper()
+	addr := freeAddr(t)
+	node, store := startNode(t, "n1", addr, []raft.Server{{ID: "n1", Address: raft.ServerAddress(addr), Suffrage: raft.Voter}})
+	waitLeader(t, node)
+	return node, store
+}
+
+// waitLeader waits for node to lead
+func waitLeader(t *testing.T, node *raftstore.Store) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("node %s did not become leader", node.ID())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
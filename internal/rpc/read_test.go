@@ -0,0 +1,77 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+)
+
+func TestReadExists(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "empty", "")
+	srv := rpc.NewLocalServer(store)
+
+	for _, tt := range []struct {
+		key  string
+		want bool
+	}{
+		{"empty", true},
+		{"missing", false},
+	} {
+		resp, err := srv.Read(context.Background(), &amberpb.ReadRequest{Key: []byte(tt.key), Consistency: amberpb.ReadConsistency_STALE})
+		if err != nil {
+			t.Fatalf("Read(%q) error: %v", tt.key, err)
+		}
+		if resp.Exists != tt.want || len(resp.Value) != 0 {
+			t.Errorf("Read(%q): got exists %v, value %q, want exists %v", tt.key, resp.Exists, resp.Value, tt.want)
+		}
+	}
+}
+
+func TestReadOwnWrites(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "k", "committed")
+	for _, txID := range []string{"tx2", "tx3"} {
+		if err := store.Begin(txID, "015", kvstore.SnapshotIsolation, "node1"); err != nil {
+			t.Fatalf("Begin error: %v", err)
+		}
+	}
+	if err := store.WriteWithTimestamp("k", "pending", "tx2", "020"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	srv := rpc.NewLocalServer(store)
+
+	for txID, want := range map[string]string{"": "committed", "tx2": "pending", "tx3": "committed"} {
+		resp, err := srv.Read(context.Background(), &amberpb.ReadRequest{Key: []byte("k"), TxId: txID, Consistency: amberpb.ReadConsistency_STALE})
+		if err != nil {
+			t.Fatalf("Read in %q error: %v", txID, err)
+		}
+		if string(resp.Value) != want {
+			t.Errorf("Read in %q: got %q, want %q", txID, resp.Value, want)
+		}
+	}
+}
+
+func TestReadAtTransactionStart(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "k", "old")
+	if err := store.Begin("tx2", "015", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	mustCommit(t, store, "tx3", "020", "k", "new")
+	srv := rpc.NewLocalServer(store)
+
+	// Without a read timestamp the transaction reads its snapshot
+	for txID, want := range map[string]string{"tx2": "old", "": "new"} {
+		resp, err := srv.Read(context.Background(), &amberpb.ReadRequest{Key: []byte("k"), TxId: txID, Consistency: amberpb.ReadConsistency_STALE})
+		if err != nil {
+			t.Fatalf("Read in %q error: %v", txID, err)
+		}
+		if string(resp.Value) != want {
+			t.Errorf("Read in %q: got %q, want %q", txID, resp.Value, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// internal/rpc/readset.go
+package rpc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readSets records what the SERIALIZABLE transactions begun on this node
+// read, to be validated when they commit. They live in the memory of the
+// leader only: a transaction whose leader changed before it committed cannot
+// tell what it read, and is aborted.
+type readSets struct {
+	mu   sync.Mutex
+	sets map[string]*readSet
+}
+
+type readSet struct {
+	startTs string
+	ranges  []kvstore.KeyRange
+}
+
+func newReadSets() *readSets {
+	return &readSets{sets: make(map[string]*readSet)}
+}
+
+// begin starts tracking the reads of txID, begun at startTs. The sets of
+// transactions begun at or before threshold, whose records GC collected,
+// are dropped.
+func (r *readSets) begin(txID, startTs, threshold string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if threshold != "" {
+		for id, set := range r.sets {
+			if set.startTs <= threshold {
+				delete(r.sets, id)
+			}
+		}
+	}
+	r.sets[txID] = &readSet{startTs: startTs}
+}
+
+// tracked reports whether the reads of txID are tracked
+func (r *readSets) tracked(txID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.sets[txID]
+	return ok
+}
+
+// add records that txID read rng, if its reads are tracked
+func (r *readSets) add(txID string, rng kvstore.KeyRange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if set, ok := r.sets[txID]; ok {
+		set.ranges = append(set.ranges, rng)
+	}
+}
+
+// get returns the ranges read by txID so far
+func (r *readSets) get(txID string) ([]kvstore.KeyRange, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	set, ok := r.sets[txID]
+	if !ok {
+		return nil, false
+	}
+	return append([]kvstore.KeyRange(nil), set.ranges...), true
+}
+
+// end stops tracking the reads of txID
+func (r *readSets) end(txID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sets, txID)
+}
+
+// forwardTxnRead reports whether a read in transaction txID must be served
+// by the leader: the reads of a SERIALIZABLE transaction are tracked there,
+// and a node that has not applied the start of txID cannot tell its isolation.
+func (s *server) forwardTxnRead(txID string) (bool, error) {
+	if txID == "" {
+		return false, nil
+	}
+	rec, ok, err := s.store.Transaction(txID)
+	if err != nil {
+		return false, readError("Transaction", err)
+	}
+	if ok && rec.Isolation != kvstore.Serializable {
+		return false, nil
+	}
+	return !s.raftStore.IsLeader(), nil
+}
+
+// txnSnapshot returns the timestamp to read at: requested if set, else the
+// start timestamp of transaction txID, else the current HLC time. It reports
+// serializable when the read must be recorded in the read set of txID, which
+// only reads its snapshot.
+func (s *server) txnSnapshot(requested, txID string) (readTs string, serializable bool, err error) {
+	if txID != "" {
+		rec, ok, err := s.store.Transaction(txID)
+		if err != nil {
+			return "", false, readError("Transaction", err)
+		}
+		if ok && rec.Isolation == kvstore.Serializable {
+			if requested != "" && requested != rec.StartTimestamp {
+				return "", false, status.Errorf(codes.InvalidArgument, "SERIALIZABLE transaction %s can only read at its start timestamp %s", txID, rec.StartTimestamp)
+			}
+			if !s.reads.tracked(txID) {
+				return "", false, status.Error(codes.Aborted, readsLost(txID))
+			}
+			return rec.StartTimestamp, true, nil
+		}
+		if ok && requested == "" {
+			return rec.StartTimestamp, false, nil
+		}
+	}
+	if requested != "" {
+		return requested, false, nil
+	}
+	return s.clock.Now(), false, nil
+}
+
+// readsLost describes the failure of a SERIALIZABLE transaction whose reads
+// this node did not track
+func readsLost(txID string) string {
+	return fmt.Sprintf("%v: transaction %s must be retried, its reads were not tracked by the current leader", kvstore.ErrSerialization, txID)
+}
+
+// isolation converts the isolation level of a BeginTransaction request
+func isolation(level amberpb.IsolationLevel) (kvstore.Isolation, error) {
+	switch level {
+	case amberpb.IsolationLevel_SNAPSHOT:
+		return kvstore.SnapshotIsolation, nil
+	case amberpb.IsolationLevel_SERIALIZABLE:
+		return kvstore.Serializable, nil
+	default:
+		return 0, status.Errorf(codes.InvalidArgument, "unknown isolation level %v", level)
+	}
+}
@@ -0,0 +1,137 @@
+// internal/rpc/scan.go
+package rpc
+
+import (
+	"io"
+	"log"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) Scan(req *amberpb.ScanRequest, stream amberpb.AmberService_ScanServer) error {
+	forward, err := s.checkConsistency("Scan", req.Consistency)
+	if err == nil && !forward {
+		forward, err = s.forwardTxnRead(req.TxId)
+	}
+	if err != nil {
+		return err
+	}
+	if forward {
+		return s.forwardScan(req, stream)
+	}
+
+	start, end := scanRange(req)
+	readTs := req.ReadTimestamp
+	if req.ContinuationToken != "" {
+		var after string
+		readTs, after, err = decodeToken(req.ContinuationToken)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		// Resume just past the last key returned
+		if req.Reverse {
+			if end == "" || after < end {
+				end = after
+			}
+		} else if next := after + "\x00"; next > start {
+			start = next
+		}
+	}
+	readTs, serializable, err := s.txnSnapshot(readTs, req.TxId)
+	if err != nil {
+		return err
+	}
+	if end != "" && start >= end {
+		return nil
+	}
+
+	// Hold back one version so the last response can carry the continuation
+	// token when more keys remain past the limit
+	var (
+		held    *amberpb.ScanResponse
+		sent    uint32
+		sendErr error
+	)
+	err = s.store.ScanInTxn(start, end, req.TxId, readTs, req.Reverse, func(v kvstore.Version) bool {
+		if held != nil {
+			if req.Limit > 0 && sent+1 == req.Limit {
+				// The token records the snapshot and the last key returned
+				held.ContinuationToken = encodeToken(readTs, string(held.Key))
+				return false
+			}
+			if sendErr = stream.Send(held); sendErr != nil {
+				return false
+			}
+			sent++
+		}
+		held = &amberpb.ScanResponse{Key: []byte(v.Key), Value: []byte(v.Value), Timestamp: v.Timestamp, ReadTimestamp: readTs}
+		return true
+	})
+	if err != nil {
+		return readError("Scan", err)
+	}
+	if serializable {
+		// Only the keys up to the last one returned were read when the
+		// limit cut the scan short
+		read := kvstore.KeyRange{Start: start, End: end}
+		if held != nil && held.ContinuationToken != "" {
+			if req.Reverse {
+				read.Start = string(held.Key)
+			} else {
+				read.End = string(held.Key) + "\x00"
+			}
+		}
+		s.reads.add(req.TxId, read)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if held != nil {
+		return stream.Send(held)
+	}
+	return nil
+}
+
+// forwardScan relays a scan to the leader and streams its responses back
+func (s *server) forwardScan(req *amberpb.ScanRequest, stream amberpb.AmberService_ScanServer) error {
+	ctx, client, err := s.forwarder.leaderContext(stream.Context())
+	if err != nil {
+		log.Printf("Scan forward error: %v", err)
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	upstream, err := client.Scan(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := upstream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// scanRange turns the key bounds and prefix of req into a [start, end) range
+func scanRange(req *amberpb.ScanRequest) (start, end string) {
+	start, end = string(req.StartKey), string(req.EndKey)
+	prefix := string(req.Prefix)
+	if prefix == "" {
+		return start, end
+	}
+	if prefix > start {
+		start = prefix
+	}
+	if prefixEnd := kvstore.PrefixEnd(prefix); prefixEnd != "" && (end == "" || prefixEnd < end) {
+		end = prefixEnd
+	}
+	return start, end
+}
@@ -0,0 +1,163 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scanStream collects the responses of a Scan
+type scanStream struct {
+	grpc.ServerStream
+	responses []*amberpb.ScanResponse
+}
+
+func (s *scanStream) Context() context.Context { return context.Background() }
+
+func (s *scanStream) Send(resp *amberpb.ScanResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+// scanPages runs req page by page until no continuation token is returned,
+// checking every page against the limit. It returns the keys and the number
+// of pages.
+func scanPages(t *testing.T, srv amberpb.AmberServiceServer, req *amberpb.ScanRequest, between func()) ([]string, int) {
+	t.Helper()
+	var keys []string
+	for pages := 1; ; pages++ {
+		if pages > 20 {
+			t.Fatalf("too many pages, got %q so far", keys)
+		}
+		stream := &scanStream{}
+		if err := srv.Scan(req, stream); err != nil {
+			t.Fatalf("Scan error: %v", err)
+		}
+		if req.Limit > 0 && len(stream.responses) > int(req.Limit) {
+			t.Errorf("page of %d keys exceeds limit %d", len(stream.responses), req.Limit)
+		}
+		token := ""
+		for i, resp := range stream.responses {
+			keys = append(keys, string(resp.Key))
+			if resp.ContinuationToken != "" && i != len(stream.responses)-1 {
+				t.Errorf("continuation token on response %d of %d", i+1, len(stream.responses))
+			}
+			token = resp.ContinuationToken
+		}
+		if token == "" {
+			return keys, pages
+		}
+		req.ContinuationToken = token
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func TestScanContinuationToken(t *testing.T) {
+	store := newTestStore(t)
+	for i, key := range []string{"a", "p", "p\x00", "p1", "p\xff", "p\xff\xff", "q"} {
+		mustCommit(t, store, "tx"+key, "0"+string(rune('1'+i))+"0", key, "v")
+	}
+	srv := rpc.NewLocalServer(store)
+
+	tests := []struct {
+		name      string
+		req       *amberpb.ScanRequest
+		want      []string
+		wantPages int
+	}{
+		{
+			name:      "forward",
+			req:       &amberpb.ScanRequest{Limit: 2},
+			want:      []string{"a", "p", "p\x00", "p1", "p\xff", "p\xff\xff", "q"},
+			wantPages: 4,
+		},
+		{
+			name:      "reverse",
+			req:       &amberpb.ScanRequest{Limit: 3, Reverse: true},
+			want:      []string{"q", "p\xff\xff", "p\xff", "p1", "p\x00", "p", "a"},
+			wantPages: 3,
+		},
+		{
+			name:      "range",
+			req:       &amberpb.ScanRequest{StartKey: []byte("p\x00"), EndKey: []byte("p\xff\xff"), Limit: 1},
+			want:      []string{"p\x00", "p1", "p\xff"},
+			wantPages: 3,
+		},
+		{
+			// The limit runs out on the last key of the prefix, so no token
+			name:      "prefix ends with the limit",
+			req:       &amberpb.ScanRequest{Prefix: []byte("p"), Limit: 5},
+			want:      []string{"p", "p\x00", "p1", "p\xff", "p\xff\xff"},
+			wantPages: 1,
+		},
+		{
+			name:      "prefix ending in 0xff",
+			req:       &amberpb.ScanRequest{Prefix: []byte("p\xff"), Limit: 1},
+			want:      []string{"p\xff", "p\xff\xff"},
+			wantPages: 2,
+		},
+		{
+			name:      "reverse prefix",
+			req:       &amberpb.ScanRequest{Prefix: []byte("p"), Limit: 2, Reverse: true},
+			want:      []string{"p\xff\xff", "p\xff", "p1", "p\x00", "p"},
+			wantPages: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Consistency = amberpb.ReadConsistency_STALE
+			keys, pages := scanPages(t, srv, tt.req, nil)
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("got keys %q, want %q", keys, tt.want)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("got %d pages, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestScanContinuationKeepsSnapshot(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "a", "v")
+	mustCommit(t, store, "tx2", "010", "b", "v")
+	srv := rpc.NewLocalServer(store)
+
+	// Keys committed after the first page are past the snapshot
+	req := &amberpb.ScanRequest{Limit: 1, ReadTimestamp: "020", Consistency: amberpb.ReadConsistency_STALE}
+	keys, _ := scanPages(t, srv, req, func() {
+		mustCommit(t, store, "tx3", "030", "c", "v")
+	})
+	if want := []string{"a", "b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+}
+
+func TestScanMalformedToken(t *testing.T) {
+	srv := rpc.NewLocalServer(newTestStore(t))
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not base64!"},
+		{"no separator", base64.RawURLEncoding.EncodeToString([]byte("020"))},
+		{"no timestamp", base64.RawURLEncoding.EncodeToString([]byte(":a"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &amberpb.ScanRequest{ContinuationToken: tt.token, Consistency: amberpb.ReadConsistency_STALE}
+			if err := srv.Scan(req, &scanStream{}); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@
 package rpc
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
+	"errors"
+	"io"
 	"log"
 	"time"
 
@@ -13,6 +13,8 @@ import (
 	"github.com/dishankoza/amberdb/internal/raftstore"
 	amberpb "github.com/dishankoza/amberdb/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type server struct {
@@ -20,101 +22,407 @@ type server struct {
 	store     *kvstore.Store
 	raftStore *raftstore.Store
 	clock     *hlc.Clock
+	forwarder *forwarder
+	reads     *readSets
 }
 
-func RegisterAmberService(grpcServer *grpc.Server, store *kvstore.Store, raftStore *raftstore.Store) {
-	// Initialize HLC clock for reads
-	clock := hlc.NewClock()
-	amberpb.RegisterAmberServiceServer(grpcServer, &server{store: store, raftStore: raftStore, clock: clock})
+// RegisterAmberService registers the key-value service. Timestamps are
+// issued by clock, which the FSM keeps past every replicated write. Followers
+// forward writes to the gRPC address the leader registered in the replicated
+// node directory, or else to the one grpcAddrs maps its raft address to. The
+// returned Closer closes the connections to leaders once the server stopped.
+func RegisterAmberService(grpcServer *grpc.Server, store *kvstore.Store, raftStore *raftstore.Store, clock *hlc.Clock, grpcAddrs map[string]string) io.Closer {
+	forwarder := newForwarder(raftStore, store, grpcAddrs)
+	amberpb.RegisterAmberServiceServer(grpcServer, &server{
+		store:     store,
+		raftStore: raftStore,
+		clock:     clock,
+		forwarder: forwarder,
+		reads:     newReadSets(),
+	})
+	return forwarder
 }
 
-func (s *server) BeginTransaction(ctx context.Context, _ *amberpb.Empty) (*amberpb.TxnID, error) {
+// checkDraining refuses new work on a node in maintenance mode. Commit and
+// Abort stay allowed so that transactions begun before the drain can finish.
+func (s *server) checkDraining() error {
+	if s.raftStore.IsDraining() {
+		return status.Error(codes.Unavailable, "node is draining")
+	}
+	return nil
+}
+
+func (s *server) BeginTransaction(ctx context.Context, req *amberpb.BeginTransactionRequest) (*amberpb.TxnID, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
+	level, err := isolation(req.Isolation)
+	if err != nil {
+		return nil, err
+	}
+	if !s.raftStore.IsLeader() {
+		ctx, client, err := s.forwarder.leaderContext(ctx)
+		if err != nil {
+			log.Printf("BeginTransaction forward error: %v", err)
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return client.BeginTransaction(ctx, req)
+	}
+
+	// The start timestamp and the commits before it are recorded through
+	// Raft, so that every replica can check the commit for conflicts
 	txID := s.store.BeginTransaction()
-	return &amberpb.TxnID{Id: txID}, nil
+	startTs := s.clock.Now()
+	cmd := raftstore.Command{Op: raftstore.OpBegin, Key: s.raftStore.ID(), TxID: txID, Timestamp: startTs, Isolation: level}
+	if _, err := s.propose("BeginTransaction", cmd); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if level == kvstore.Serializable {
+		s.reads.begin(txID, startTs, s.store.GCThreshold())
+	}
+	return &amberpb.TxnID{Id: txID, StartTimestamp: startTs}, nil
 }
 
 func (s *server) Write(ctx context.Context, req *amberpb.WriteRequest) (*amberpb.Status, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
 	if !s.raftStore.IsLeader() {
-		log.Printf("Write rejected: not the leader")
-		return &amberpb.Status{Success: false, Message: "not the leader"}, nil
+		return s.forwarder.forward(ctx, "Write", func(ctx context.Context, c amberpb.AmberServiceClient) (*amberpb.Status, error) {
+			return c.Write(ctx, req)
+		})
+	}
+
+	if req.TtlMs < 0 {
+		return &amberpb.Status{Success: false, Message: "ttl_ms must not be negative"}, nil
+	}
+	if _, err := s.pendingTxn(req.TxId, "write to"); err != nil {
+		return nil, err
 	}
 
 	// Use HLC timestamp for ordering
 	ts := s.clock.Now()
+	expiresAt, err := expiry(ts, req.TtlMs)
+	if err != nil {
+		return &amberpb.Status{Success: false, Message: err.Error()}, nil
+	}
 	cmd := raftstore.Command{
-		Op:        "WRITE",
-		Key:       req.Key,
-		Value:     req.Value,
+		Op:        raftstore.OpWrite,
+		Key:       string(req.Key),
+		Value:     string(req.Value),
 		TxID:      req.TxId,
 		Timestamp: ts,
+		ExpiresAt: expiresAt,
 	}
 
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(cmd); err != nil {
-		log.Printf("Encode error: %v", err)
-		return &amberpb.Status{Success: false, Message: "encoding failed"}, nil
+	if _, err := s.propose("Write", cmd); err != nil {
+		return txnFailure(err)
 	}
+	return &amberpb.Status{Success: true, Message: "OK"}, nil
+}
 
-	applyFuture := s.raftStore.Apply(buf.Bytes(), 5*time.Second)
-	if err := applyFuture.Error(); err != nil {
-		log.Printf("Raft apply error: %v", err)
-		return &amberpb.Status{Success: false, Message: "raft apply failed"}, nil
+func (s *server) WriteBatch(ctx context.Context, req *amberpb.WriteBatchRequest) (*amberpb.Status, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
+	if !s.raftStore.IsLeader() {
+		return s.forwarder.forward(ctx, "WriteBatch", func(ctx context.Context, c amberpb.AmberServiceClient) (*amberpb.Status, error) {
+			return c.WriteBatch(ctx, req)
+		})
+	}
+	if len(req.Pairs) == 0 {
+		return &amberpb.Status{Success: false, Message: "empty batch"}, nil
+	}
+	if _, err := s.pendingTxn(req.TxId, "write to"); err != nil {
+		return nil, err
 	}
 
+	batch := make([]kvstore.KeyValue, 0, len(req.Pairs))
+	for _, p := range req.Pairs {
+		batch = append(batch, kvstore.KeyValue{Key: string(p.Key), Value: string(p.Value)})
+	}
+	// One HLC timestamp and one Raft entry for the whole batch
+	cmd := raftstore.Command{
+		Op:        raftstore.OpWriteBatch,
+		TxID:      req.TxId,
+		Timestamp: s.clock.Now(),
+		Batch:     batch,
+	}
+	if _, err := s.propose("WriteBatch", cmd); err != nil {
+		return txnFailure(err)
+	}
 	return &amberpb.Status{Success: true, Message: "OK"}, nil
 }
 
+func (s *server) Delete(ctx context.Context, req *amberpb.DeleteRequest) (*amberpb.Status, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
+	if !s.raftStore.IsLeader() {
+		return s.forwarder.forward(ctx, "Delete", func(ctx context.Context, c amberpb.AmberServiceClient) (*amberpb.Status, error) {
+			return c.Delete(ctx, req)
+		})
+	}
+
+	if _, err := s.pendingTxn(req.TxId, "write to"); err != nil {
+		return nil, err
+	}
+	// The tombstone is a version like any write, ordered by HLC
+	cmd := raftstore.Command{
+		Op:        raftstore.OpDelete,
+		Key:       string(req.Key),
+		TxID:      req.TxId,
+		Timestamp: s.clock.Now(),
+	}
+	if _, err := s.propose("Delete", cmd); err != nil {
+		return txnFailure(err)
+	}
+	return &amberpb.Status{Success: true, Message: "OK"}, nil
+}
+
+func (s *server) CompareAndSwap(ctx context.Context, req *amberpb.CompareAndSwapRequest) (*amberpb.CompareAndSwapResponse, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
+	if !s.raftStore.IsLeader() {
+		ctx, client, err := s.forwarder.leaderContext(ctx)
+		if errors.Is(err, errAlreadyForwarded) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if err != nil {
+			log.Printf("CompareAndSwap forward error: %v", err)
+			return &amberpb.CompareAndSwapResponse{Success: false, Message: err.Error()}, nil
+		}
+		return client.CompareAndSwap(ctx, req)
+	}
+
+	var cond kvstore.Condition
+	switch c := req.Condition.(type) {
+	case *amberpb.CompareAndSwapRequest_ExpectedValue:
+		cond = kvstore.Condition{Kind: kvstore.ValueEquals, Value: string(c.ExpectedValue)}
+	case *amberpb.CompareAndSwapRequest_ExpectedVersion:
+		cond = kvstore.Condition{Kind: kvstore.VersionEquals, Version: c.ExpectedVersion}
+	case *amberpb.CompareAndSwapRequest_MustNotExist:
+		if !c.MustNotExist {
+			return &amberpb.CompareAndSwapResponse{Success: false, Message: "must_not_exist must be true when set"}, nil
+		}
+		cond = kvstore.Condition{Kind: kvstore.NotExists}
+	default:
+		return &amberpb.CompareAndSwapResponse{Success: false, Message: "missing condition"}, nil
+	}
+	if req.TtlMs < 0 {
+		return &amberpb.CompareAndSwapResponse{Success: false, Message: "ttl_ms must not be negative"}, nil
+	}
+
+	// The condition is evaluated by the FSM, so every replica agrees on the
+	// outcome no matter what it saw when the request arrived
+	txID := s.store.BeginTransaction()
+	resp, _, err := s.applyFresh("CompareAndSwap", func(ts string) (raftstore.Command, error) {
+		expiresAt, err := expiry(ts, req.TtlMs)
+		if err != nil {
+			return raftstore.Command{}, err
+		}
+		return raftstore.Command{
+			Op:        raftstore.OpCAS,
+			Key:       string(req.Key),
+			Value:     string(req.Value),
+			TxID:      txID,
+			Timestamp: ts,
+			Condition: &cond,
+			ExpiresAt: expiresAt,
+		}, nil
+	})
+	if err != nil {
+		return &amberpb.CompareAndSwapResponse{Success: false, Message: err.Error()}, nil
+	}
+	result, ok := resp.(kvstore.CASResult)
+	if !ok {
+		log.Printf("CompareAndSwap unexpected apply response: %v", resp)
+		return nil, status.Error(codes.Internal, "unexpected apply response")
+	}
+	out := &amberpb.CompareAndSwapResponse{
+		Success:         result.Succeeded,
+		ConditionFailed: !result.Succeeded,
+		Exists:          result.Exists,
+		CurrentValue:    []byte(result.Current.Value),
+		CurrentVersion:  result.Current.Timestamp,
+	}
+	if result.Succeeded {
+		out.Message = "OK"
+	} else {
+		out.Message = "condition failed"
+	}
+	return out, nil
+}
+
 func (s *server) Read(ctx context.Context, req *amberpb.ReadRequest) (*amberpb.ReadResponse, error) {
-	// If client did not supply read_timestamp, use HLC.Now()
-	readTs := req.ReadTimestamp
-	if readTs == "" {
-		readTs = s.clock.Now()
+	forward, err := s.checkConsistency("Read", req.Consistency)
+	if err == nil && !forward {
+		forward, err = s.forwardTxnRead(req.TxId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if forward {
+		ctx, client, err := s.forwarder.leaderContext(ctx)
+		if err != nil {
+			log.Printf("Read forward error: %v", err)
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return client.Read(ctx, req)
 	}
-	// Follower reads allowed: we read local store directly
-	val, err := s.store.Read(req.Key, readTs)
+
+	readTs, serializable, err := s.txnSnapshot(req.ReadTimestamp, req.TxId)
 	if err != nil {
-		log.Printf("Read error: %v", err)
 		return nil, err
 	}
-	return &amberpb.ReadResponse{Value: val}, nil
+	v, exists, err := s.store.GetInTxn(string(req.Key), req.TxId, readTs)
+	if err != nil {
+		return nil, readError("Read", err)
+	}
+	if serializable {
+		s.reads.add(req.TxId, kvstore.PointRange(string(req.Key)))
+	}
+	return &amberpb.ReadResponse{Value: []byte(v.Value), Exists: exists}, nil
+}
+
+// checkConsistency prepares a local read at the requested consistency. It
+// reports forward when the read must be served by the leader instead.
+func (s *server) checkConsistency(name string, consistency amberpb.ReadConsistency) (forward bool, err error) {
+	switch consistency {
+	case amberpb.ReadConsistency_STALE:
+		// Follower reads allowed: we read local store directly
+	case amberpb.ReadConsistency_LEASE, amberpb.ReadConsistency_LINEARIZABLE:
+		if !s.raftStore.IsLeader() {
+			return true, nil
+		}
+		verify := s.raftStore.VerifyRead
+		if consistency == amberpb.ReadConsistency_LEASE {
+			verify = s.raftStore.VerifyLease
+		}
+		if err := verify(s.raftStore.ApplyTimeout()); err != nil {
+			log.Printf("%s verify error: %v", name, err)
+			return false, status.Error(codes.Unavailable, err.Error())
+		}
+	default:
+		return false, status.Errorf(codes.InvalidArgument, "unknown read consistency %v", consistency)
+	}
+	return false, nil
+}
+
+// readError converts a store read error to a gRPC status
+func readError(name string, err error) error {
+	if errors.Is(err, kvstore.ErrBelowGCThreshold) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Printf("%s error: %v", name, err)
+	return status.Error(codes.Internal, err.Error())
 }
 
 func (s *server) Commit(ctx context.Context, req *amberpb.TxnID) (*amberpb.Status, error) {
 	if !s.raftStore.IsLeader() {
-		log.Printf("Commit rejected: not the leader")
-		return &amberpb.Status{Success: false, Message: "not the leader"}, nil
+		return s.forwarder.forward(ctx, "Commit", func(ctx context.Context, c amberpb.AmberServiceClient) (*amberpb.Status, error) {
+			return c.Commit(ctx, req)
+		})
 	}
-	// Replicate commit via Raft
-	cmd := raftstore.Command{Op: "COMMIT", TxID: req.Id}
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(cmd); err != nil {
-		log.Printf("Encode commit error: %v", err)
-		return &amberpb.Status{Success: false, Message: "encoding failed"}, nil
+	rec, err := s.pendingTxn(req.Id, "commit")
+	if err != nil {
+		return nil, err
 	}
-	applyFuture := s.raftStore.Apply(buf.Bytes(), 5*time.Second)
-	if err := applyFuture.Error(); err != nil {
-		log.Printf("Raft commit error: %v", err)
-		return &amberpb.Status{Success: false, Message: "raft apply failed"}, nil
+	// Replicate commit via Raft, along with what a SERIALIZABLE transaction
+	// read so that every replica validates it alike
+	cmd := raftstore.Command{Op: raftstore.OpCommit, TxID: req.Id, Timestamp: s.clock.Now()}
+	if rec.Isolation == kvstore.Serializable {
+		reads, tracked := s.reads.get(req.Id)
+		if !tracked {
+			if _, err := s.propose("Abort", raftstore.Command{Op: raftstore.OpAbort, TxID: req.Id}); err != nil {
+				return txnFailure(err)
+			}
+			return nil, status.Error(codes.Aborted, readsLost(req.Id))
+		}
+		cmd.ReadSet = reads
 	}
+	if _, err := s.propose("Commit", cmd); err != nil {
+		if errors.Is(err, kvstore.ErrConflict) || errors.Is(err, kvstore.ErrSerialization) {
+			s.reads.end(req.Id)
+		}
+		return txnFailure(err)
+	}
+	s.reads.end(req.Id)
 	return &amberpb.Status{Success: true, Message: "Committed"}, nil
 }
 
 func (s *server) Abort(ctx context.Context, req *amberpb.TxnID) (*amberpb.Status, error) {
 	if !s.raftStore.IsLeader() {
-		log.Printf("Abort rejected: not the leader")
-		return &amberpb.Status{Success: false, Message: "not the leader"}, nil
+		return s.forwarder.forward(ctx, "Abort", func(ctx context.Context, c amberpb.AmberServiceClient) (*amberpb.Status, error) {
+			return c.Abort(ctx, req)
+		})
+	}
+	if _, err := s.pendingTxn(req.Id, "abort"); err != nil {
+		return nil, err
 	}
 	// Replicate abort via Raft
-	cmd := raftstore.Command{Op: "ABORT", TxID: req.Id}
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(cmd); err != nil {
-		log.Printf("Encode abort error: %v", err)
-		return &amberpb.Status{Success: false, Message: "encoding failed"}, nil
+	cmd := raftstore.Command{Op: raftstore.OpAbort, TxID: req.Id}
+	if _, err := s.propose("Abort", cmd); err != nil {
+		return txnFailure(err)
 	}
-	applyFuture := s.raftStore.Apply(buf.Bytes(), 5*time.Second)
+	s.reads.end(req.Id)
+	return &amberpb.Status{Success: true, Message: "Aborted"}, nil
+}
+
+// expiry returns the HLC timestamp ttlMs milliseconds after ts, or "" if
+// ttlMs is 0
+func expiry(ts string, ttlMs int64) (string, error) {
+	if ttlMs == 0 {
+		return "", nil
+	}
+	return hlc.Add(ts, time.Duration(ttlMs)*time.Millisecond)
+}
+
+// propose encodes cmd, applies it through Raft and returns the FSM response.
+// Errors returned by the FSM are passed through; encoding and Raft failures
+// are logged and reported briefly.
+func (s *server) propose(name string, cmd raftstore.Command) (interface{}, error) {
+	data, err := raftstore.EncodeCommand(cmd)
+	if err != nil {
+		log.Printf("%s encode error: %v", name, err)
+		return nil, errors.New("encoding failed")
+	}
+	applyFuture := s.raftStore.Apply(data, s.raftStore.ApplyTimeout())
 	if err := applyFuture.Error(); err != nil {
-		log.Printf("Raft abort error: %v", err)
-		return &amberpb.Status{Success: false, Message: "raft apply failed"}, nil
+		log.Printf("%s raft apply error: %v", name, err)
+		return nil, errors.New("raft apply failed")
+	}
+	resp := applyFuture.Response()
+	if err, ok := resp.(error); ok && err != nil {
+		log.Printf("%s apply error: %v", name, err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// writeTooOldRetries bounds how often applyFresh proposes a command again
+const writeTooOldRetries = 3
+
+// applyFresh proposes the command built by build for a new HLC timestamp and
+// returns the FSM response and the timestamp used. If the FSM refuses it
+// with kvstore.ErrWriteTooOld, because a newer version was committed in the
+// meantime, the clock is moved past that version and the command is proposed
+// again, up to writeTooOldRetries times.
+func (s *server) applyFresh(name string, build func(ts string) (raftstore.Command, error)) (interface{}, string, error) {
+	for attempt := 0; ; attempt++ {
+		ts := s.clock.Now()
+		cmd, err := build(ts)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := s.propose(name, cmd)
+		var tooOld *kvstore.WriteTooOldError
+		if attempt == writeTooOldRetries || !errors.As(err, &tooOld) {
+			return resp, ts, err
+		}
+		if err := s.clock.Update(tooOld.Newer); err != nil {
+			return nil, "", err
+		}
 	}
-	return &amberpb.Status{Success: true, Message: "Aborted"}, nil
 }
@@ -0,0 +1,28 @@
+// internal/rpc/token.go
+package rpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// encodeToken builds the continuation token of a paged read from an HLC
+// timestamp and the position of the last item returned at it. HLC
+// timestamps never contain ':', so the first one separates the two.
+func encodeToken(ts, last string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ts + ":" + last))
+}
+
+// decodeToken splits a continuation token built by encodeToken
+func decodeToken(token string) (ts, last string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed continuation token")
+	}
+	ts, last, ok := strings.Cut(string(data), ":")
+	if !ok || ts == "" {
+		return "", "", fmt.Errorf("malformed continuation token")
+	}
+	return ts, last, nil
+}
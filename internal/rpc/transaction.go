@@ -0,0 +1,58 @@
+// internal/rpc/transaction.go
+package rpc
+
+import (
+	"errors"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pendingTxn returns the record of transaction txID before op, failing with
+// NOT_FOUND if it was never begun and FAILED_PRECONDITION if it already
+// committed or aborted. A new leader may not have applied the start of a
+// transaction yet, so it catches up before reporting one unknown. Must be
+// called on the leader.
+func (s *server) pendingTxn(txID, op string) (kvstore.TxnRecord, error) {
+	rec, err := s.store.PendingTransaction(txID, op)
+	if errors.Is(err, kvstore.ErrTxnNotFound) {
+		if err := s.raftStore.VerifyRead(s.raftStore.ApplyTimeout()); err != nil {
+			return rec, status.Error(codes.Unavailable, err.Error())
+		}
+		rec, err = s.store.PendingTransaction(txID, op)
+	}
+	if err != nil {
+		if txnErr := txnError(err); txnErr != nil {
+			return rec, txnErr
+		}
+		return rec, readError("Transaction", err)
+	}
+	return rec, nil
+}
+
+// txnError converts an error about the state of a transaction to a gRPC
+// status, or returns nil for any other error
+func txnError(err error) error {
+	switch {
+	case errors.Is(err, kvstore.ErrTxnNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, kvstore.ErrTxnCommitted), errors.Is(err, kvstore.ErrTxnAborted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, kvstore.ErrConflict), errors.Is(err, kvstore.ErrSerialization):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return nil
+}
+
+// txnFailure reports err, returned by proposing a write, commit or abort of
+// a transaction: the FSM may still refuse it because of the state of the
+// transaction, which fails like pendingTxn, and any other failure is an
+// unsuccessful status
+func txnFailure(err error) (*amberpb.Status, error) {
+	if txnErr := txnError(err); txnErr != nil {
+		return nil, txnErr
+	}
+	return &amberpb.Status{Success: false, Message: err.Error()}, nil
+}
@@ -0,0 +1,133 @@
+// internal/rpc/txn.go
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/raftstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) Txn(ctx context.Context, req *amberpb.TxnRequest) (*amberpb.TxnResponse, error) {
+	if err := s.checkDraining(); err != nil {
+		return nil, err
+	}
+	if !s.raftStore.IsLeader() {
+		ctx, client, err := s.forwarder.leaderContext(ctx)
+		if errors.Is(err, errAlreadyForwarded) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if err != nil {
+			log.Printf("Txn forward error: %v", err)
+			return &amberpb.TxnResponse{Success: false, Message: err.Error()}, nil
+		}
+		return client.Txn(ctx, req)
+	}
+
+	// Comparisons, reads and writes all happen at one HLC timestamp, and the
+	// whole transaction is a single Raft entry evaluated by the FSM
+	compares, err := txnCompares(req.Compare)
+	if err != nil {
+		return &amberpb.TxnResponse{Success: false, Message: err.Error()}, nil
+	}
+	txID := s.store.BeginTransaction()
+	var success, failure []kvstore.TxnOp
+	resp, ts, err := s.applyFresh("Txn", func(ts string) (raftstore.Command, error) {
+		// TTLs are relative to the timestamp, so the ops are built for each attempt
+		var err error
+		if success, err = txnOps(req.Success, ts); err != nil {
+			return raftstore.Command{}, err
+		}
+		if failure, err = txnOps(req.Failure, ts); err != nil {
+			return raftstore.Command{}, err
+		}
+		if err := kvstore.ValidateTxn(success, failure); err != nil {
+			return raftstore.Command{}, err
+		}
+		return raftstore.Command{
+			Op:        raftstore.OpTxn,
+			TxID:      txID,
+			Timestamp: ts,
+			Compares:  compares,
+			Success:   success,
+			Failure:   failure,
+		}, nil
+	})
+	if err != nil {
+		return &amberpb.TxnResponse{Success: false, Message: err.Error()}, nil
+	}
+	result, ok := resp.(kvstore.TxnResult)
+	if !ok {
+		log.Printf("Txn unexpected apply response: %v", resp)
+		return nil, status.Error(codes.Internal, "unexpected apply response")
+	}
+
+	ops := success
+	if !result.Succeeded {
+		ops = failure
+	}
+	out := &amberpb.TxnResponse{Success: true, Message: "OK", CompareSucceeded: result.Succeeded, Timestamp: ts}
+	for i, r := range result.Results {
+		out.Responses = append(out.Responses, &amberpb.TxnOpResponse{
+			Key:     []byte(ops[i].Key),
+			Exists:  r.Exists,
+			Value:   []byte(r.Current.Value),
+			Version: r.Current.Timestamp,
+		})
+	}
+	return out, nil
+}
+
+// txnCompares converts the comparisons of a TxnRequest
+func txnCompares(compares []*amberpb.TxnCompare) ([]kvstore.Comparison, error) {
+	var out []kvstore.Comparison
+	for i, c := range compares {
+		cmp := kvstore.Comparison{Key: string(c.Key)}
+		switch t := c.Target.(type) {
+		case *amberpb.TxnCompare_Value:
+			cmp.Condition = kvstore.Condition{Kind: kvstore.ValueEquals, Value: string(t.Value)}
+		case *amberpb.TxnCompare_Version:
+			cmp.Condition = kvstore.Condition{Kind: kvstore.VersionEquals, Version: t.Version}
+		case *amberpb.TxnCompare_Exists:
+			cmp.Condition = kvstore.Condition{Kind: kvstore.NotExists}
+			if t.Exists {
+				cmp.Condition.Kind = kvstore.Exists
+			}
+		default:
+			return nil, fmt.Errorf("compare %d has no target", i)
+		}
+		out = append(out, cmp)
+	}
+	return out, nil
+}
+
+// txnOps converts the ops of a Txn branch, resolving TTLs relative to ts
+func txnOps(ops []*amberpb.TxnOp, ts string) ([]kvstore.TxnOp, error) {
+	var out []kvstore.TxnOp
+	for i, op := range ops {
+		switch o := op.Op.(type) {
+		case *amberpb.TxnOp_Put:
+			if o.Put.TtlMs < 0 {
+				return nil, fmt.Errorf("op %d: ttl_ms must not be negative", i)
+			}
+			expiresAt, err := expiry(ts, o.Put.TtlMs)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, kvstore.TxnOp{Kind: kvstore.TxnPut, Key: string(o.Put.Key), Value: string(o.Put.Value), ExpiresAt: expiresAt})
+		case *amberpb.TxnOp_Delete:
+			out = append(out, kvstore.TxnOp{Kind: kvstore.TxnDelete, Key: string(o.Delete.Key)})
+		case *amberpb.TxnOp_Get:
+			out = append(out, kvstore.TxnOp{Kind: kvstore.TxnGet, Key: string(o.Get.Key)})
+		default:
+			return nil, fmt.Errorf("op %d is empty", i)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+// internal/rpc/watch.go
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultWatchProgressInterval = 10 * time.Second
+
+// Watch is served from the local replica: every node applies the same commits
+// in the same order, a follower just sees them a little later.
+func (s *server) Watch(req *amberpb.WatchRequest, stream amberpb.AmberService_WatchServer) error {
+	if len(req.Key) > 0 && len(req.Prefix) > 0 {
+		return status.Error(codes.InvalidArgument, "set at most one of key and prefix")
+	}
+	start, end := string(req.Prefix), kvstore.PrefixEnd(string(req.Prefix))
+	if len(req.Key) > 0 {
+		start, end = string(req.Key), string(req.Key)+"\x00"
+	}
+
+	if req.StartTimestamp != "" && req.StartSequence != 0 {
+		return status.Error(codes.InvalidArgument, "set at most one of start_timestamp and start_sequence")
+	}
+	var (
+		w   *kvstore.Watcher
+		err error
+	)
+	if req.StartTimestamp != "" {
+		w, err = s.store.WatchSince(start, end, req.StartTimestamp)
+	} else {
+		w, err = s.store.Watch(start, end, req.StartSequence)
+	}
+	if err != nil {
+		return watchError(err)
+	}
+	defer w.Close()
+
+	interval := defaultWatchProgressInterval
+	if req.ProgressIntervalMs > 0 {
+		interval = time.Duration(req.ProgressIntervalMs) * time.Millisecond
+	}
+	ctx := stream.Context()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.RequestProgress()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		ev, err := w.Next(ctx)
+		if err != nil {
+			return watchError(err)
+		}
+		resp := &amberpb.WatchResponse{Type: amberpb.WatchResponse_PROGRESS, Sequence: ev.Sequence}
+		if !ev.Progress {
+			v := ev.Version
+			resp = &amberpb.WatchResponse{
+				Type:      amberpb.WatchResponse_PUT,
+				Key:       []byte(v.Key),
+				Value:     []byte(v.Value),
+				Timestamp: v.Timestamp,
+				TxId:      v.TxID,
+				ExpiresAt: v.ExpiresAt,
+				Sequence:  ev.Sequence,
+			}
+			if v.Tombstone {
+				resp.Type = amberpb.WatchResponse_DELETE
+			}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// watchError converts the reason a watcher could not start or stopped to a
+// gRPC status. Clients can resume after the sequence of the last event they
+// received.
+func watchError(err error) error {
+	switch {
+	case errors.Is(err, kvstore.ErrWatchCompacted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, kvstore.ErrWatchLagging):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, kvstore.ErrWatchReset):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, kvstore.ErrWatchClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	log.Printf("Watch error: %v", err)
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,210 @@
+package rpc_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dishankoza/amberdb/internal/kvstore"
+	"github.com/dishankoza/amberdb/internal/rpc"
+	amberpb "github.com/dishankoza/amberdb/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestStore(t *testing.T) *kvstore.Store {
+	t.Helper()
+	store, err := kvstore.Open(kvstore.EngineBolt, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func mustCommit(t *testing.T, store *kvstore.Store, txID, ts, key, value string) {
+	t.Helper()
+	if err := store.Begin(txID, ts, kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin(%s) error: %v", txID, err)
+	}
+	if err := store.WriteWithTimestamp(key, value, txID, ts); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := store.Commit(txID); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+}
+
+// watchStream is a server stream that hands sent responses to the test
+type watchStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *amberpb.WatchResponse
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) Send(resp *amberpb.WatchResponse) error {
+	select {
+	case s.responses <- resp:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// startWatch runs the Watch RPC until the test ends and returns its stream
+// and the channel its result is sent on
+func startWatch(t *testing.T, srv amberpb.AmberServiceServer, req *amberpb.WatchRequest) (*watchStream, <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &watchStream{ctx: ctx, responses: make(chan *amberpb.WatchResponse)}
+	done := make(chan error, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		done <- srv.Watch(req, stream)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-exited
+	})
+	return stream, done
+}
+
+// receive returns the next response, skipping progress events unless
+// progress is set
+func receive(t *testing.T, stream *watchStream, progress bool) *amberpb.WatchResponse {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case resp := <-stream.responses:
+			if progress || resp.Type != amberpb.WatchResponse_PROGRESS {
+				return resp
+			}
+		case <-timeout:
+			t.Fatal("no watch response")
+		}
+	}
+}
+
+func describe(resp *amberpb.WatchResponse) string {
+	return fmt.Sprintf("%s %s=%s@%s#%d", resp.Type, resp.Key, resp.Value, resp.Timestamp, resp.Sequence)
+}
+
+func TestWatch(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "a1", "old")
+	mustCommit(t, store, "tx2", "020", "a2", "v")
+	mustCommit(t, store, "tx3", "030", "b", "v")
+
+	stream, _ := startWatch(t, rpc.NewLocalServer(store), &amberpb.WatchRequest{
+		Prefix:             []byte("a"),
+		StartSequence:      2,
+		ProgressIntervalMs: 10,
+	})
+	if got, want := describe(receive(t, stream, false)), "PUT a2=v@020#2"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Deleted at an older timestamp than the replayed change, committed later
+	if err := store.Begin("tx4", "015", kvstore.SnapshotIsolation, "node1"); err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := store.DeleteWithTimestamp("a1", "tx4", "015"); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	if err := store.Commit("tx4"); err != nil {
+		t.Fatalf("commit error: %v", err)
+	}
+	if got, want := describe(receive(t, stream, false)), "DELETE a1=@015#4"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for {
+		resp := receive(t, stream, true)
+		if resp.Type != amberpb.WatchResponse_PROGRESS {
+			t.Fatalf("unexpected change %s", describe(resp))
+		}
+		if resp.Sequence == 4 {
+			break
+		}
+		if resp.Sequence > 4 {
+			t.Fatalf("progress past the last commit: %d", resp.Sequence)
+		}
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "k", "v1")
+	mustCommit(t, store, "tx2", "020", "k", "v2")
+	if err := store.GC("100"); err != nil {
+		t.Fatalf("GC error: %v", err)
+	}
+	srv := rpc.NewLocalServer(store)
+
+	tests := []struct {
+		name string
+		req  *amberpb.WatchRequest
+		want codes.Code
+	}{
+		{"key and prefix", &amberpb.WatchRequest{Key: []byte("k"), Prefix: []byte("k")}, codes.InvalidArgument},
+		{"collected sequence", &amberpb.WatchRequest{StartSequence: 1}, codes.FailedPrecondition},
+		{"collected timestamp", &amberpb.WatchRequest{StartTimestamp: "050"}, codes.FailedPrecondition},
+		{"timestamp and sequence", &amberpb.WatchRequest{StartTimestamp: "150", StartSequence: 2}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, done := startWatch(t, srv, tt.req)
+			select {
+			case err := <-done:
+				if got := status.Code(err); got != tt.want {
+					t.Errorf("got %v, want %v", err, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Watch did not fail")
+			}
+		})
+	}
+
+	// The version that survived can still be replayed
+	stream, _ := startWatch(t, srv, &amberpb.WatchRequest{Key: []byte("k"), StartSequence: 2})
+	if got, want := describe(receive(t, stream, false)), "PUT k=v2@020#2"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWatchFromTimestamp(t *testing.T) {
+	store := newTestStore(t)
+	mustCommit(t, store, "tx1", "010", "a1", "old")
+	mustCommit(t, store, "tx2", "030", "a2", "v")
+	mustCommit(t, store, "tx3", "020", "a3", "late")
+
+	stream, _ := startWatch(t, rpc.NewLocalServer(store), &amberpb.WatchRequest{Prefix: []byte("a"), StartTimestamp: "025"})
+	for _, want := range []string{"PUT a2=v@030#2", "PUT a3=late@020#3"} {
+		if got := describe(receive(t, stream, false)); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestWatchEndsWithStream(t *testing.T) {
+	store := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &watchStream{ctx: ctx, responses: make(chan *amberpb.WatchResponse)}
+	done := make(chan error, 1)
+	go func() { done <- rpc.NewLocalServer(store).Watch(&amberpb.WatchRequest{}, stream) }()
+	cancel()
+	select {
+	case err := <-done:
+		if got := status.Code(err); got != codes.Canceled {
+			t.Errorf("expected Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not end with its stream")
+	}
+}
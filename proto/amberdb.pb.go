@@ -21,6 +21,168 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// IsolationLevel is the isolation of a transaction.
+type IsolationLevel int32
+
+const (
+	// Reads see the snapshot at the start timestamp; commit fails if a key
+	// written by the transaction was written by a later commit. Write skew is
+	// possible.
+	IsolationLevel_SNAPSHOT IsolationLevel = 0
+	// SNAPSHOT, and commit also fails if a key or range read by the
+	// transaction was written by a later commit. Reads are tracked by the
+	// leader, so they are always served there.
+	IsolationLevel_SERIALIZABLE IsolationLevel = 1
+)
+
+// Enum value maps for IsolationLevel.
+var (
+	IsolationLevel_name = map[int32]string{
+		0: "SNAPSHOT",
+		1: "SERIALIZABLE",
+	}
+	IsolationLevel_value = map[string]int32{
+		"SNAPSHOT":     0,
+		"SERIALIZABLE": 1,
+	}
+)
+
+func (x IsolationLevel) Enum() *IsolationLevel {
+	p := new(IsolationLevel)
+	*p = x
+	return p
+}
+
+func (x IsolationLevel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IsolationLevel) Descriptor() protoreflect.EnumDescriptor {
+	return file_amberdb_proto_enumTypes[0].Descriptor()
+}
+
+func (IsolationLevel) Type() protoreflect.EnumType {
+	return &file_amberdb_proto_enumTypes[0]
+}
+
+func (x IsolationLevel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IsolationLevel.Descriptor instead.
+func (IsolationLevel) EnumDescriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{0}
+}
+
+// ReadConsistency trades read latency for freshness.
+type ReadConsistency int32
+
+const (
+	// Read the local store of whichever node receives the request. Fastest,
+	// but a follower may return stale or missing data.
+	ReadConsistency_STALE ReadConsistency = 0
+	// Read on the leader without contacting the rest of the cluster. Fresh as
+	// long as the leader has not been deposed within its lease.
+	ReadConsistency_LEASE ReadConsistency = 1
+	// Read on the leader after confirming leadership with a quorum and waiting
+	// for every committed entry to be applied (read-index).
+	ReadConsistency_LINEARIZABLE ReadConsistency = 2
+)
+
+// Enum value maps for ReadConsistency.
+var (
+	ReadConsistency_name = map[int32]string{
+		0: "STALE",
+		1: "LEASE",
+		2: "LINEARIZABLE",
+	}
+	ReadConsistency_value = map[string]int32{
+		"STALE":        0,
+		"LEASE":        1,
+		"LINEARIZABLE": 2,
+	}
+)
+
+func (x ReadConsistency) Enum() *ReadConsistency {
+	p := new(ReadConsistency)
+	*p = x
+	return p
+}
+
+func (x ReadConsistency) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReadConsistency) Descriptor() protoreflect.EnumDescriptor {
+	return file_amberdb_proto_enumTypes[1].Descriptor()
+}
+
+func (ReadConsistency) Type() protoreflect.EnumType {
+	return &file_amberdb_proto_enumTypes[1]
+}
+
+func (x ReadConsistency) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReadConsistency.Descriptor instead.
+func (ReadConsistency) EnumDescriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{1}
+}
+
+type WatchResponse_Type int32
+
+const (
+	WatchResponse_TYPE_UNSPECIFIED WatchResponse_Type = 0
+	WatchResponse_PUT              WatchResponse_Type = 1
+	WatchResponse_DELETE           WatchResponse_Type = 2
+	// PROGRESS carries no change: every commit up to sequence has been sent.
+	WatchResponse_PROGRESS WatchResponse_Type = 3
+)
+
+// Enum value maps for WatchResponse_Type.
+var (
+	WatchResponse_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "PUT",
+		2: "DELETE",
+		3: "PROGRESS",
+	}
+	WatchResponse_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"PUT":              1,
+		"DELETE":           2,
+		"PROGRESS":         3,
+	}
+)
+
+func (x WatchResponse_Type) Enum() *WatchResponse_Type {
+	p := new(WatchResponse_Type)
+	*p = x
+	return p
+}
+
+func (x WatchResponse_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchResponse_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_amberdb_proto_enumTypes[2].Descriptor()
+}
+
+func (WatchResponse_Type) Type() protoreflect.EnumType {
+	return &file_amberdb_proto_enumTypes[2]
+}
+
+func (x WatchResponse_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchResponse_Type.Descriptor instead.
+func (WatchResponse_Type) EnumDescriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{25, 0}
+}
+
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -57,16 +219,63 @@ func (*Empty) Descriptor() ([]byte, []int) {
 	return file_amberdb_proto_rawDescGZIP(), []int{0}
 }
 
-type TxnID struct {
+type BeginTransactionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Isolation     IsolationLevel         `protobuf:"varint,1,opt,name=isolation,proto3,enum=amberdb.IsolationLevel" json:"isolation,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *BeginTransactionRequest) Reset() {
+	*x = BeginTransactionRequest{}
+	mi := &file_amberdb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginTransactionRequest) ProtoMessage() {}
+
+func (x *BeginTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginTransactionRequest.ProtoReflect.Descriptor instead.
+func (*BeginTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BeginTransactionRequest) GetIsolation() IsolationLevel {
+	if x != nil {
+		return x.Isolation
+	}
+	return IsolationLevel_SNAPSHOT
+}
+
+type TxnID struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// start_timestamp is set by BeginTransaction: reads with the tx_id and no
+	// read_timestamp see the snapshot at it
+	StartTimestamp string `protobuf:"bytes,2,opt,name=start_timestamp,json=startTimestamp,proto3" json:"start_timestamp,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
 func (x *TxnID) Reset() {
 	*x = TxnID{}
-	mi := &file_amberdb_proto_msgTypes[1]
+	mi := &file_amberdb_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -78,7 +287,7 @@ func (x *TxnID) String() string {
 func (*TxnID) ProtoMessage() {}
 
 func (x *TxnID) ProtoReflect() protoreflect.Message {
-	mi := &file_amberdb_proto_msgTypes[1]
+	mi := &file_amberdb_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -91,7 +300,7 @@ func (x *TxnID) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TxnID.ProtoReflect.Descriptor instead.
 func (*TxnID) Descriptor() ([]byte, []int) {
-	return file_amberdb_proto_rawDescGZIP(), []int{1}
+	return file_amberdb_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *TxnID) GetId() string {
@@ -101,18 +310,28 @@ func (x *TxnID) GetId() string {
 	return ""
 }
 
+func (x *TxnID) GetStartTimestamp() string {
+	if x != nil {
+		return x.StartTimestamp
+	}
+	return ""
+}
+
 type WriteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	TxId          string                 `protobuf:"bytes,3,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	TxId  string                 `protobuf:"bytes,3,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	// ttl_ms expires the value this many milliseconds after the write's HLC
+	// timestamp; reads at or after then see the key as deleted. 0 never expires.
+	TtlMs         int64 `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *WriteRequest) Reset() {
 	*x = WriteRequest{}
-	mi := &file_amberdb_proto_msgTypes[2]
+	mi := &file_amberdb_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -124,7 +343,7 @@ func (x *WriteRequest) String() string {
 func (*WriteRequest) ProtoMessage() {}
 
 func (x *WriteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_amberdb_proto_msgTypes[2]
+	mi := &file_amberdb_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -137,21 +356,21 @@ func (x *WriteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WriteRequest.ProtoReflect.Descriptor instead.
 func (*WriteRequest) Descriptor() ([]byte, []int) {
-	return file_amberdb_proto_rawDescGZIP(), []int{2}
+	return file_amberdb_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *WriteRequest) GetKey() string {
+func (x *WriteRequest) GetKey() []byte {
 	if x != nil {
 		return x.Key
 	}
-	return ""
+	return nil
 }
 
-func (x *WriteRequest) GetValue() string {
+func (x *WriteRequest) GetValue() []byte {
 	if x != nil {
 		return x.Value
 	}
-	return ""
+	return nil
 }
 
 func (x *WriteRequest) GetTxId() string {
@@ -161,29 +380,36 @@ func (x *WriteRequest) GetTxId() string {
 	return ""
 }
 
-type ReadRequest struct {
+func (x *WriteRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type KeyValue struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	ReadTimestamp string                 `protobuf:"bytes,2,opt,name=read_timestamp,json=readTimestamp,proto3" json:"read_timestamp,omitempty"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ReadRequest) Reset() {
-	*x = ReadRequest{}
-	mi := &file_amberdb_proto_msgTypes[3]
+func (x *KeyValue) Reset() {
+	*x = KeyValue{}
+	mi := &file_amberdb_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ReadRequest) String() string {
+func (x *KeyValue) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ReadRequest) ProtoMessage() {}
+func (*KeyValue) ProtoMessage() {}
 
-func (x *ReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_amberdb_proto_msgTypes[3]
+func (x *KeyValue) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -194,47 +420,48 @@ func (x *ReadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
-func (*ReadRequest) Descriptor() ([]byte, []int) {
-	return file_amberdb_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use KeyValue.ProtoReflect.Descriptor instead.
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *ReadRequest) GetKey() string {
+func (x *KeyValue) GetKey() []byte {
 	if x != nil {
 		return x.Key
 	}
-	return ""
+	return nil
 }
 
-func (x *ReadRequest) GetReadTimestamp() string {
+func (x *KeyValue) GetValue() []byte {
 	if x != nil {
-		return x.ReadTimestamp
+		return x.Value
 	}
-	return ""
+	return nil
 }
 
-type ReadResponse struct {
+type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	TxId          string                 `protobuf:"bytes,2,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ReadResponse) Reset() {
-	*x = ReadResponse{}
-	mi := &file_amberdb_proto_msgTypes[4]
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_amberdb_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ReadResponse) String() string {
+func (x *DeleteRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ReadResponse) ProtoMessage() {}
+func (*DeleteRequest) ProtoMessage() {}
 
-func (x *ReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_amberdb_proto_msgTypes[4]
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -245,41 +472,55 @@ func (x *ReadResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ReadResponse.ProtoReflect.Descriptor instead.
-func (*ReadResponse) Descriptor() ([]byte, []int) {
-	return file_amberdb_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ReadResponse) GetValue() string {
+func (x *DeleteRequest) GetKey() []byte {
 	if x != nil {
-		return x.Value
+		return x.Key
+	}
+	return nil
+}
+
+func (x *DeleteRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
 	}
 	return ""
 }
 
-type Status struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+type CompareAndSwapRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Types that are valid to be assigned to Condition:
+	//
+	//	*CompareAndSwapRequest_ExpectedValue
+	//	*CompareAndSwapRequest_ExpectedVersion
+	//	*CompareAndSwapRequest_MustNotExist
+	Condition     isCompareAndSwapRequest_Condition `protobuf_oneof:"condition"`
+	TtlMs         int64                             `protobuf:"varint,6,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"` // as in WriteRequest
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Status) Reset() {
-	*x = Status{}
-	mi := &file_amberdb_proto_msgTypes[5]
+func (x *CompareAndSwapRequest) Reset() {
+	*x = CompareAndSwapRequest{}
+	mi := &file_amberdb_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Status) String() string {
+func (x *CompareAndSwapRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Status) ProtoMessage() {}
+func (*CompareAndSwapRequest) ProtoMessage() {}
 
-func (x *Status) ProtoReflect() protoreflect.Message {
-	mi := &file_amberdb_proto_msgTypes[5]
+func (x *CompareAndSwapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -290,108 +531,2151 @@ func (x *Status) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Status.ProtoReflect.Descriptor instead.
-func (*Status) Descriptor() ([]byte, []int) {
-	return file_amberdb_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use CompareAndSwapRequest.ProtoReflect.Descriptor instead.
+func (*CompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *Status) GetSuccess() bool {
+func (x *CompareAndSwapRequest) GetKey() []byte {
 	if x != nil {
-		return x.Success
+		return x.Key
 	}
-	return false
+	return nil
 }
 
-func (x *Status) GetMessage() string {
+func (x *CompareAndSwapRequest) GetValue() []byte {
 	if x != nil {
-		return x.Message
+		return x.Value
+	}
+	return nil
+}
+
+func (x *CompareAndSwapRequest) GetCondition() isCompareAndSwapRequest_Condition {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+func (x *CompareAndSwapRequest) GetExpectedValue() []byte {
+	if x != nil {
+		if x, ok := x.Condition.(*CompareAndSwapRequest_ExpectedValue); ok {
+			return x.ExpectedValue
+		}
+	}
+	return nil
+}
+
+func (x *CompareAndSwapRequest) GetExpectedVersion() string {
+	if x != nil {
+		if x, ok := x.Condition.(*CompareAndSwapRequest_ExpectedVersion); ok {
+			return x.ExpectedVersion
+		}
 	}
 	return ""
 }
 
-var File_amberdb_proto protoreflect.FileDescriptor
+func (x *CompareAndSwapRequest) GetMustNotExist() bool {
+	if x != nil {
+		if x, ok := x.Condition.(*CompareAndSwapRequest_MustNotExist); ok {
+			return x.MustNotExist
+		}
+	}
+	return false
+}
 
-const file_amberdb_proto_rawDesc = "" +
-	"\n" +
-	"\ramberdb.proto\x12\aamberdb\"\a\n" +
-	"\x05Empty\"\x17\n" +
-	"\x05TxnID\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"K\n" +
-	"\fWriteRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\x12\x13\n" +
-	"\x05tx_id\x18\x03 \x01(\tR\x04txId\"F\n" +
-	"\vReadRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12%\n" +
-	"\x0eread_timestamp\x18\x02 \x01(\tR\rreadTimestamp\"$\n" +
-	"\fReadResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\tR\x05value\"<\n" +
-	"\x06Status\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xfd\x01\n" +
-	"\fAmberService\x122\n" +
-	"\x10BeginTransaction\x12\x0e.amberdb.Empty\x1a\x0e.amberdb.TxnID\x12/\n" +
-	"\x05Write\x12\x15.amberdb.WriteRequest\x1a\x0f.amberdb.Status\x123\n" +
-	"\x04Read\x12\x14.amberdb.ReadRequest\x1a\x15.amberdb.ReadResponse\x12)\n" +
-	"\x06Commit\x12\x0e.amberdb.TxnID\x1a\x0f.amberdb.Status\x12(\n" +
-	"\x05Abort\x12\x0e.amberdb.TxnID\x1a\x0f.amberdb.StatusB\tZ\a./protob\x06proto3"
+func (x *CompareAndSwapRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
 
-var (
-	file_amberdb_proto_rawDescOnce sync.Once
-	file_amberdb_proto_rawDescData []byte
-)
+type isCompareAndSwapRequest_Condition interface {
+	isCompareAndSwapRequest_Condition()
+}
 
-func file_amberdb_proto_rawDescGZIP() []byte {
-	file_amberdb_proto_rawDescOnce.Do(func() {
-		file_amberdb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_amberdb_proto_rawDesc), len(file_amberdb_proto_rawDesc)))
-	})
-	return file_amberdb_proto_rawDescData
+type CompareAndSwapRequest_ExpectedValue struct {
+	ExpectedValue []byte `protobuf:"bytes,3,opt,name=expected_value,json=expectedValue,proto3,oneof"` // the key must exist with this value
 }
 
-var file_amberdb_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_amberdb_proto_goTypes = []any{
-	(*Empty)(nil),        // 0: amberdb.Empty
-	(*TxnID)(nil),        // 1: amberdb.TxnID
-	(*WriteRequest)(nil), // 2: amberdb.WriteRequest
-	(*ReadRequest)(nil),  // 3: amberdb.ReadRequest
-	(*ReadResponse)(nil), // 4: amberdb.ReadResponse
-	(*Status)(nil),       // 5: amberdb.Status
+type CompareAndSwapRequest_ExpectedVersion struct {
+	ExpectedVersion string `protobuf:"bytes,4,opt,name=expected_version,json=expectedVersion,proto3,oneof"` // the key's version timestamp must be this
 }
-var file_amberdb_proto_depIdxs = []int32{
-	0, // 0: amberdb.AmberService.BeginTransaction:input_type -> amberdb.Empty
-	2, // 1: amberdb.AmberService.Write:input_type -> amberdb.WriteRequest
-	3, // 2: amberdb.AmberService.Read:input_type -> amberdb.ReadRequest
-	1, // 3: amberdb.AmberService.Commit:input_type -> amberdb.TxnID
-	1, // 4: amberdb.AmberService.Abort:input_type -> amberdb.TxnID
-	1, // 5: amberdb.AmberService.BeginTransaction:output_type -> amberdb.TxnID
-	5, // 6: amberdb.AmberService.Write:output_type -> amberdb.Status
-	4, // 7: amberdb.AmberService.Read:output_type -> amberdb.ReadResponse
-	5, // 8: amberdb.AmberService.Commit:output_type -> amberdb.Status
-	5, // 9: amberdb.AmberService.Abort:output_type -> amberdb.Status
-	5, // [5:10] is the sub-list for method output_type
-	0, // [0:5] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+type CompareAndSwapRequest_MustNotExist struct {
+	MustNotExist bool `protobuf:"varint,5,opt,name=must_not_exist,json=mustNotExist,proto3,oneof"` // the key must be absent, deleted or expired
 }
 
-func init() { file_amberdb_proto_init() }
-func file_amberdb_proto_init() {
-	if File_amberdb_proto != nil {
-		return
+func (*CompareAndSwapRequest_ExpectedValue) isCompareAndSwapRequest_Condition() {}
+
+func (*CompareAndSwapRequest_ExpectedVersion) isCompareAndSwapRequest_Condition() {}
+
+func (*CompareAndSwapRequest_MustNotExist) isCompareAndSwapRequest_Condition() {}
+
+type CompareAndSwapResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // the condition held and the value was written
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	ConditionFailed bool                   `protobuf:"varint,3,opt,name=condition_failed,json=conditionFailed,proto3" json:"condition_failed,omitempty"`
+	// The current state of the key: the value the condition failed against,
+	// or the value just written, with its version timestamp.
+	Exists         bool   `protobuf:"varint,4,opt,name=exists,proto3" json:"exists,omitempty"`
+	CurrentValue   []byte `protobuf:"bytes,5,opt,name=current_value,json=currentValue,proto3" json:"current_value,omitempty"`
+	CurrentVersion string `protobuf:"bytes,6,opt,name=current_version,json=currentVersion,proto3" json:"current_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CompareAndSwapResponse) Reset() {
+	*x = CompareAndSwapResponse{}
+	mi := &file_amberdb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndSwapResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndSwapResponse) ProtoMessage() {}
+
+func (x *CompareAndSwapResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndSwapResponse.ProtoReflect.Descriptor instead.
+func (*CompareAndSwapResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CompareAndSwapResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CompareAndSwapResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CompareAndSwapResponse) GetConditionFailed() bool {
+	if x != nil {
+		return x.ConditionFailed
+	}
+	return false
+}
+
+func (x *CompareAndSwapResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *CompareAndSwapResponse) GetCurrentValue() []byte {
+	if x != nil {
+		return x.CurrentValue
+	}
+	return nil
+}
+
+func (x *CompareAndSwapResponse) GetCurrentVersion() string {
+	if x != nil {
+		return x.CurrentVersion
+	}
+	return ""
+}
+
+// TxnCompare is a condition on the latest committed version of a key
+type TxnCompare struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Types that are valid to be assigned to Target:
+	//
+	//	*TxnCompare_Value
+	//	*TxnCompare_Version
+	//	*TxnCompare_Exists
+	Target        isTxnCompare_Target `protobuf_oneof:"target"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnCompare) Reset() {
+	*x = TxnCompare{}
+	mi := &file_amberdb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnCompare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnCompare) ProtoMessage() {}
+
+func (x *TxnCompare) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnCompare.ProtoReflect.Descriptor instead.
+func (*TxnCompare) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TxnCompare) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *TxnCompare) GetTarget() isTxnCompare_Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *TxnCompare) GetValue() []byte {
+	if x != nil {
+		if x, ok := x.Target.(*TxnCompare_Value); ok {
+			return x.Value
+		}
+	}
+	return nil
+}
+
+func (x *TxnCompare) GetVersion() string {
+	if x != nil {
+		if x, ok := x.Target.(*TxnCompare_Version); ok {
+			return x.Version
+		}
+	}
+	return ""
+}
+
+func (x *TxnCompare) GetExists() bool {
+	if x != nil {
+		if x, ok := x.Target.(*TxnCompare_Exists); ok {
+			return x.Exists
+		}
+	}
+	return false
+}
+
+type isTxnCompare_Target interface {
+	isTxnCompare_Target()
+}
+
+type TxnCompare_Value struct {
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3,oneof"` // the key exists with this value
+}
+
+type TxnCompare_Version struct {
+	Version string `protobuf:"bytes,3,opt,name=version,proto3,oneof"` // the key's version timestamp is this
+}
+
+type TxnCompare_Exists struct {
+	Exists bool `protobuf:"varint,4,opt,name=exists,proto3,oneof"` // the key exists (true) or is absent, deleted or expired (false)
+}
+
+func (*TxnCompare_Value) isTxnCompare_Target() {}
+
+func (*TxnCompare_Version) isTxnCompare_Target() {}
+
+func (*TxnCompare_Exists) isTxnCompare_Target() {}
+
+type TxnPut struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"` // as in WriteRequest
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnPut) Reset() {
+	*x = TxnPut{}
+	mi := &file_amberdb_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnPut) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnPut) ProtoMessage() {}
+
+func (x *TxnPut) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnPut.ProtoReflect.Descriptor instead.
+func (*TxnPut) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TxnPut) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *TxnPut) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *TxnPut) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type TxnDelete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnDelete) Reset() {
+	*x = TxnDelete{}
+	mi := &file_amberdb_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnDelete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnDelete) ProtoMessage() {}
+
+func (x *TxnDelete) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnDelete.ProtoReflect.Descriptor instead.
+func (*TxnDelete) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TxnDelete) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type TxnGet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnGet) Reset() {
+	*x = TxnGet{}
+	mi := &file_amberdb_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnGet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnGet) ProtoMessage() {}
+
+func (x *TxnGet) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnGet.ProtoReflect.Descriptor instead.
+func (*TxnGet) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TxnGet) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+// TxnOp is one operation of a Txn branch. A key may be written at most once
+// per branch; gets see the writes of earlier ops in the branch.
+type TxnOp struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Op:
+	//
+	//	*TxnOp_Put
+	//	*TxnOp_Delete
+	//	*TxnOp_Get
+	Op            isTxnOp_Op `protobuf_oneof:"op"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnOp) Reset() {
+	*x = TxnOp{}
+	mi := &file_amberdb_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnOp) ProtoMessage() {}
+
+func (x *TxnOp) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnOp.ProtoReflect.Descriptor instead.
+func (*TxnOp) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TxnOp) GetOp() isTxnOp_Op {
+	if x != nil {
+		return x.Op
+	}
+	return nil
+}
+
+func (x *TxnOp) GetPut() *TxnPut {
+	if x != nil {
+		if x, ok := x.Op.(*TxnOp_Put); ok {
+			return x.Put
+		}
+	}
+	return nil
+}
+
+func (x *TxnOp) GetDelete() *TxnDelete {
+	if x != nil {
+		if x, ok := x.Op.(*TxnOp_Delete); ok {
+			return x.Delete
+		}
+	}
+	return nil
+}
+
+func (x *TxnOp) GetGet() *TxnGet {
+	if x != nil {
+		if x, ok := x.Op.(*TxnOp_Get); ok {
+			return x.Get
+		}
+	}
+	return nil
+}
+
+type isTxnOp_Op interface {
+	isTxnOp_Op()
+}
+
+type TxnOp_Put struct {
+	Put *TxnPut `protobuf:"bytes,1,opt,name=put,proto3,oneof"`
+}
+
+type TxnOp_Delete struct {
+	Delete *TxnDelete `protobuf:"bytes,2,opt,name=delete,proto3,oneof"`
+}
+
+type TxnOp_Get struct {
+	Get *TxnGet `protobuf:"bytes,3,opt,name=get,proto3,oneof"`
+}
+
+func (*TxnOp_Put) isTxnOp_Op() {}
+
+func (*TxnOp_Delete) isTxnOp_Op() {}
+
+func (*TxnOp_Get) isTxnOp_Op() {}
+
+type TxnRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Compare       []*TxnCompare          `protobuf:"bytes,1,rep,name=compare,proto3" json:"compare,omitempty"`
+	Success       []*TxnOp               `protobuf:"bytes,2,rep,name=success,proto3" json:"success,omitempty"`
+	Failure       []*TxnOp               `protobuf:"bytes,3,rep,name=failure,proto3" json:"failure,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnRequest) Reset() {
+	*x = TxnRequest{}
+	mi := &file_amberdb_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnRequest) ProtoMessage() {}
+
+func (x *TxnRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnRequest.ProtoReflect.Descriptor instead.
+func (*TxnRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TxnRequest) GetCompare() []*TxnCompare {
+	if x != nil {
+		return x.Compare
+	}
+	return nil
+}
+
+func (x *TxnRequest) GetSuccess() []*TxnOp {
+	if x != nil {
+		return x.Success
+	}
+	return nil
+}
+
+func (x *TxnRequest) GetFailure() []*TxnOp {
+	if x != nil {
+		return x.Failure
+	}
+	return nil
+}
+
+// TxnOpResponse is the state of an op's key once the op ran: the value read
+// by a get or written by a put. exists is false after a delete.
+type TxnOpResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Exists        bool                   `protobuf:"varint,2,opt,name=exists,proto3" json:"exists,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Version       string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnOpResponse) Reset() {
+	*x = TxnOpResponse{}
+	mi := &file_amberdb_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnOpResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnOpResponse) ProtoMessage() {}
+
+func (x *TxnOpResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnOpResponse.ProtoReflect.Descriptor instead.
+func (*TxnOpResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TxnOpResponse) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *TxnOpResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *TxnOpResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *TxnOpResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type TxnResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"` // the transaction was applied, whichever branch ran
+	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	CompareSucceeded bool                   `protobuf:"varint,3,opt,name=compare_succeeded,json=compareSucceeded,proto3" json:"compare_succeeded,omitempty"` // every comparison held and the success ops ran
+	Responses        []*TxnOpResponse       `protobuf:"bytes,4,rep,name=responses,proto3" json:"responses,omitempty"`                                        // one per op of the branch that ran
+	Timestamp        string                 `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                        // HLC timestamp of the comparisons, reads and writes
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TxnResponse) Reset() {
+	*x = TxnResponse{}
+	mi := &file_amberdb_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnResponse) ProtoMessage() {}
+
+func (x *TxnResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnResponse.ProtoReflect.Descriptor instead.
+func (*TxnResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TxnResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *TxnResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *TxnResponse) GetCompareSucceeded() bool {
+	if x != nil {
+		return x.CompareSucceeded
+	}
+	return false
+}
+
+func (x *TxnResponse) GetResponses() []*TxnOpResponse {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+func (x *TxnResponse) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+type WriteBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Pairs         []*KeyValue            `protobuf:"bytes,2,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteBatchRequest) Reset() {
+	*x = WriteBatchRequest{}
+	mi := &file_amberdb_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteBatchRequest) ProtoMessage() {}
+
+func (x *WriteBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteBatchRequest.ProtoReflect.Descriptor instead.
+func (*WriteBatchRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WriteBatchRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *WriteBatchRequest) GetPairs() []*KeyValue {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+type ReadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ReadTimestamp string                 `protobuf:"bytes,2,opt,name=read_timestamp,json=readTimestamp,proto3" json:"read_timestamp,omitempty"`
+	Consistency   ReadConsistency        `protobuf:"varint,3,opt,name=consistency,proto3,enum=amberdb.ReadConsistency" json:"consistency,omitempty"`
+	// tx_id makes the read see the uncommitted writes of that transaction
+	TxId          string `protobuf:"bytes,4,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadRequest) Reset() {
+	*x = ReadRequest{}
+	mi := &file_amberdb_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRequest) ProtoMessage() {}
+
+func (x *ReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
+func (*ReadRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReadRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *ReadRequest) GetReadTimestamp() string {
+	if x != nil {
+		return x.ReadTimestamp
+	}
+	return ""
+}
+
+func (x *ReadRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_STALE
+}
+
+func (x *ReadRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+type ReadResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// exists is false if the key has no live version at the read timestamp,
+	// which an empty value alone cannot tell apart from an empty value
+	Exists        bool `protobuf:"varint,2,opt,name=exists,proto3" json:"exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadResponse) Reset() {
+	*x = ReadResponse{}
+	mi := &file_amberdb_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadResponse) ProtoMessage() {}
+
+func (x *ReadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadResponse.ProtoReflect.Descriptor instead.
+func (*ReadResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ReadResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *ReadResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+type ScanRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	StartKey []byte                 `protobuf:"bytes,1,opt,name=start_key,json=startKey,proto3" json:"start_key,omitempty"` // inclusive
+	EndKey   []byte                 `protobuf:"bytes,2,opt,name=end_key,json=endKey,proto3" json:"end_key,omitempty"`       // exclusive; empty means no upper bound
+	// prefix restricts the range to keys starting with it, intersected with
+	// start_key and end_key
+	Prefix        []byte          `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Limit         uint32          `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`     // maximum number of keys; 0 means no limit
+	Reverse       bool            `protobuf:"varint,5,opt,name=reverse,proto3" json:"reverse,omitempty"` // descending key order
+	ReadTimestamp string          `protobuf:"bytes,6,opt,name=read_timestamp,json=readTimestamp,proto3" json:"read_timestamp,omitempty"`
+	Consistency   ReadConsistency `protobuf:"varint,7,opt,name=consistency,proto3,enum=amberdb.ReadConsistency" json:"consistency,omitempty"`
+	// continuation_token resumes a previous scan after its last key, at the
+	// same snapshot timestamp. The range, prefix and direction must not change.
+	ContinuationToken string `protobuf:"bytes,8,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
+	// tx_id makes the scan see the uncommitted writes of that transaction
+	TxId          string `protobuf:"bytes,9,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_amberdb_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ScanRequest) GetStartKey() []byte {
+	if x != nil {
+		return x.StartKey
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetEndKey() []byte {
+	if x != nil {
+		return x.EndKey
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetPrefix() []byte {
+	if x != nil {
+		return x.Prefix
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetReverse() bool {
+	if x != nil {
+		return x.Reverse
+	}
+	return false
+}
+
+func (x *ScanRequest) GetReadTimestamp() string {
+	if x != nil {
+		return x.ReadTimestamp
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_STALE
+}
+
+func (x *ScanRequest) GetContinuationToken() string {
+	if x != nil {
+		return x.ContinuationToken
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+type ScanResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                              // commit timestamp of the returned version
+	ReadTimestamp string                 `protobuf:"bytes,4,opt,name=read_timestamp,json=readTimestamp,proto3" json:"read_timestamp,omitempty"` // snapshot the scan reads at
+	// continuation_token is set on the last response when the limit stopped
+	// the scan before the end of the range
+	ContinuationToken string `protobuf:"bytes,5,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
+	mi := &file_amberdb_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResponse) ProtoMessage() {}
+
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ScanResponse) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *ScanResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *ScanResponse) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *ScanResponse) GetReadTimestamp() string {
+	if x != nil {
+		return x.ReadTimestamp
+	}
+	return ""
+}
+
+func (x *ScanResponse) GetContinuationToken() string {
+	if x != nil {
+		return x.ContinuationToken
+	}
+	return ""
+}
+
+type GetHistoryRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Key            []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	StartTimestamp string                 `protobuf:"bytes,2,opt,name=start_timestamp,json=startTimestamp,proto3" json:"start_timestamp,omitempty"` // inclusive; empty means from the oldest version
+	EndTimestamp   string                 `protobuf:"bytes,3,opt,name=end_timestamp,json=endTimestamp,proto3" json:"end_timestamp,omitempty"`       // inclusive; empty means up to the newest version
+	Limit          uint32                 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                                        // maximum number of versions; 0 means no limit
+	Consistency    ReadConsistency        `protobuf:"varint,5,opt,name=consistency,proto3,enum=amberdb.ReadConsistency" json:"consistency,omitempty"`
+	// continuation_token resumes a previous call after its last version. The
+	// key and timestamps must not change.
+	ContinuationToken string `protobuf:"bytes,6,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_amberdb_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetHistoryRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *GetHistoryRequest) GetStartTimestamp() string {
+	if x != nil {
+		return x.StartTimestamp
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetEndTimestamp() string {
+	if x != nil {
+		return x.EndTimestamp
+	}
+	return ""
+}
+
+func (x *GetHistoryRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetHistoryRequest) GetConsistency() ReadConsistency {
+	if x != nil {
+		return x.Consistency
+	}
+	return ReadConsistency_STALE
+}
+
+func (x *GetHistoryRequest) GetContinuationToken() string {
+	if x != nil {
+		return x.ContinuationToken
+	}
+	return ""
+}
+
+// KeyVersion is one committed version of a key. Versions older than the GC
+// threshold may have been collected.
+type KeyVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TxId          string                 `protobuf:"bytes,3,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Tombstone     bool                   `protobuf:"varint,4,opt,name=tombstone,proto3" json:"tombstone,omitempty"`                 // the version deleted the key
+	ExpiresAt     string                 `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // HLC timestamp at which the value expires, if it has a TTL
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyVersion) Reset() {
+	*x = KeyVersion{}
+	mi := &file_amberdb_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyVersion) ProtoMessage() {}
+
+func (x *KeyVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyVersion.ProtoReflect.Descriptor instead.
+func (*KeyVersion) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *KeyVersion) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *KeyVersion) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *KeyVersion) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *KeyVersion) GetTombstone() bool {
+	if x != nil {
+		return x.Tombstone
+	}
+	return false
+}
+
+func (x *KeyVersion) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type GetHistoryResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Versions []*KeyVersion          `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"` // newest first
+	// continuation_token is set when more versions remain past the limit
+	ContinuationToken string `protobuf:"bytes,2,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_amberdb_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetHistoryResponse) GetVersions() []*KeyVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *GetHistoryResponse) GetContinuationToken() string {
+	if x != nil {
+		return x.ContinuationToken
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// key watches a single key and prefix every key starting with it; set at
+	// most one. Leaving both empty watches every key.
+	Key    []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Prefix []byte `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// start_timestamp first replays, in commit order, the changes from the
+	// first commit of a version with an HLC timestamp at or after it. A commit
+	// of an older timestamp that came after the last change received may be
+	// missed; start_sequence resumes exactly. Set at most one of the two.
+	StartTimestamp string `protobuf:"bytes,3,opt,name=start_timestamp,json=startTimestamp,proto3" json:"start_timestamp,omitempty"`
+	// progress_interval_ms is how often to send a PROGRESS event; 0 means
+	// every 10 seconds.
+	ProgressIntervalMs uint32 `protobuf:"varint,4,opt,name=progress_interval_ms,json=progressIntervalMs,proto3" json:"progress_interval_ms,omitempty"`
+	// start_sequence first replays, in commit order, the changes with a
+	// commit sequence at or after it. 0 means only changes committed from now
+	// on. To resume, pass one more than the last sequence received.
+	StartSequence uint64 `protobuf:"varint,5,opt,name=start_sequence,json=startSequence,proto3" json:"start_sequence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_amberdb_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *WatchRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetPrefix() []byte {
+	if x != nil {
+		return x.Prefix
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetStartTimestamp() string {
+	if x != nil {
+		return x.StartTimestamp
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetProgressIntervalMs() uint32 {
+	if x != nil {
+		return x.ProgressIntervalMs
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetStartSequence() uint64 {
+	if x != nil {
+		return x.StartSequence
+	}
+	return 0
+}
+
+type WatchResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      WatchResponse_Type     `protobuf:"varint,1,opt,name=type,proto3,enum=amberdb.WatchResponse_Type" json:"type,omitempty"`
+	Key       []byte                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value     []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp string                 `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // version timestamp of the change
+	TxId      string                 `protobuf:"bytes,5,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	ExpiresAt string                 `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // set on a PUT with a TTL
+	// sequence is the commit sequence of the change, the Raft index of the
+	// entry that committed it, so it is the same on every node. Changes
+	// committed together share it.
+	Sequence      uint64 `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_amberdb_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *WatchResponse) GetType() WatchResponse_Type {
+	if x != nil {
+		return x.Type
+	}
+	return WatchResponse_TYPE_UNSPECIFIED
+}
+
+func (x *WatchResponse) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+type Status struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	mi := &file_amberdb_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Status) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *Status) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type TransferLeadershipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // target server; empty lets raft pick the most up-to-date voter
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferLeadershipRequest) Reset() {
+	*x = TransferLeadershipRequest{}
+	mi := &file_amberdb_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLeadershipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLeadershipRequest) ProtoMessage() {}
+
+func (x *TransferLeadershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferLeadershipRequest.ProtoReflect.Descriptor instead.
+func (*TransferLeadershipRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TransferLeadershipRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type AddServerRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"` // raft address, e.g. node4:9004
+	// grpc_address is the client address followers forward writes to once
+	// the server leads, e.g. node4:50054. A node also registers its own
+	// address when it is elected.
+	GrpcAddress   string `protobuf:"bytes,3,opt,name=grpc_address,json=grpcAddress,proto3" json:"grpc_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddServerRequest) Reset() {
+	*x = AddServerRequest{}
+	mi := &file_amberdb_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddServerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddServerRequest) ProtoMessage() {}
+
+func (x *AddServerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddServerRequest.ProtoReflect.Descriptor instead.
+func (*AddServerRequest) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *AddServerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AddServerRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *AddServerRequest) GetGrpcAddress() string {
+	if x != nil {
+		return x.GrpcAddress
+	}
+	return ""
+}
+
+type ServerID struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerID) Reset() {
+	*x = ServerID{}
+	mi := &file_amberdb_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerID) ProtoMessage() {}
+
+func (x *ServerID) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerID.ProtoReflect.Descriptor instead.
+func (*ServerID) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ServerID) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Server struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Suffrage      string                 `protobuf:"bytes,3,opt,name=suffrage,proto3" json:"suffrage,omitempty"` // Voter, Nonvoter or Staging
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Server) Reset() {
+	*x = Server{}
+	mi := &file_amberdb_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Server) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server) ProtoMessage() {}
+
+func (x *Server) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server.ProtoReflect.Descriptor instead.
+func (*Server) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Server) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Server) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Server) GetSuffrage() string {
+	if x != nil {
+		return x.Suffrage
+	}
+	return ""
+}
+
+type Configuration struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Servers       []*Server              `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Configuration) Reset() {
+	*x = Configuration{}
+	mi := &file_amberdb_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Configuration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Configuration) ProtoMessage() {}
+
+func (x *Configuration) ProtoReflect() protoreflect.Message {
+	mi := &file_amberdb_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Configuration.ProtoReflect.Descriptor instead.
+func (*Configuration) Descriptor() ([]byte, []int) {
+	return file_amberdb_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *Configuration) GetServers() []*Server {
+	if x != nil {
+		return x.Servers
+	}
+	return nil
+}
+
+func (x *Configuration) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+var File_amberdb_proto protoreflect.FileDescriptor
+
+const file_amberdb_proto_rawDesc = "" +
+	"\n" +
+	"\ramberdb.proto\x12\aamberdb\"\a\n" +
+	"\x05Empty\"P\n" +
+	"\x17BeginTransactionRequest\x125\n" +
+	"\tisolation\x18\x01 \x01(\x0e2\x17.amberdb.IsolationLevelR\tisolation\"@\n" +
+	"\x05TxnID\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x0fstart_timestamp\x18\x02 \x01(\tR\x0estartTimestamp\"b\n" +
+	"\fWriteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x13\n" +
+	"\x05tx_id\x18\x03 \x01(\tR\x04txId\x12\x15\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03R\x05ttlMs\"2\n" +
+	"\bKeyValue\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\"6\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x13\n" +
+	"\x05tx_id\x18\x02 \x01(\tR\x04txId\"\xe1\x01\n" +
+	"\x15CompareAndSwapRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12'\n" +
+	"\x0eexpected_value\x18\x03 \x01(\fH\x00R\rexpectedValue\x12+\n" +
+	"\x10expected_version\x18\x04 \x01(\tH\x00R\x0fexpectedVersion\x12&\n" +
+	"\x0emust_not_exist\x18\x05 \x01(\bH\x00R\fmustNotExist\x12\x15\n" +
+	"\x06ttl_ms\x18\x06 \x01(\x03R\x05ttlMsB\v\n" +
+	"\tcondition\"\xdd\x01\n" +
+	"\x16CompareAndSwapResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12)\n" +
+	"\x10condition_failed\x18\x03 \x01(\bR\x0fconditionFailed\x12\x16\n" +
+	"\x06exists\x18\x04 \x01(\bR\x06exists\x12#\n" +
+	"\rcurrent_value\x18\x05 \x01(\fR\fcurrentValue\x12'\n" +
+	"\x0fcurrent_version\x18\x06 \x01(\tR\x0ecurrentVersion\"v\n" +
+	"\n" +
+	"TxnCompare\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x16\n" +
+	"\x05value\x18\x02 \x01(\fH\x00R\x05value\x12\x1a\n" +
+	"\aversion\x18\x03 \x01(\tH\x00R\aversion\x12\x18\n" +
+	"\x06exists\x18\x04 \x01(\bH\x00R\x06existsB\b\n" +
+	"\x06target\"G\n" +
+	"\x06TxnPut\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\"\x1d\n" +
+	"\tTxnDelete\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\"\x1a\n" +
+	"\x06TxnGet\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\"\x85\x01\n" +
+	"\x05TxnOp\x12#\n" +
+	"\x03put\x18\x01 \x01(\v2\x0f.amberdb.TxnPutH\x00R\x03put\x12,\n" +
+	"\x06delete\x18\x02 \x01(\v2\x12.amberdb.TxnDeleteH\x00R\x06delete\x12#\n" +
+	"\x03get\x18\x03 \x01(\v2\x0f.amberdb.TxnGetH\x00R\x03getB\x04\n" +
+	"\x02op\"\x8f\x01\n" +
+	"\n" +
+	"TxnRequest\x12-\n" +
+	"\acompare\x18\x01 \x03(\v2\x13.amberdb.TxnCompareR\acompare\x12(\n" +
+	"\asuccess\x18\x02 \x03(\v2\x0e.amberdb.TxnOpR\asuccess\x12(\n" +
+	"\afailure\x18\x03 \x03(\v2\x0e.amberdb.TxnOpR\afailure\"i\n" +
+	"\rTxnOpResponse\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x16\n" +
+	"\x06exists\x18\x02 \x01(\bR\x06exists\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\"\xc2\x01\n" +
+	"\vTxnResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12+\n" +
+	"\x11compare_succeeded\x18\x03 \x01(\bR\x10compareSucceeded\x124\n" +
+	"\tresponses\x18\x04 \x03(\v2\x16.amberdb.TxnOpResponseR\tresponses\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\tR\ttimestamp\"Q\n" +
+	"\x11WriteBatchRequest\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12'\n" +
+	"\x05pairs\x18\x02 \x03(\v2\x11.amberdb.KeyValueR\x05pairs\"\x97\x01\n" +
+	"\vReadRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12%\n" +
+	"\x0eread_timestamp\x18\x02 \x01(\tR\rreadTimestamp\x12:\n" +
+	"\vconsistency\x18\x03 \x01(\x0e2\x18.amberdb.ReadConsistencyR\vconsistency\x12\x13\n" +
+	"\x05tx_id\x18\x04 \x01(\tR\x04txId\"<\n" +
+	"\fReadResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x16\n" +
+	"\x06exists\x18\x02 \x01(\bR\x06exists\"\xb2\x02\n" +
+	"\vScanRequest\x12\x1b\n" +
+	"\tstart_key\x18\x01 \x01(\fR\bstartKey\x12\x17\n" +
+	"\aend_key\x18\x02 \x01(\fR\x06endKey\x12\x16\n" +
+	"\x06prefix\x18\x03 \x01(\fR\x06prefix\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\rR\x05limit\x12\x18\n" +
+	"\areverse\x18\x05 \x01(\bR\areverse\x12%\n" +
+	"\x0eread_timestamp\x18\x06 \x01(\tR\rreadTimestamp\x12:\n" +
+	"\vconsistency\x18\a \x01(\x0e2\x18.amberdb.ReadConsistencyR\vconsistency\x12-\n" +
+	"\x12continuation_token\x18\b \x01(\tR\x11continuationToken\x12\x13\n" +
+	"\x05tx_id\x18\t \x01(\tR\x04txId\"\xaa\x01\n" +
+	"\fScanResponse\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\tR\ttimestamp\x12%\n" +
+	"\x0eread_timestamp\x18\x04 \x01(\tR\rreadTimestamp\x12-\n" +
+	"\x12continuation_token\x18\x05 \x01(\tR\x11continuationToken\"\xf4\x01\n" +
+	"\x11GetHistoryRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12'\n" +
+	"\x0fstart_timestamp\x18\x02 \x01(\tR\x0estartTimestamp\x12#\n" +
+	"\rend_timestamp\x18\x03 \x01(\tR\fendTimestamp\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\rR\x05limit\x12:\n" +
+	"\vconsistency\x18\x05 \x01(\x0e2\x18.amberdb.ReadConsistencyR\vconsistency\x12-\n" +
+	"\x12continuation_token\x18\x06 \x01(\tR\x11continuationToken\"\x92\x01\n" +
+	"\n" +
+	"KeyVersion\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\tR\ttimestamp\x12\x13\n" +
+	"\x05tx_id\x18\x03 \x01(\tR\x04txId\x12\x1c\n" +
+	"\ttombstone\x18\x04 \x01(\bR\ttombstone\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\tR\texpiresAt\"t\n" +
+	"\x12GetHistoryResponse\x12/\n" +
+	"\bversions\x18\x01 \x03(\v2\x13.amberdb.KeyVersionR\bversions\x12-\n" +
+	"\x12continuation_token\x18\x02 \x01(\tR\x11continuationToken\"\xba\x01\n" +
+	"\fWatchRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\fR\x06prefix\x12'\n" +
+	"\x0fstart_timestamp\x18\x03 \x01(\tR\x0estartTimestamp\x120\n" +
+	"\x14progress_interval_ms\x18\x04 \x01(\rR\x12progressIntervalMs\x12%\n" +
+	"\x0estart_sequence\x18\x05 \x01(\x04R\rstartSequence\"\x97\x02\n" +
+	"\rWatchResponse\x12/\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1b.amberdb.WatchResponse.TypeR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\tR\ttimestamp\x12\x13\n" +
+	"\x05tx_id\x18\x05 \x01(\tR\x04txId\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\tR\texpiresAt\x12\x1a\n" +
+	"\bsequence\x18\a \x01(\x04R\bsequence\"?\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\a\n" +
+	"\x03PUT\x10\x01\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\x02\x12\f\n" +
+	"\bPROGRESS\x10\x03\"<\n" +
+	"\x06Status\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"+\n" +
+	"\x19TransferLeadershipRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"_\n" +
+	"\x10AddServerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12!\n" +
+	"\fgrpc_address\x18\x03 \x01(\tR\vgrpcAddress\"\x1a\n" +
+	"\bServerID\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"N\n" +
+	"\x06Server\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x1a\n" +
+	"\bsuffrage\x18\x03 \x01(\tR\bsuffrage\"W\n" +
+	"\rConfiguration\x12)\n" +
+	"\aservers\x18\x01 \x03(\v2\x0f.amberdb.ServerR\aservers\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId*0\n" +
+	"\x0eIsolationLevel\x12\f\n" +
+	"\bSNAPSHOT\x10\x00\x12\x10\n" +
+	"\fSERIALIZABLE\x10\x01*9\n" +
+	"\x0fReadConsistency\x12\t\n" +
+	"\x05STALE\x10\x00\x12\t\n" +
+	"\x05LEASE\x10\x01\x12\x10\n" +
+	"\fLINEARIZABLE\x10\x022\xba\x05\n" +
+	"\fAmberService\x12D\n" +
+	"\x10BeginTransaction\x12 .amberdb.BeginTransactionRequest\x1a\x0e.amberdb.TxnID\x12/\n" +
+	"\x05Write\x12\x15.amberdb.WriteRequest\x1a\x0f.amberdb.Status\x129\n" +
+	"\n" +
+	"WriteBatch\x12\x1a.amberdb.WriteBatchRequest\x1a\x0f.amberdb.Status\x121\n" +
+	"\x06Delete\x12\x16.amberdb.DeleteRequest\x1a\x0f.amberdb.Status\x12Q\n" +
+	"\x0eCompareAndSwap\x12\x1e.amberdb.CompareAndSwapRequest\x1a\x1f.amberdb.CompareAndSwapResponse\x120\n" +
+	"\x03Txn\x12\x13.amberdb.TxnRequest\x1a\x14.amberdb.TxnResponse\x123\n" +
+	"\x04Read\x12\x14.amberdb.ReadRequest\x1a\x15.amberdb.ReadResponse\x125\n" +
+	"\x04Scan\x12\x14.amberdb.ScanRequest\x1a\x15.amberdb.ScanResponse0\x01\x12E\n" +
+	"\n" +
+	"GetHistory\x12\x1a.amberdb.GetHistoryRequest\x1a\x1b.amberdb.GetHistoryResponse\x128\n" +
+	"\x05Watch\x12\x15.amberdb.WatchRequest\x1a\x16.amberdb.WatchResponse0\x01\x12)\n" +
+	"\x06Commit\x12\x0e.amberdb.TxnID\x1a\x0f.amberdb.Status\x12(\n" +
+	"\x05Abort\x12\x0e.amberdb.TxnID\x1a\x0f.amberdb.Status2\xc5\x03\n" +
+	"\fAdminService\x126\n" +
+	"\bAddVoter\x12\x19.amberdb.AddServerRequest\x1a\x0f.amberdb.Status\x129\n" +
+	"\vAddNonvoter\x12\x19.amberdb.AddServerRequest\x1a\x0f.amberdb.Status\x122\n" +
+	"\fRemoveServer\x12\x11.amberdb.ServerID\x1a\x0f.amberdb.Status\x121\n" +
+	"\vDemoteVoter\x12\x11.amberdb.ServerID\x1a\x0f.amberdb.Status\x12:\n" +
+	"\x10GetConfiguration\x12\x0e.amberdb.Empty\x1a\x16.amberdb.Configuration\x12I\n" +
+	"\x12TransferLeadership\x12\".amberdb.TransferLeadershipRequest\x1a\x0f.amberdb.Status\x12(\n" +
+	"\x05Drain\x12\x0e.amberdb.Empty\x1a\x0f.amberdb.Status\x12*\n" +
+	"\aUndrain\x12\x0e.amberdb.Empty\x1a\x0f.amberdb.StatusB\tZ\a./protob\x06proto3"
+
+var (
+	file_amberdb_proto_rawDescOnce sync.Once
+	file_amberdb_proto_rawDescData []byte
+)
+
+func file_amberdb_proto_rawDescGZIP() []byte {
+	file_amberdb_proto_rawDescOnce.Do(func() {
+		file_amberdb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_amberdb_proto_rawDesc), len(file_amberdb_proto_rawDesc)))
+	})
+	return file_amberdb_proto_rawDescData
+}
+
+var file_amberdb_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_amberdb_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
+var file_amberdb_proto_goTypes = []any{
+	(IsolationLevel)(0),               // 0: amberdb.IsolationLevel
+	(ReadConsistency)(0),              // 1: amberdb.ReadConsistency
+	(WatchResponse_Type)(0),           // 2: amberdb.WatchResponse.Type
+	(*Empty)(nil),                     // 3: amberdb.Empty
+	(*BeginTransactionRequest)(nil),   // 4: amberdb.BeginTransactionRequest
+	(*TxnID)(nil),                     // 5: amberdb.TxnID
+	(*WriteRequest)(nil),              // 6: amberdb.WriteRequest
+	(*KeyValue)(nil),                  // 7: amberdb.KeyValue
+	(*DeleteRequest)(nil),             // 8: amberdb.DeleteRequest
+	(*CompareAndSwapRequest)(nil),     // 9: amberdb.CompareAndSwapRequest
+	(*CompareAndSwapResponse)(nil),    // 10: amberdb.CompareAndSwapResponse
+	(*TxnCompare)(nil),                // 11: amberdb.TxnCompare
+	(*TxnPut)(nil),                    // 12: amberdb.TxnPut
+	(*TxnDelete)(nil),                 // 13: amberdb.TxnDelete
+	(*TxnGet)(nil),                    // 14: amberdb.TxnGet
+	(*TxnOp)(nil),                     // 15: amberdb.TxnOp
+	(*TxnRequest)(nil),                // 16: amberdb.TxnRequest
+	(*TxnOpResponse)(nil),             // 17: amberdb.TxnOpResponse
+	(*TxnResponse)(nil),               // 18: amberdb.TxnResponse
+	(*WriteBatchRequest)(nil),         // 19: amberdb.WriteBatchRequest
+	(*ReadRequest)(nil),               // 20: amberdb.ReadRequest
+	(*ReadResponse)(nil),              // 21: amberdb.ReadResponse
+	(*ScanRequest)(nil),               // 22: amberdb.ScanRequest
+	(*ScanResponse)(nil),              // 23: amberdb.ScanResponse
+	(*GetHistoryRequest)(nil),         // 24: amberdb.GetHistoryRequest
+	(*KeyVersion)(nil),                // 25: amberdb.KeyVersion
+	(*GetHistoryResponse)(nil),        // 26: amberdb.GetHistoryResponse
+	(*WatchRequest)(nil),              // 27: amberdb.WatchRequest
+	(*WatchResponse)(nil),             // 28: amberdb.WatchResponse
+	(*Status)(nil),                    // 29: amberdb.Status
+	(*TransferLeadershipRequest)(nil), // 30: amberdb.TransferLeadershipRequest
+	(*AddServerRequest)(nil),          // 31: amberdb.AddServerRequest
+	(*ServerID)(nil),                  // 32: amberdb.ServerID
+	(*Server)(nil),                    // 33: amberdb.Server
+	(*Configuration)(nil),             // 34: amberdb.Configuration
+}
+var file_amberdb_proto_depIdxs = []int32{
+	0,  // 0: amberdb.BeginTransactionRequest.isolation:type_name -> amberdb.IsolationLevel
+	12, // 1: amberdb.TxnOp.put:type_name -> amberdb.TxnPut
+	13, // 2: amberdb.TxnOp.delete:type_name -> amberdb.TxnDelete
+	14, // 3: amberdb.TxnOp.get:type_name -> amberdb.TxnGet
+	11, // 4: amberdb.TxnRequest.compare:type_name -> amberdb.TxnCompare
+	15, // 5: amberdb.TxnRequest.success:type_name -> amberdb.TxnOp
+	15, // 6: amberdb.TxnRequest.failure:type_name -> amberdb.TxnOp
+	17, // 7: amberdb.TxnResponse.responses:type_name -> amberdb.TxnOpResponse
+	7,  // 8: amberdb.WriteBatchRequest.pairs:type_name -> amberdb.KeyValue
+	1,  // 9: amberdb.ReadRequest.consistency:type_name -> amberdb.ReadConsistency
+	1,  // 10: amberdb.ScanRequest.consistency:type_name -> amberdb.ReadConsistency
+	1,  // 11: amberdb.GetHistoryRequest.consistency:type_name -> amberdb.ReadConsistency
+	25, // 12: amberdb.GetHistoryResponse.versions:type_name -> amberdb.KeyVersion
+	2,  // 13: amberdb.WatchResponse.type:type_name -> amberdb.WatchResponse.Type
+	33, // 14: amberdb.Configuration.servers:type_name -> amberdb.Server
+	4,  // 15: amberdb.AmberService.BeginTransaction:input_type -> amberdb.BeginTransactionRequest
+	6,  // 16: amberdb.AmberService.Write:input_type -> amberdb.WriteRequest
+	19, // 17: amberdb.AmberService.WriteBatch:input_type -> amberdb.WriteBatchRequest
+	8,  // 18: amberdb.AmberService.Delete:input_type -> amberdb.DeleteRequest
+	9,  // 19: amberdb.AmberService.CompareAndSwap:input_type -> amberdb.CompareAndSwapRequest
+	16, // 20: amberdb.AmberService.Txn:input_type -> amberdb.TxnRequest
+	20, // 21: amberdb.AmberService.Read:input_type -> amberdb.ReadRequest
+	22, // 22: amberdb.AmberService.Scan:input_type -> amberdb.ScanRequest
+	24, // 23: amberdb.AmberService.GetHistory:input_type -> amberdb.GetHistoryRequest
+	27, // 24: amberdb.AmberService.Watch:input_type -> amberdb.WatchRequest
+	5,  // 25: amberdb.AmberService.Commit:input_type -> amberdb.TxnID
+	5,  // 26: amberdb.AmberService.Abort:input_type -> amberdb.TxnID
+	31, // 27: amberdb.AdminService.AddVoter:input_type -> amberdb.AddServerRequest
+	31, // 28: amberdb.AdminService.AddNonvoter:input_type -> amberdb.AddServerRequest
+	32, // 29: amberdb.AdminService.RemoveServer:input_type -> amberdb.ServerID
+	32, // 30: amberdb.AdminService.DemoteVoter:input_type -> amberdb.ServerID
+	3,  // 31: amberdb.AdminService.GetConfiguration:input_type -> amberdb.Empty
+	30, // 32: amberdb.AdminService.TransferLeadership:input_type -> amberdb.TransferLeadershipRequest
+	3,  // 33: amberdb.AdminService.Drain:input_type -> amberdb.Empty
+	3,  // 34: amberdb.AdminService.Undrain:input_type -> amberdb.Empty
+	5,  // 35: amberdb.AmberService.BeginTransaction:output_type -> amberdb.TxnID
+	29, // 36: amberdb.AmberService.Write:output_type -> amberdb.Status
+	29, // 37: amberdb.AmberService.WriteBatch:output_type -> amberdb.Status
+	29, // 38: amberdb.AmberService.Delete:output_type -> amberdb.Status
+	10, // 39: amberdb.AmberService.CompareAndSwap:output_type -> amberdb.CompareAndSwapResponse
+	18, // 40: amberdb.AmberService.Txn:output_type -> amberdb.TxnResponse
+	21, // 41: amberdb.AmberService.Read:output_type -> amberdb.ReadResponse
+	23, // 42: amberdb.AmberService.Scan:output_type -> amberdb.ScanResponse
+	26, // 43: amberdb.AmberService.GetHistory:output_type -> amberdb.GetHistoryResponse
+	28, // 44: amberdb.AmberService.Watch:output_type -> amberdb.WatchResponse
+	29, // 45: amberdb.AmberService.Commit:output_type -> amberdb.Status
+	29, // 46: amberdb.AmberService.Abort:output_type -> amberdb.Status
+	29, // 47: amberdb.AdminService.AddVoter:output_type -> amberdb.Status
+	29, // 48: amberdb.AdminService.AddNonvoter:output_type -> amberdb.Status
+	29, // 49: amberdb.AdminService.RemoveServer:output_type -> amberdb.Status
+	29, // 50: amberdb.AdminService.DemoteVoter:output_type -> amberdb.Status
+	34, // 51: amberdb.AdminService.GetConfiguration:output_type -> amberdb.Configuration
+	29, // 52: amberdb.AdminService.TransferLeadership:output_type -> amberdb.Status
+	29, // 53: amberdb.AdminService.Drain:output_type -> amberdb.Status
+	29, // 54: amberdb.AdminService.Undrain:output_type -> amberdb.Status
+	35, // [35:55] is the sub-list for method output_type
+	15, // [15:35] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_amberdb_proto_init() }
+func file_amberdb_proto_init() {
+	if File_amberdb_proto != nil {
+		return
+	}
+	file_amberdb_proto_msgTypes[6].OneofWrappers = []any{
+		(*CompareAndSwapRequest_ExpectedValue)(nil),
+		(*CompareAndSwapRequest_ExpectedVersion)(nil),
+		(*CompareAndSwapRequest_MustNotExist)(nil),
+	}
+	file_amberdb_proto_msgTypes[8].OneofWrappers = []any{
+		(*TxnCompare_Value)(nil),
+		(*TxnCompare_Version)(nil),
+		(*TxnCompare_Exists)(nil),
+	}
+	file_amberdb_proto_msgTypes[12].OneofWrappers = []any{
+		(*TxnOp_Put)(nil),
+		(*TxnOp_Delete)(nil),
+		(*TxnOp_Get)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_amberdb_proto_rawDesc), len(file_amberdb_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      3,
+			NumMessages:   32,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_amberdb_proto_goTypes,
 		DependencyIndexes: file_amberdb_proto_depIdxs,
+		EnumInfos:         file_amberdb_proto_enumTypes,
 		MessageInfos:      file_amberdb_proto_msgTypes,
 	}.Build()
 	File_amberdb_proto = out.File
@@ -21,7 +21,14 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	AmberService_BeginTransaction_FullMethodName = "/amberdb.AmberService/BeginTransaction"
 	AmberService_Write_FullMethodName            = "/amberdb.AmberService/Write"
+	AmberService_WriteBatch_FullMethodName       = "/amberdb.AmberService/WriteBatch"
+	AmberService_Delete_FullMethodName           = "/amberdb.AmberService/Delete"
+	AmberService_CompareAndSwap_FullMethodName   = "/amberdb.AmberService/CompareAndSwap"
+	AmberService_Txn_FullMethodName              = "/amberdb.AmberService/Txn"
 	AmberService_Read_FullMethodName             = "/amberdb.AmberService/Read"
+	AmberService_Scan_FullMethodName             = "/amberdb.AmberService/Scan"
+	AmberService_GetHistory_FullMethodName       = "/amberdb.AmberService/GetHistory"
+	AmberService_Watch_FullMethodName            = "/amberdb.AmberService/Watch"
 	AmberService_Commit_FullMethodName           = "/amberdb.AmberService/Commit"
 	AmberService_Abort_FullMethodName            = "/amberdb.AmberService/Abort"
 )
@@ -30,9 +37,39 @@ const (
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AmberServiceClient interface {
-	BeginTransaction(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TxnID, error)
+	// BeginTransaction starts a transaction at the leader's current HLC time,
+	// returned as start_timestamp, with the requested isolation level.
+	BeginTransaction(ctx context.Context, in *BeginTransactionRequest, opts ...grpc.CallOption) (*TxnID, error)
 	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*Status, error)
+	// WriteBatch writes many keys in one transaction with a single Raft round trip.
+	WriteBatch(ctx context.Context, in *WriteBatchRequest, opts ...grpc.CallOption) (*Status, error)
+	// Delete removes a key as part of a transaction. Reads at or after the
+	// commit see no value; reads at earlier timestamps still see the old one.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Status, error)
+	// CompareAndSwap writes and commits a key, outside any transaction, only
+	// if its current committed value or version matches the condition.
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error)
+	// Txn checks a list of comparisons and, in one atomic step, runs and
+	// commits the success ops if all of them hold or the failure ops if not.
+	Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error)
 	Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error)
+	// Scan streams the latest committed value of every key in a range as of
+	// one snapshot timestamp, in key order.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanResponse], error)
+	// GetHistory returns the committed versions of a key between two
+	// timestamps, newest first, including deletes.
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// Watch streams the puts and deletes committed to a key or prefix, in
+	// commit order, with periodic progress notifications. Any node can serve
+	// it from its own replica.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error)
+	// Commit fails with ABORTED, and aborts the transaction, if another
+	// transaction committed a write to one of its keys after it began or, for
+	// a SERIALIZABLE transaction, to anything it read. The transaction can
+	// then be retried from BeginTransaction.
+	// Write, WriteBatch, Delete, Commit and Abort fail with NOT_FOUND for a
+	// tx_id that was never begun, and with FAILED_PRECONDITION for one that
+	// already committed or aborted.
 	Commit(ctx context.Context, in *TxnID, opts ...grpc.CallOption) (*Status, error)
 	Abort(ctx context.Context, in *TxnID, opts ...grpc.CallOption) (*Status, error)
 }
@@ -45,7 +82,7 @@ func NewAmberServiceClient(cc grpc.ClientConnInterface) AmberServiceClient {
 	return &amberServiceClient{cc}
 }
 
-func (c *amberServiceClient) BeginTransaction(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TxnID, error) {
+func (c *amberServiceClient) BeginTransaction(ctx context.Context, in *BeginTransactionRequest, opts ...grpc.CallOption) (*TxnID, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(TxnID)
 	err := c.cc.Invoke(ctx, AmberService_BeginTransaction_FullMethodName, in, out, cOpts...)
@@ -65,6 +102,46 @@ func (c *amberServiceClient) Write(ctx context.Context, in *WriteRequest, opts .
 	return out, nil
 }
 
+func (c *amberServiceClient) WriteBatch(ctx context.Context, in *WriteBatchRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AmberService_WriteBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *amberServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AmberService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *amberServiceClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompareAndSwapResponse)
+	err := c.cc.Invoke(ctx, AmberService_CompareAndSwap_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *amberServiceClient) Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TxnResponse)
+	err := c.cc.Invoke(ctx, AmberService_Txn_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *amberServiceClient) Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ReadResponse)
@@ -75,6 +152,54 @@ func (c *amberServiceClient) Read(ctx context.Context, in *ReadRequest, opts ...
 	return out, nil
 }
 
+func (c *amberServiceClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AmberService_ServiceDesc.Streams[0], AmberService_Scan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScanRequest, ScanResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AmberService_ScanClient = grpc.ServerStreamingClient[ScanResponse]
+
+func (c *amberServiceClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, AmberService_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *amberServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AmberService_ServiceDesc.Streams[1], AmberService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AmberService_WatchClient = grpc.ServerStreamingClient[WatchResponse]
+
 func (c *amberServiceClient) Commit(ctx context.Context, in *TxnID, opts ...grpc.CallOption) (*Status, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Status)
@@ -99,9 +224,39 @@ func (c *amberServiceClient) Abort(ctx context.Context, in *TxnID, opts ...grpc.
 // All implementations must embed UnimplementedAmberServiceServer
 // for forward compatibility.
 type AmberServiceServer interface {
-	BeginTransaction(context.Context, *Empty) (*TxnID, error)
+	// BeginTransaction starts a transaction at the leader's current HLC time,
+	// returned as start_timestamp, with the requested isolation level.
+	BeginTransaction(context.Context, *BeginTransactionRequest) (*TxnID, error)
 	Write(context.Context, *WriteRequest) (*Status, error)
+	// WriteBatch writes many keys in one transaction with a single Raft round trip.
+	WriteBatch(context.Context, *WriteBatchRequest) (*Status, error)
+	// Delete removes a key as part of a transaction. Reads at or after the
+	// commit see no value; reads at earlier timestamps still see the old one.
+	Delete(context.Context, *DeleteRequest) (*Status, error)
+	// CompareAndSwap writes and commits a key, outside any transaction, only
+	// if its current committed value or version matches the condition.
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error)
+	// Txn checks a list of comparisons and, in one atomic step, runs and
+	// commits the success ops if all of them hold or the failure ops if not.
+	Txn(context.Context, *TxnRequest) (*TxnResponse, error)
 	Read(context.Context, *ReadRequest) (*ReadResponse, error)
+	// Scan streams the latest committed value of every key in a range as of
+	// one snapshot timestamp, in key order.
+	Scan(*ScanRequest, grpc.ServerStreamingServer[ScanResponse]) error
+	// GetHistory returns the committed versions of a key between two
+	// timestamps, newest first, including deletes.
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// Watch streams the puts and deletes committed to a key or prefix, in
+	// commit order, with periodic progress notifications. Any node can serve
+	// it from its own replica.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error
+	// Commit fails with ABORTED, and aborts the transaction, if another
+	// transaction committed a write to one of its keys after it began or, for
+	// a SERIALIZABLE transaction, to anything it read. The transaction can
+	// then be retried from BeginTransaction.
+	// Write, WriteBatch, Delete, Commit and Abort fail with NOT_FOUND for a
+	// tx_id that was never begun, and with FAILED_PRECONDITION for one that
+	// already committed or aborted.
 	Commit(context.Context, *TxnID) (*Status, error)
 	Abort(context.Context, *TxnID) (*Status, error)
 	mustEmbedUnimplementedAmberServiceServer()
@@ -114,15 +269,36 @@ type AmberServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedAmberServiceServer struct{}
 
-func (UnimplementedAmberServiceServer) BeginTransaction(context.Context, *Empty) (*TxnID, error) {
+func (UnimplementedAmberServiceServer) BeginTransaction(context.Context, *BeginTransactionRequest) (*TxnID, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BeginTransaction not implemented")
 }
 func (UnimplementedAmberServiceServer) Write(context.Context, *WriteRequest) (*Status, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Write not implemented")
 }
+func (UnimplementedAmberServiceServer) WriteBatch(context.Context, *WriteBatchRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteBatch not implemented")
+}
+func (UnimplementedAmberServiceServer) Delete(context.Context, *DeleteRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedAmberServiceServer) CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
+func (UnimplementedAmberServiceServer) Txn(context.Context, *TxnRequest) (*TxnResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Txn not implemented")
+}
 func (UnimplementedAmberServiceServer) Read(context.Context, *ReadRequest) (*ReadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Read not implemented")
 }
+func (UnimplementedAmberServiceServer) Scan(*ScanRequest, grpc.ServerStreamingServer[ScanResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedAmberServiceServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedAmberServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedAmberServiceServer) Commit(context.Context, *TxnID) (*Status, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Commit not implemented")
 }
@@ -151,7 +327,7 @@ func RegisterAmberServiceServer(s grpc.ServiceRegistrar, srv AmberServiceServer)
 }
 
 func _AmberService_BeginTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(BeginTransactionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -163,7 +339,7 @@ func _AmberService_BeginTransaction_Handler(srv interface{}, ctx context.Context
 		FullMethod: AmberService_BeginTransaction_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AmberServiceServer).BeginTransaction(ctx, req.(*Empty))
+		return srv.(AmberServiceServer).BeginTransaction(ctx, req.(*BeginTransactionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -186,6 +362,78 @@ func _AmberService_Write_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AmberService_WriteBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AmberServiceServer).WriteBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AmberService_WriteBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AmberServiceServer).WriteBatch(ctx, req.(*WriteBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AmberService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AmberServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AmberService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AmberServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AmberService_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AmberServiceServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AmberService_CompareAndSwap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AmberServiceServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AmberService_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AmberServiceServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AmberService_Txn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AmberServiceServer).Txn(ctx, req.(*TxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AmberService_Read_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ReadRequest)
 	if err := dec(in); err != nil {
@@ -204,6 +452,46 @@ func _AmberService_Read_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AmberService_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AmberServiceServer).Scan(m, &grpc.GenericServerStream[ScanRequest, ScanResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AmberService_ScanServer = grpc.ServerStreamingServer[ScanResponse]
+
+func _AmberService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AmberServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AmberService_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AmberServiceServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AmberService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AmberServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AmberService_WatchServer = grpc.ServerStreamingServer[WatchResponse]
+
 func _AmberService_Commit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(TxnID)
 	if err := dec(in); err != nil {
@@ -255,10 +543,30 @@ var AmberService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Write",
 			Handler:    _AmberService_Write_Handler,
 		},
+		{
+			MethodName: "WriteBatch",
+			Handler:    _AmberService_WriteBatch_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _AmberService_Delete_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _AmberService_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _AmberService_Txn_Handler,
+		},
 		{
 			MethodName: "Read",
 			Handler:    _AmberService_Read_Handler,
 		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _AmberService_GetHistory_Handler,
+		},
 		{
 			MethodName: "Commit",
 			Handler:    _AmberService_Commit_Handler,
@@ -268,6 +576,397 @@ var AmberService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _AmberService_Abort_Handler,
 		},
 	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _AmberService_Scan_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _AmberService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "amberdb.proto",
+}
+
+const (
+	AdminService_AddVoter_FullMethodName           = "/amberdb.AdminService/AddVoter"
+	AdminService_AddNonvoter_FullMethodName        = "/amberdb.AdminService/AddNonvoter"
+	AdminService_RemoveServer_FullMethodName       = "/amberdb.AdminService/RemoveServer"
+	AdminService_DemoteVoter_FullMethodName        = "/amberdb.AdminService/DemoteVoter"
+	AdminService_GetConfiguration_FullMethodName   = "/amberdb.AdminService/GetConfiguration"
+	AdminService_TransferLeadership_FullMethodName = "/amberdb.AdminService/TransferLeadership"
+	AdminService_Drain_FullMethodName              = "/amberdb.AdminService/Drain"
+	AdminService_Undrain_FullMethodName            = "/amberdb.AdminService/Undrain"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService manages the membership of the Raft group.
+// Membership changes must be sent to the leader.
+type AdminServiceClient interface {
+	AddVoter(ctx context.Context, in *AddServerRequest, opts ...grpc.CallOption) (*Status, error)
+	AddNonvoter(ctx context.Context, in *AddServerRequest, opts ...grpc.CallOption) (*Status, error)
+	RemoveServer(ctx context.Context, in *ServerID, opts ...grpc.CallOption) (*Status, error)
+	DemoteVoter(ctx context.Context, in *ServerID, opts ...grpc.CallOption) (*Status, error)
+	GetConfiguration(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Configuration, error)
+	// TransferLeadership hands leadership to another voter. Must be sent to the leader.
+	TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*Status, error)
+	// Drain transfers leadership away from the receiving node, if it leads,
+	// and makes it refuse new transactions until Undrain.
+	Drain(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Status, error)
+	Undrain(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Status, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) AddVoter(ctx context.Context, in *AddServerRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_AddVoter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) AddNonvoter(ctx context.Context, in *AddServerRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_AddNonvoter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RemoveServer(ctx context.Context, in *ServerID, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_RemoveServer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DemoteVoter(ctx context.Context, in *ServerID, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_DemoteVoter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetConfiguration(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Configuration, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Configuration)
+	err := c.cc.Invoke(ctx, AdminService_GetConfiguration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) TransferLeadership(ctx context.Context, in *TransferLeadershipRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_TransferLeadership_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Drain(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_Drain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) Undrain(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, AdminService_Undrain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService manages the membership of the Raft group.
+// Membership changes must be sent to the leader.
+type AdminServiceServer interface {
+	AddVoter(context.Context, *AddServerRequest) (*Status, error)
+	AddNonvoter(context.Context, *AddServerRequest) (*Status, error)
+	RemoveServer(context.Context, *ServerID) (*Status, error)
+	DemoteVoter(context.Context, *ServerID) (*Status, error)
+	GetConfiguration(context.Context, *Empty) (*Configuration, error)
+	// TransferLeadership hands leadership to another voter. Must be sent to the leader.
+	TransferLeadership(context.Context, *TransferLeadershipRequest) (*Status, error)
+	// Drain transfers leadership away from the receiving node, if it leads,
+	// and makes it refuse new transactions until Undrain.
+	Drain(context.Context, *Empty) (*Status, error)
+	Undrain(context.Context, *Empty) (*Status, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) AddVoter(context.Context, *AddServerRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddVoter not implemented")
+}
+func (UnimplementedAdminServiceServer) AddNonvoter(context.Context, *AddServerRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNonvoter not implemented")
+}
+func (UnimplementedAdminServiceServer) RemoveServer(context.Context, *ServerID) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveServer not implemented")
+}
+func (UnimplementedAdminServiceServer) DemoteVoter(context.Context, *ServerID) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DemoteVoter not implemented")
+}
+func (UnimplementedAdminServiceServer) GetConfiguration(context.Context, *Empty) (*Configuration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfiguration not implemented")
+}
+func (UnimplementedAdminServiceServer) TransferLeadership(context.Context, *TransferLeadershipRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferLeadership not implemented")
+}
+func (UnimplementedAdminServiceServer) Drain(context.Context, *Empty) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedAdminServiceServer) Undrain(context.Context, *Empty) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Undrain not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_AddVoter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AddVoter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_AddVoter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AddVoter(ctx, req.(*AddServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_AddNonvoter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AddNonvoter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_AddNonvoter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AddNonvoter(ctx, req.(*AddServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RemoveServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RemoveServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RemoveServer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RemoveServer(ctx, req.(*ServerID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DemoteVoter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServerID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DemoteVoter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DemoteVoter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DemoteVoter(ctx, req.(*ServerID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetConfiguration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetConfiguration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetConfiguration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetConfiguration(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_TransferLeadership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferLeadershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).TransferLeadership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_TransferLeadership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).TransferLeadership(ctx, req.(*TransferLeadershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Drain(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_Undrain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Undrain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Undrain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Undrain(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "amberdb.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddVoter",
+			Handler:    _AdminService_AddVoter_Handler,
+		},
+		{
+			MethodName: "AddNonvoter",
+			Handler:    _AdminService_AddNonvoter_Handler,
+		},
+		{
+			MethodName: "RemoveServer",
+			Handler:    _AdminService_RemoveServer_Handler,
+		},
+		{
+			MethodName: "DemoteVoter",
+			Handler:    _AdminService_DemoteVoter_Handler,
+		},
+		{
+			MethodName: "GetConfiguration",
+			Handler:    _AdminService_GetConfiguration_Handler,
+		},
+		{
+			MethodName: "TransferLeadership",
+			Handler:    _AdminService_TransferLeadership_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _AdminService_Drain_Handler,
+		},
+		{
+			MethodName: "Undrain",
+			Handler:    _AdminService_Undrain_Handler,
+		},
+	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "amberdb.proto",
 }
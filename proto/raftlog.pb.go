@@ -0,0 +1,854 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.28.2
+// source: raftlog.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// LogOp is the operation carried by a Raft log entry.
+type LogOp int32
+
+const (
+	LogOp_LOG_OP_UNSPECIFIED LogOp = 0
+	LogOp_LOG_OP_WRITE       LogOp = 1
+	LogOp_LOG_OP_WRITE_BATCH LogOp = 2
+	// LOG_OP_COMMIT commits tx_id at timestamp, validating read_set.
+	LogOp_LOG_OP_COMMIT LogOp = 3
+	LogOp_LOG_OP_ABORT  LogOp = 4
+	LogOp_LOG_OP_DELETE LogOp = 5
+	// LOG_OP_GC collects versions older than the timestamp field.
+	LogOp_LOG_OP_GC LogOp = 6
+	// LOG_OP_EXPIRE writes a tombstone at the expiry of every batch pair whose
+	// key still holds the version expiring then.
+	LogOp_LOG_OP_EXPIRE LogOp = 7
+	// LOG_OP_CAS writes and commits key/value only if condition holds.
+	LogOp_LOG_OP_CAS LogOp = 8
+	// LOG_OP_TXN checks compares and runs and commits one of two op lists.
+	LogOp_LOG_OP_TXN LogOp = 9
+	// LOG_OP_REGISTER_NODE records value as the gRPC address of node key.
+	LogOp_LOG_OP_REGISTER_NODE LogOp = 10
+	// LOG_OP_BEGIN starts transaction tx_id of node key with the snapshot at
+	// timestamp and isolation.
+	LogOp_LOG_OP_BEGIN LogOp = 11
+)
+
+// Enum value maps for LogOp.
+var (
+	LogOp_name = map[int32]string{
+		0:  "LOG_OP_UNSPECIFIED",
+		1:  "LOG_OP_WRITE",
+		2:  "LOG_OP_WRITE_BATCH",
+		3:  "LOG_OP_COMMIT",
+		4:  "LOG_OP_ABORT",
+		5:  "LOG_OP_DELETE",
+		6:  "LOG_OP_GC",
+		7:  "LOG_OP_EXPIRE",
+		8:  "LOG_OP_CAS",
+		9:  "LOG_OP_TXN",
+		10: "LOG_OP_REGISTER_NODE",
+		11: "LOG_OP_BEGIN",
+	}
+	LogOp_value = map[string]int32{
+		"LOG_OP_UNSPECIFIED":   0,
+		"LOG_OP_WRITE":         1,
+		"LOG_OP_WRITE_BATCH":   2,
+		"LOG_OP_COMMIT":        3,
+		"LOG_OP_ABORT":         4,
+		"LOG_OP_DELETE":        5,
+		"LOG_OP_GC":            6,
+		"LOG_OP_EXPIRE":        7,
+		"LOG_OP_CAS":           8,
+		"LOG_OP_TXN":           9,
+		"LOG_OP_REGISTER_NODE": 10,
+		"LOG_OP_BEGIN":         11,
+	}
+)
+
+func (x LogOp) Enum() *LogOp {
+	p := new(LogOp)
+	*p = x
+	return p
+}
+
+func (x LogOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_raftlog_proto_enumTypes[0].Descriptor()
+}
+
+func (LogOp) Type() protoreflect.EnumType {
+	return &file_raftlog_proto_enumTypes[0]
+}
+
+func (x LogOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogOp.Descriptor instead.
+func (LogOp) EnumDescriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0}
+}
+
+type LogEntry_Isolation int32
+
+const (
+	LogEntry_SNAPSHOT_ISOLATION LogEntry_Isolation = 0
+	LogEntry_SERIALIZABLE       LogEntry_Isolation = 1
+)
+
+// Enum value maps for LogEntry_Isolation.
+var (
+	LogEntry_Isolation_name = map[int32]string{
+		0: "SNAPSHOT_ISOLATION",
+		1: "SERIALIZABLE",
+	}
+	LogEntry_Isolation_value = map[string]int32{
+		"SNAPSHOT_ISOLATION": 0,
+		"SERIALIZABLE":       1,
+	}
+)
+
+func (x LogEntry_Isolation) Enum() *LogEntry_Isolation {
+	p := new(LogEntry_Isolation)
+	*p = x
+	return p
+}
+
+func (x LogEntry_Isolation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogEntry_Isolation) Descriptor() protoreflect.EnumDescriptor {
+	return file_raftlog_proto_enumTypes[1].Descriptor()
+}
+
+func (LogEntry_Isolation) Type() protoreflect.EnumType {
+	return &file_raftlog_proto_enumTypes[1]
+}
+
+func (x LogEntry_Isolation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogEntry_Isolation.Descriptor instead.
+func (LogEntry_Isolation) EnumDescriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type LogEntry_Condition_Kind int32
+
+const (
+	LogEntry_Condition_KIND_UNSPECIFIED LogEntry_Condition_Kind = 0
+	LogEntry_Condition_VALUE_EQUALS     LogEntry_Condition_Kind = 1
+	LogEntry_Condition_VERSION_EQUALS   LogEntry_Condition_Kind = 2
+	LogEntry_Condition_NOT_EXISTS       LogEntry_Condition_Kind = 3
+	LogEntry_Condition_EXISTS           LogEntry_Condition_Kind = 4
+)
+
+// Enum value maps for LogEntry_Condition_Kind.
+var (
+	LogEntry_Condition_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "VALUE_EQUALS",
+		2: "VERSION_EQUALS",
+		3: "NOT_EXISTS",
+		4: "EXISTS",
+	}
+	LogEntry_Condition_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"VALUE_EQUALS":     1,
+		"VERSION_EQUALS":   2,
+		"NOT_EXISTS":       3,
+		"EXISTS":           4,
+	}
+)
+
+func (x LogEntry_Condition_Kind) Enum() *LogEntry_Condition_Kind {
+	p := new(LogEntry_Condition_Kind)
+	*p = x
+	return p
+}
+
+func (x LogEntry_Condition_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogEntry_Condition_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_raftlog_proto_enumTypes[2].Descriptor()
+}
+
+func (LogEntry_Condition_Kind) Type() protoreflect.EnumType {
+	return &file_raftlog_proto_enumTypes[2]
+}
+
+func (x LogEntry_Condition_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogEntry_Condition_Kind.Descriptor instead.
+func (LogEntry_Condition_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 1, 0}
+}
+
+type LogEntry_TxnOp_Kind int32
+
+const (
+	LogEntry_TxnOp_KIND_UNSPECIFIED LogEntry_TxnOp_Kind = 0
+	LogEntry_TxnOp_PUT              LogEntry_TxnOp_Kind = 1
+	LogEntry_TxnOp_DELETE           LogEntry_TxnOp_Kind = 2
+	LogEntry_TxnOp_GET              LogEntry_TxnOp_Kind = 3
+)
+
+// Enum value maps for LogEntry_TxnOp_Kind.
+var (
+	LogEntry_TxnOp_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "PUT",
+		2: "DELETE",
+		3: "GET",
+	}
+	LogEntry_TxnOp_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"PUT":              1,
+		"DELETE":           2,
+		"GET":              3,
+	}
+)
+
+func (x LogEntry_TxnOp_Kind) Enum() *LogEntry_TxnOp_Kind {
+	p := new(LogEntry_TxnOp_Kind)
+	*p = x
+	return p
+}
+
+func (x LogEntry_TxnOp_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogEntry_TxnOp_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_raftlog_proto_enumTypes[3].Descriptor()
+}
+
+func (LogEntry_TxnOp_Kind) Type() protoreflect.EnumType {
+	return &file_raftlog_proto_enumTypes[3]
+}
+
+func (x LogEntry_TxnOp_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogEntry_TxnOp_Kind.Descriptor instead.
+func (LogEntry_TxnOp_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 4, 0}
+}
+
+// LogEntry is the payload of a Raft log entry. It is kept separate from the
+// client API so that replaying raft-log.bolt never depends on RPC messages.
+// New fields must be optional to older readers; incompatible changes bump
+// format_version. Keys and values were strings in the first release; bytes
+// share their wire encoding, so those entries still decode.
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FormatVersion uint32                 `protobuf:"varint,1,opt,name=format_version,json=formatVersion,proto3" json:"format_version,omitempty"`
+	Op            LogOp                  `protobuf:"varint,2,opt,name=op,proto3,enum=amberdb.LogOp" json:"op,omitempty"`
+	Key           []byte                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	TxId          string                 `protobuf:"bytes,5,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // HLC timestamp for versioning
+	Batch         []*LogEntry_Pair       `protobuf:"bytes,7,rep,name=batch,proto3" json:"batch,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`                  // HLC timestamp at which a WRITE or CAS expires
+	Condition     *LogEntry_Condition    `protobuf:"bytes,9,opt,name=condition,proto3" json:"condition,omitempty"`                                   // precondition of a CAS
+	Compares      []*LogEntry_Compare    `protobuf:"bytes,10,rep,name=compares,proto3" json:"compares,omitempty"`                                    // TXN comparisons
+	Success       []*LogEntry_TxnOp      `protobuf:"bytes,11,rep,name=success,proto3" json:"success,omitempty"`                                      // TXN ops run if every comparison holds
+	Failure       []*LogEntry_TxnOp      `protobuf:"bytes,12,rep,name=failure,proto3" json:"failure,omitempty"`                                      // TXN ops run otherwise
+	ReadSet       []*LogEntry_KeyRange   `protobuf:"bytes,13,rep,name=read_set,json=readSet,proto3" json:"read_set,omitempty"`                       // what a SERIALIZABLE transaction read, on COMMIT
+	Isolation     LogEntry_Isolation     `protobuf:"varint,14,opt,name=isolation,proto3,enum=amberdb.LogEntry_Isolation" json:"isolation,omitempty"` // of the transaction started by BEGIN
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_raftlog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LogEntry) GetFormatVersion() uint32 {
+	if x != nil {
+		return x.FormatVersion
+	}
+	return 0
+}
+
+func (x *LogEntry) GetOp() LogOp {
+	if x != nil {
+		return x.Op
+	}
+	return LogOp_LOG_OP_UNSPECIFIED
+}
+
+func (x *LogEntry) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *LogEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *LogEntry) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *LogEntry) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *LogEntry) GetBatch() []*LogEntry_Pair {
+	if x != nil {
+		return x.Batch
+	}
+	return nil
+}
+
+func (x *LogEntry) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *LogEntry) GetCondition() *LogEntry_Condition {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+func (x *LogEntry) GetCompares() []*LogEntry_Compare {
+	if x != nil {
+		return x.Compares
+	}
+	return nil
+}
+
+func (x *LogEntry) GetSuccess() []*LogEntry_TxnOp {
+	if x != nil {
+		return x.Success
+	}
+	return nil
+}
+
+func (x *LogEntry) GetFailure() []*LogEntry_TxnOp {
+	if x != nil {
+		return x.Failure
+	}
+	return nil
+}
+
+func (x *LogEntry) GetReadSet() []*LogEntry_KeyRange {
+	if x != nil {
+		return x.ReadSet
+	}
+	return nil
+}
+
+func (x *LogEntry) GetIsolation() LogEntry_Isolation {
+	if x != nil {
+		return x.Isolation
+	}
+	return LogEntry_SNAPSHOT_ISOLATION
+}
+
+type LogEntry_Pair struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry_Pair) Reset() {
+	*x = LogEntry_Pair{}
+	mi := &file_raftlog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry_Pair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry_Pair) ProtoMessage() {}
+
+func (x *LogEntry_Pair) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry_Pair.ProtoReflect.Descriptor instead.
+func (*LogEntry_Pair) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 0}
+}
+
+func (x *LogEntry_Pair) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *LogEntry_Pair) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *LogEntry_Pair) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type LogEntry_Condition struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Kind          LogEntry_Condition_Kind `protobuf:"varint,1,opt,name=kind,proto3,enum=amberdb.LogEntry_Condition_Kind" json:"kind,omitempty"`
+	Value         []byte                  `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Version       string                  `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry_Condition) Reset() {
+	*x = LogEntry_Condition{}
+	mi := &file_raftlog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry_Condition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry_Condition) ProtoMessage() {}
+
+func (x *LogEntry_Condition) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry_Condition.ProtoReflect.Descriptor instead.
+func (*LogEntry_Condition) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 1}
+}
+
+func (x *LogEntry_Condition) GetKind() LogEntry_Condition_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return LogEntry_Condition_KIND_UNSPECIFIED
+}
+
+func (x *LogEntry_Condition) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *LogEntry_Condition) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type LogEntry_Compare struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Condition     *LogEntry_Condition    `protobuf:"bytes,2,opt,name=condition,proto3" json:"condition,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry_Compare) Reset() {
+	*x = LogEntry_Compare{}
+	mi := &file_raftlog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry_Compare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry_Compare) ProtoMessage() {}
+
+func (x *LogEntry_Compare) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry_Compare.ProtoReflect.Descriptor instead.
+func (*LogEntry_Compare) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 2}
+}
+
+func (x *LogEntry_Compare) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *LogEntry_Compare) GetCondition() *LogEntry_Condition {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+// KeyRange is the range [start, end) of keys; an empty end is unbounded.
+type LogEntry_KeyRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         []byte                 `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           []byte                 `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry_KeyRange) Reset() {
+	*x = LogEntry_KeyRange{}
+	mi := &file_raftlog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry_KeyRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry_KeyRange) ProtoMessage() {}
+
+func (x *LogEntry_KeyRange) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry_KeyRange.ProtoReflect.Descriptor instead.
+func (*LogEntry_KeyRange) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 3}
+}
+
+func (x *LogEntry_KeyRange) GetStart() []byte {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *LogEntry_KeyRange) GetEnd() []byte {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+type LogEntry_TxnOp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          LogEntry_TxnOp_Kind    `protobuf:"varint,1,opt,name=kind,proto3,enum=amberdb.LogEntry_TxnOp_Kind" json:"kind,omitempty"`
+	Key           []byte                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry_TxnOp) Reset() {
+	*x = LogEntry_TxnOp{}
+	mi := &file_raftlog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry_TxnOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry_TxnOp) ProtoMessage() {}
+
+func (x *LogEntry_TxnOp) ProtoReflect() protoreflect.Message {
+	mi := &file_raftlog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry_TxnOp.ProtoReflect.Descriptor instead.
+func (*LogEntry_TxnOp) Descriptor() ([]byte, []int) {
+	return file_raftlog_proto_rawDescGZIP(), []int{0, 4}
+}
+
+func (x *LogEntry_TxnOp) GetKind() LogEntry_TxnOp_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return LogEntry_TxnOp_KIND_UNSPECIFIED
+}
+
+func (x *LogEntry_TxnOp) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *LogEntry_TxnOp) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *LogEntry_TxnOp) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+var File_raftlog_proto protoreflect.FileDescriptor
+
+const file_raftlog_proto_rawDesc = "" +
+	"\n" +
+	"\rraftlog.proto\x12\aamberdb\"\xe8\t\n" +
+	"\bLogEntry\x12%\n" +
+	"\x0eformat_version\x18\x01 \x01(\rR\rformatVersion\x12\x1e\n" +
+	"\x02op\x18\x02 \x01(\x0e2\x0e.amberdb.LogOpR\x02op\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\fR\x05value\x12\x13\n" +
+	"\x05tx_id\x18\x05 \x01(\tR\x04txId\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\tR\ttimestamp\x12,\n" +
+	"\x05batch\x18\a \x03(\v2\x16.amberdb.LogEntry.PairR\x05batch\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\b \x01(\tR\texpiresAt\x129\n" +
+	"\tcondition\x18\t \x01(\v2\x1b.amberdb.LogEntry.ConditionR\tcondition\x125\n" +
+	"\bcompares\x18\n" +
+	" \x03(\v2\x19.amberdb.LogEntry.CompareR\bcompares\x121\n" +
+	"\asuccess\x18\v \x03(\v2\x17.amberdb.LogEntry.TxnOpR\asuccess\x121\n" +
+	"\afailure\x18\f \x03(\v2\x17.amberdb.LogEntry.TxnOpR\afailure\x125\n" +
+	"\bread_set\x18\r \x03(\v2\x1a.amberdb.LogEntry.KeyRangeR\areadSet\x129\n" +
+	"\tisolation\x18\x0e \x01(\x0e2\x1b.amberdb.LogEntry.IsolationR\tisolation\x1aM\n" +
+	"\x04Pair\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\tR\texpiresAt\x1a\xd1\x01\n" +
+	"\tCondition\x124\n" +
+	"\x04kind\x18\x01 \x01(\x0e2 .amberdb.LogEntry.Condition.KindR\x04kind\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\"^\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fVALUE_EQUALS\x10\x01\x12\x12\n" +
+	"\x0eVERSION_EQUALS\x10\x02\x12\x0e\n" +
+	"\n" +
+	"NOT_EXISTS\x10\x03\x12\n" +
+	"\n" +
+	"\x06EXISTS\x10\x04\x1aV\n" +
+	"\aCompare\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x129\n" +
+	"\tcondition\x18\x02 \x01(\v2\x1b.amberdb.LogEntry.ConditionR\tcondition\x1a2\n" +
+	"\bKeyRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\fR\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\fR\x03end\x1a\xbc\x01\n" +
+	"\x05TxnOp\x120\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x1c.amberdb.LogEntry.TxnOp.KindR\x04kind\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\fR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\tR\texpiresAt\":\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\a\n" +
+	"\x03PUT\x10\x01\x12\n" +
+	"\n" +
+	"\x06DELETE\x10\x02\x12\a\n" +
+	"\x03GET\x10\x03\"5\n" +
+	"\tIsolation\x12\x16\n" +
+	"\x12SNAPSHOT_ISOLATION\x10\x00\x12\x10\n" +
+	"\fSERIALIZABLE\x10\x01*\xef\x01\n" +
+	"\x05LogOp\x12\x16\n" +
+	"\x12LOG_OP_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fLOG_OP_WRITE\x10\x01\x12\x16\n" +
+	"\x12LOG_OP_WRITE_BATCH\x10\x02\x12\x11\n" +
+	"\rLOG_OP_COMMIT\x10\x03\x12\x10\n" +
+	"\fLOG_OP_ABORT\x10\x04\x12\x11\n" +
+	"\rLOG_OP_DELETE\x10\x05\x12\r\n" +
+	"\tLOG_OP_GC\x10\x06\x12\x11\n" +
+	"\rLOG_OP_EXPIRE\x10\a\x12\x0e\n" +
+	"\n" +
+	"LOG_OP_CAS\x10\b\x12\x0e\n" +
+	"\n" +
+	"LOG_OP_TXN\x10\t\x12\x18\n" +
+	"\x14LOG_OP_REGISTER_NODE\x10\n" +
+	"\x12\x10\n" +
+	"\fLOG_OP_BEGIN\x10\vB\tZ\a./protob\x06proto3"
+
+var (
+	file_raftlog_proto_rawDescOnce sync.Once
+	file_raftlog_proto_rawDescData []byte
+)
+
+func file_raftlog_proto_rawDescGZIP() []byte {
+	file_raftlog_proto_rawDescOnce.Do(func() {
+		file_raftlog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_raftlog_proto_rawDesc), len(file_raftlog_proto_rawDesc)))
+	})
+	return file_raftlog_proto_rawDescData
+}
+
+var file_raftlog_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_raftlog_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_raftlog_proto_goTypes = []any{
+	(LogOp)(0),                   // 0: amberdb.LogOp
+	(LogEntry_Isolation)(0),      // 1: amberdb.LogEntry.Isolation
+	(LogEntry_Condition_Kind)(0), // 2: amberdb.LogEntry.Condition.Kind
+	(LogEntry_TxnOp_Kind)(0),     // 3: amberdb.LogEntry.TxnOp.Kind
+	(*LogEntry)(nil),             // 4: amberdb.LogEntry
+	(*LogEntry_Pair)(nil),        // 5: amberdb.LogEntry.Pair
+	(*LogEntry_Condition)(nil),   // 6: amberdb.LogEntry.Condition
+	(*LogEntry_Compare)(nil),     // 7: amberdb.LogEntry.Compare
+	(*LogEntry_KeyRange)(nil),    // 8: amberdb.LogEntry.KeyRange
+	(*LogEntry_TxnOp)(nil),       // 9: amberdb.LogEntry.TxnOp
+}
+var file_raftlog_proto_depIdxs = []int32{
+	0,  // 0: amberdb.LogEntry.op:type_name -> amberdb.LogOp
+	5,  // 1: amberdb.LogEntry.batch:type_name -> amberdb.LogEntry.Pair
+	6,  // 2: amberdb.LogEntry.condition:type_name -> amberdb.LogEntry.Condition
+	7,  // 3: amberdb.LogEntry.compares:type_name -> amberdb.LogEntry.Compare
+	9,  // 4: amberdb.LogEntry.success:type_name -> amberdb.LogEntry.TxnOp
+	9,  // 5: amberdb.LogEntry.failure:type_name -> amberdb.LogEntry.TxnOp
+	8,  // 6: amberdb.LogEntry.read_set:type_name -> amberdb.LogEntry.KeyRange
+	1,  // 7: amberdb.LogEntry.isolation:type_name -> amberdb.LogEntry.Isolation
+	2,  // 8: amberdb.LogEntry.Condition.kind:type_name -> amberdb.LogEntry.Condition.Kind
+	6,  // 9: amberdb.LogEntry.Compare.condition:type_name -> amberdb.LogEntry.Condition
+	3,  // 10: amberdb.LogEntry.TxnOp.kind:type_name -> amberdb.LogEntry.TxnOp.Kind
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_raftlog_proto_init() }
+func file_raftlog_proto_init() {
+	if File_raftlog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_raftlog_proto_rawDesc), len(file_raftlog_proto_rawDesc)),
+			NumEnums:      4,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_raftlog_proto_goTypes,
+		DependencyIndexes: file_raftlog_proto_depIdxs,
+		EnumInfos:         file_raftlog_proto_enumTypes,
+		MessageInfos:      file_raftlog_proto_msgTypes,
+	}.Build()
+	File_raftlog_proto = out.File
+	file_raftlog_proto_goTypes = nil
+	file_raftlog_proto_depIdxs = nil
+}
@@ -13,9 +13,9 @@ go build -o amberdb-node ./cmd/node
 # Prepare raft_config.json for localhost
 cat > ./internal/raftstore/raft_config.json <<EOF
 [
-  {"id": "node1", "address": "localhost:9001"},
-  {"id": "node2", "address": "localhost:9002"},
-  {"id": "node3", "address": "localhost:9003"}
+  {"id": "node1", "address": "localhost:9001", "grpc_address": "localhost:50051"},
+  {"id": "node2", "address": "localhost:9002", "grpc_address": "localhost:50052"},
+  {"id": "node3", "address": "localhost:9003", "grpc_address": "localhost:50053"}
 ]
 EOF
 